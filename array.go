@@ -0,0 +1,128 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// isArrayField reports whether field of t is scanned from array column. e.g) postgres text[] -> []string
+func isArrayField(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(scannerType)
+}
+
+// newArrayConverter returns scan converter which parses array literal. e.g) {a,"b c",NULL} into slice of t
+func newArrayConverter(t reflect.Type) func(src interface{}) (interface{}, error) {
+	return func(src interface{}) (interface{}, error) {
+		var literal string
+		switch s := src.(type) {
+		case nil:
+			return nil, nil
+		case []byte:
+			literal = string(s)
+		case string:
+			literal = s
+		default:
+			if reflect.TypeOf(src).AssignableTo(t) {
+				return src, nil
+			}
+			return nil, fmt.Errorf("unsupported array source type %T", src)
+		}
+
+		elements, err := parseArrayLiteral(literal)
+		if err != nil {
+			return nil, err
+		}
+
+		slice := reflect.MakeSlice(t, len(elements), len(elements))
+		for i, e := range elements {
+			if e == nil {
+				continue // NULL is zero value
+			}
+			if err := convertAssign(slice.Index(i).Addr().Interface(), *e); err != nil {
+				return nil, fmt.Errorf("array element %d : %s", i, err.Error())
+			}
+		}
+		return slice.Interface(), nil
+	}
+}
+
+// parseArrayLiteral parses one dimensional array literal. nil element is NULL
+func parseArrayLiteral(literal string) ([]*string, error) {
+	literal = strings.TrimSpace(literal)
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal : %s", literal)
+	}
+
+	body := literal[1 : len(literal)-1]
+	elements := make([]*string, 0)
+	if len(strings.TrimSpace(body)) == 0 {
+		return elements, nil
+	}
+
+	var element strings.Builder
+	quoted := false
+	inQuote := false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(body):
+			i++
+			element.WriteByte(body[i])
+		case c == '"':
+			inQuote = !inQuote
+			quoted = true
+		case inQuote:
+			element.WriteByte(c)
+		case c == '{' || c == '}':
+			return nil, fmt.Errorf("multi dimensional array is not supported : %s", literal)
+		case c == ',':
+			elements = append(elements, arrayElement(element.String(), quoted))
+			element.Reset()
+			quoted = false
+		default:
+			element.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in array literal : %s", literal)
+	}
+	elements = append(elements, arrayElement(element.String(), quoted))
+	return elements, nil
+}
+
+func arrayElement(s string, quoted bool) *string {
+	if !quoted {
+		s = strings.TrimSpace(s)
+		if strings.EqualFold(s, "NULL") {
+			return nil
+		}
+	}
+	return &s
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+const defaultQueryBatchConcurrency = 4
+
+// BatchRequest is a select statement of QueryBatch
+type BatchRequest struct {
+	StmtId string
+	Params []interface{}
+}
+
+// QueryBatch runs select statements concurrently up to QueryBatchConcurrency and returns their results in order.
+// rows of each result are read into memory so that the connection is released at once.
+// failure of a statement is GetError() of its result and does not fail the others
+func (man *QueryMan) QueryBatch(ctx context.Context, requests []BatchRequest) ([]*QueryResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	workers := man.preference.QueryBatchConcurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	results := make([]*QueryResult, len(requests))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = man.queryBuffered(ctx, requests[i])
+			}
+		}()
+	}
+	for i := range requests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// queryBuffered queries request and reads all rows into memory
+func (man *QueryMan) queryBuffered(ctx context.Context, request BatchRequest) *QueryResult {
+	result := man.QueryWithStmtContext(ctx, request.StmtId, request.Params...)
+	if result.err != nil {
+		return result
+	}
+	if _, ok := result.rows.(*cachedRows); ok {
+		return result
+	}
+
+	cached, err := materialize(result, 0)
+	result.Close()
+	if err != nil {
+		return newQueryResultError(err)
+	}
+	return man.newCachedQueryResult(cached)
+}
+
+// EachResolver picks statement and its parameters of item for ExecuteEach. params is passed as the single
+// parameter of ExecuteWithStmt (struct, map or []interface{} of positional values) and nil executes without parameters
+type EachResolver func(item interface{}) (stmtId string, params interface{})
+
+// ExecuteEach executes the statement resolved per item in one transaction and returns results in item order.
+// the transaction is rolled back at the first failure and results of the executed items are returned with the error
+func (man *QueryMan) ExecuteEach(resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	return man.ExecuteEachContext(context.Background(), resolver, items)
+}
+
+func (man *QueryMan) ExecuteEachContext(ctx context.Context, resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	tx, err := man.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := executeEach(ctx, man.WrapTx(tx).ExecuteWithStmtContext, resolver, items)
+	if err != nil {
+		tx.Rollback()
+		return results, err
+	}
+	return results, tx.Commit()
+}
+
+// ExecuteEach executes the statement resolved per item within the transaction. commit and rollback stay with the caller
+func (t *DBTransaction) ExecuteEach(resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	return t.ExecuteEachContext(context.Background(), resolver, items)
+}
+
+func (t *DBTransaction) ExecuteEachContext(ctx context.Context, resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	return executeEach(ctx, t.ExecuteWithStmtContext, resolver, items)
+}
+
+// ExecuteEach executes the statement resolved per item on the session without transaction.
+// items executed before a failure stay applied
+func (s *Session) ExecuteEach(resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	return s.ExecuteEachContext(context.Background(), resolver, items)
+}
+
+func (s *Session) ExecuteEachContext(ctx context.Context, resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	return executeEach(ctx, s.ExecuteWithStmtContext, resolver, items)
+}
+
+// executeEach executes items in order and stops at the first failure
+func executeEach(ctx context.Context, exec func(ctx context.Context, stmtId string, v ...interface{}) (sql.Result, error), resolver EachResolver, items []interface{}) ([]sql.Result, error) {
+	if resolver == nil {
+		return nil, fmt.Errorf("each resolver is nil")
+	}
+
+	results := make([]sql.Result, 0, len(items))
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		stmtId, params := resolver(item)
+		if len(stmtId) == 0 {
+			return results, fmt.Errorf("no statement resolved for item %d", i)
+		}
+
+		var result sql.Result
+		var err error
+		if params == nil {
+			result, err = exec(ctx, stmtId)
+		} else {
+			result, err = exec(ctx, stmtId, params)
+		}
+		if err != nil {
+			return results, fmt.Errorf("fail to execute item %d [%s] : %w", i, stmtId, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	bindModifierDelim  = ":"
+	bindModifierJson   = "json"
+	bindModifierInt    = "int"
+	bindModifierFloat  = "float"
+	bindModifierString = "string"
+	bindModifierBool   = "bool"
+	bindModifierTime   = "time"
+	bindModifierBytes  = "bytes"
+	bindModifierLike   = "like"
+	likeEscapeChar     = `\`
+)
+
+// bindModifiers converts parameter value of bind declared with modifier. e.g) {Payload:json}
+var bindModifiers = map[string]func(v interface{}) (interface{}, error){
+	bindModifierJson: jsonBindValue,
+	bindModifierLike: likeBindValue,
+}
+
+// bindTypes only verify the kind of parameter of bind declared with type annotation. e.g) {Age:int}.
+// IN array bind is verified per element. e.g) IN ({Ids:int})
+var bindTypes = map[string]func(v interface{}) (interface{}, error){
+	bindModifierInt:    expectBindKind(bindModifierInt),
+	bindModifierFloat:  expectBindKind(bindModifierFloat),
+	bindModifierString: expectBindKind(bindModifierString),
+	bindModifierBool:   expectBindKind(bindModifierBool),
+	bindModifierTime:   expectBindKind(bindModifierTime),
+	bindModifierBytes:  expectBindKind(bindModifierBytes),
+}
+
+var likeEscaper = strings.NewReplacer(likeEscapeChar, likeEscapeChar+likeEscapeChar, "%", likeEscapeChar+"%", "_", likeEscapeChar+"_")
+
+// likeBindValue escapes LIKE wildcards of v and wraps it with %. e.g) 10%_off -> %10\%\_off%
+func likeBindValue(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	return "%" + likeEscaper.Replace(asString(rv.Interface())) + "%", nil
+}
+
+// parseColumnBind splits bind declaration into name and modifier (or type annotation)
+func parseColumnBind(declare string) (string, string, error) {
+	index := strings.Index(declare, bindModifierDelim)
+	if index < 0 {
+		return declare, "", nil
+	}
+
+	name := strings.TrimSpace(declare[:index])
+	modifier := strings.ToLower(strings.TrimSpace(declare[index+1:]))
+	_, isModifier := bindModifiers[modifier]
+	if _, isType := bindTypes[modifier]; !isModifier && !isType {
+		return name, modifier, fmt.Errorf("unknown bind modifier %s of %s", modifier, name)
+	}
+	return name, modifier, nil
+}
+
+func jsonBindValue(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// expectBindKind returns modifier passing v as it is when v is nil or kind of expected type.
+// pointer is dereferenced and driver.Valuer is checked with its value
+func expectBindKind(expected string) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		checking := v
+		if valuer, ok := v.(driver.Valuer); ok {
+			value, err := valuer.Value()
+			if err != nil {
+				return nil, err
+			}
+			checking = value
+		}
+
+		rv := reflect.ValueOf(checking)
+		for rv.IsValid() && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return v, nil
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() || isBindKind(expected, rv) {
+			return v, nil
+		}
+		return nil, fmt.Errorf("expect %s but %s", expected, rv.Type())
+	}
+}
+
+func isBindKind(expected string, rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return expected == bindModifierInt || expected == bindModifierFloat
+	case reflect.Float32, reflect.Float64:
+		return expected == bindModifierFloat
+	case reflect.String:
+		return expected == bindModifierString
+	case reflect.Bool:
+		return expected == bindModifierBool
+	case reflect.Slice:
+		return expected == bindModifierBytes && rv.Type().Elem().Kind() == reflect.Uint8
+	case reflect.Struct:
+		return expected == bindModifierTime && rv.Type() == reflect.TypeOf(time.Time{})
+	}
+	return false
+}
+
+// modifyBindValue verifies v with the type annotation of bind and converts v with the modifier of bind
+func modifyBindValue(bind ColumnBind, v interface{}) (interface{}, error) {
+	if len(bind.expect) > 0 {
+		if _, err := bindTypes[bind.expect](v); err != nil {
+			return nil, fmt.Errorf("fail to bind %s as %s : %s", bind.name, bind.expect, err.Error())
+		}
+	}
+	if len(bind.modifier) == 0 {
+		return v, nil
+	}
+
+	converted, err := bindModifiers[bind.modifier](v)
+	if err != nil {
+		return nil, fmt.Errorf("fail to bind %s as %s : %s", bind.name, bind.modifier, err.Error())
+	}
+	return converted, nil
+}
+
+// checkBindElements verifies each element of IN array bind with the type annotation of bind
+func checkBindElements(bind ColumnBind, elements []interface{}) error {
+	if len(bind.expect) == 0 {
+		return nil
+	}
+
+	for i, e := range elements {
+		if _, err := bindTypes[bind.expect](e); err != nil {
+			return fmt.Errorf("fail to bind %s[%d] as %s : %s", bind.name, i, bind.expect, err.Error())
+		}
+	}
+	return nil
+}
+
+// modifyBindValues converts positional args aligned with column binds
+func modifyBindValues(stmt QueryStatement, args []interface{}) ([]interface{}, error) {
+	if !stmt.hasBindModifier() {
+		return args, nil
+	}
+
+	modified := make([]interface{}, len(args))
+	copy(modified, args)
+	for i, bind := range stmt.columnMention {
+		if i >= len(modified) {
+			break
+		}
+
+		v, err := modifyBindValue(bind, modified[i])
+		if err != nil {
+			return nil, err
+		}
+		modified[i] = v
+	}
+	return modified, nil
+}
@@ -21,36 +21,185 @@
 package queryman
 
 import (
+	"context"
 	"database/sql"
-	"database/sql/driver"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 type Bulk interface {
 	AddBatch(params ...interface{}) error
 	Execute() (sql.Result, error)
+	// CommitEvery commits every n rows in its own transaction while executing.
+	// only available for bulk created from QueryMan (not in transaction)
+	CommitEvery(n int) Bulk
+	// AddSeq adds rows pulled lazily from seq while executing, so rows are never held at once.
+	// seq is compatible with iter.Seq[any] and each item is handled like AddBatch(item).
+	// typed iter.Seq[T] is adapted by BulkSeq (go1.23 or later)
+	AddSeq(seq func(yield func(interface{}) bool)) Bulk
+	// BatchSize sets the number of rows in one multi-row insert. default 1000 for AddSeq
+	BatchSize(n int) Bulk
+	// DynamicColumns omits columns whose parameter is nil or missing from map parameter (absent) so that column defaults apply.
+	// rows are grouped by their present columns and each group is inserted with its own column list
+	DynamicColumns() Bulk
+	// Upsert appends conflict clause of the dialect to the multi-row insert.
+	// mysql : ON DUPLICATE KEY UPDATE (conflictColumns are not used), postgresql : ON CONFLICT (conflictColumns) DO UPDATE.
+	// postgresql without updateColumns is DO NOTHING
+	Upsert(conflictColumns []string, updateColumns ...string) Bulk
+	// Returning appends RETURNING idColumn to the multi-row insert and collects the returned ids
+	// into GetInsertIdList of ExecMultiResult, same as ids of exec path. e.g) postgresql
+	Returning(idColumn string) Bulk
+	// OnProgress calls fn with inserted and total rows after executed chunks, at most once per every rows
+	// and always when all rows are done. total is -1 with AddSeq. every <= 0 reports each chunk
+	OnProgress(every int, fn func(done, total int)) Bulk
+}
+
+const defaultBulkBatchSize = 1000
+
+// BulkCommitError reports rows committed durably before bulk execution failed
+type BulkCommitError struct {
+	Committed int
+	Err       error
+}
+
+func (e *BulkCommitError) Error() string {
+	return fmt.Sprintf("bulk failed after %d rows committed : %s", e.Committed, e.Err.Error())
+}
+
+func (e *BulkCommitError) Unwrap() error {
+	return e.Err
 }
 
 func newQuerymanBulk(sqlProxy SqlProxy, stmt QueryStatement) *querymanBulk {
 	b := &querymanBulk{}
 	b.sqlProxy = sqlProxy
 	b.stmt = stmt
-	b.params = make([]interface{}, 0)
-	stmt.HasCondition()
+	b.rows = make([][]interface{}, 0)
 	return b
 }
 
 type querymanBulk struct {
-	stmt      QueryStatement
-	sqlProxy  SqlProxy
-	params    []interface{}
-	execCount int
+	stmt           QueryStatement
+	sqlProxy       SqlProxy
+	rows           [][]interface{}
+	seqs           []func(yield func(interface{}) bool)
+	commitEvery    int
+	batchSize      int
+	dynamicColumns bool
+	upsert         *upsertColumns
+	returning      string
+	onProgress     func(done, total int)
+	progressEvery  int
+	reported       int // done rows of the last progress report
+}
+
+type upsertColumns struct {
+	conflict []string
+	update   []string
 }
 
 func (b *querymanBulk) String() string {
-	return fmt.Sprintf("stmt=[%s], execCount=[%d], params.len=[%d]", b.stmt.Query, b.execCount, len(b.params))
+	return fmt.Sprintf("stmt=[%s], execCount=[%d], commitEvery=[%d]", b.stmt.Query, len(b.rows), b.commitEvery)
+}
+
+func (b *querymanBulk) CommitEvery(n int) Bulk {
+	b.commitEvery = n
+	return b
+}
+
+func (b *querymanBulk) AddSeq(seq func(yield func(interface{}) bool)) Bulk {
+	b.seqs = append(b.seqs, seq)
+	return b
+}
+
+func (b *querymanBulk) BatchSize(n int) Bulk {
+	b.batchSize = n
+	return b
+}
+
+func (b *querymanBulk) DynamicColumns() Bulk {
+	b.dynamicColumns = true
+	return b
+}
+
+func (b *querymanBulk) Upsert(conflictColumns []string, updateColumns ...string) Bulk {
+	b.upsert = &upsertColumns{conflict: conflictColumns, update: updateColumns}
+	return b
+}
+
+func (b *querymanBulk) Returning(idColumn string) Bulk {
+	b.returning = idColumn
+	return b
+}
+
+func (b *querymanBulk) OnProgress(every int, fn func(done, total int)) Bulk {
+	b.progressEvery = every
+	b.onProgress = fn
+	return b
+}
+
+// reportProgress calls OnProgress callback when done rows reach the next interval or all rows
+func (b *querymanBulk) reportProgress(done int) {
+	if b.onProgress == nil {
+		return
+	}
+
+	total := len(b.rows)
+	if len(b.seqs) > 0 {
+		total = -1
+	}
+	if done-b.reported < b.progressEvery && done != total {
+		return
+	}
+	b.reported = done
+	b.onProgress(done, total)
+}
+
+// finishProgress reports done rows once more when the last chunk is not reported yet.
+// total of seqs is unknown so the last chunk may not reach the interval
+func (b *querymanBulk) finishProgress(done int) {
+	if b.onProgress == nil || done == b.reported {
+		return
+	}
+
+	total := len(b.rows)
+	if len(b.seqs) > 0 {
+		total = -1
+	}
+	b.reported = done
+	b.onProgress(done, total)
+}
+
+func (b *querymanBulk) chunkSize() int {
+	size := defaultBulkBatchSize
+	if b.commitEvery > 0 {
+		size = b.commitEvery
+	} else if b.batchSize > 0 {
+		size = b.batchSize
+	}
+	if limit := b.rowsPerStatement(); size > limit {
+		return limit
+	}
+	return size
+}
+
+// rowsPerStatement returns rows of a multi-row insert under placeholder limit of the database
+func (b *querymanBulk) rowsPerStatement() int {
+	perRow := len(b.stmt.columnMention)
+	if len(b.rows) > 0 && len(b.rows[0]) > perRow {
+		perRow = len(b.rows[0])
+	}
+	if perRow == 0 {
+		return defaultBulkBatchSize
+	}
+
+	rows := maxPlaceholders(b.sqlProxy) / perRow
+	if rows < 1 {
+		return 1
+	}
+	return rows
 }
 
 func (b *querymanBulk) AddBatch(params ...interface{}) (err error) {
@@ -64,6 +213,10 @@ func (b *querymanBulk) AddBatch(params ...interface{}) (err error) {
 		}
 	}()
 
+	if m, ok := findBoundParams(params); ok {
+		return b.addWithMap(m)
+	}
+
 	atype := reflect.TypeOf(params[0])
 	val := params[0]
 
@@ -83,9 +236,11 @@ func (b *querymanBulk) AddBatch(params ...interface{}) (err error) {
 	case reflect.Ptr:
 		return ErrPtrIsNotSupported
 	case reflect.Slice, reflect.Array:
-		return b.addList(val)
+		if !b.stmt.firstArgsHasModifier() && !isBindConverted(params[0]) {
+			return b.addList(val)
+		}
 	case reflect.Struct:
-		if _, is := val.(driver.Valuer); !is {
+		if !isValueStruct(val) {
 			return b.addWithObject(val)
 		}
 	case reflect.Map:
@@ -106,9 +261,198 @@ func (b *querymanBulk) Execute() (sql.Result, error) {
 }
 
 func (b *querymanBulk) executeInsert() (sql.Result, error) {
-	bulkInsertQuery := findValuesClauseInInsert(b.stmt.Query)
-	sql := bulkInsertQuery.buildMultiValueQuery(b.execCount)
-	return b.sqlProxy.exec(sql, b.params...)
+	b.reported = 0
+	if b.dynamicColumns {
+		return b.executeInsertDynamic()
+	}
+
+	if b.commitEvery <= 0 && b.batchSize <= 0 && len(b.seqs) == 0 && len(b.rows) <= b.rowsPerStatement() {
+		result, err := b.execInsertRows(b.sqlProxy, b.rows)
+		if err == nil {
+			b.reportProgress(len(b.rows))
+		}
+		return result, err
+	}
+
+	return b.executeInsertChunked()
+}
+
+// executeInsertChunked executes rows and rows pulled from seqs in chunks of chunkSize.
+// with CommitEvery, each chunk is committed in its own transaction
+func (b *querymanBulk) executeInsertChunked() (sql.Result, error) {
+	var man *QueryMan
+	if b.commitEvery > 0 {
+		m, ok := b.sqlProxy.(*QueryMan)
+		if !ok {
+			return nil, fmt.Errorf("CommitEvery is not available in transaction or on replica")
+		}
+		man = m
+	}
+
+	result := ExecMultiResult{}
+	committed := 0
+	flush := func(rows [][]interface{}) error {
+		var res sql.Result
+		var err error
+		if man != nil {
+			res, err = b.commitInsertRows(man, rows)
+		} else {
+			res, err = b.execInsertRows(b.sqlProxy, rows)
+		}
+		if err != nil {
+			if b.sqlProxy.isTransaction() {
+				return err
+			}
+			return &BulkCommitError{Committed: committed, Err: err}
+		}
+
+		(&result).merge(res)
+		committed += len(rows)
+		b.reportProgress(committed)
+		return nil
+	}
+
+	chunkSize := b.chunkSize()
+	rows := b.rows
+	for len(rows) > 0 {
+		n := chunkSize
+		if n > len(rows) {
+			n = len(rows)
+		}
+		if err := flush(rows[:n]); err != nil {
+			return result, err
+		}
+		rows = rows[n:]
+	}
+
+	for _, seq := range b.seqs {
+		pending := newQuerymanBulk(b.sqlProxy, b.stmt)
+		var err error
+		seq(func(item interface{}) bool {
+			if err = pending.AddBatch(item); err != nil {
+				return false
+			}
+			if len(pending.rows) >= chunkSize {
+				err = flush(pending.rows)
+				pending.rows = make([][]interface{}, 0)
+			}
+			return err == nil
+		})
+		if err == nil && len(pending.rows) > 0 {
+			err = flush(pending.rows)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	b.finishProgress(committed)
+	return result, nil
+}
+
+func (b *querymanBulk) commitInsertRows(man *QueryMan, rows [][]interface{}) (sql.Result, error) {
+	tx, err := man.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.execInsertRows(tx, rows)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return res, tx.Commit()
+}
+
+func (b *querymanBulk) execInsertRows(sqlProxy SqlProxy, rows [][]interface{}) (sql.Result, error) {
+	if len(rows) == 0 {
+		return ExecMultiResult{}, nil
+	}
+
+	params := make([]interface{}, 0)
+	for _, row := range rows {
+		params = append(params, row...)
+	}
+
+	return b.execInsertQuery(sqlProxy, b.buildInsertQuery(sqlProxy, len(rows)), params)
+}
+
+func (b *querymanBulk) execInsertQuery(sqlProxy SqlProxy, query string, params []interface{}) (sql.Result, error) {
+	bindCtx := withBindTimer(context.Background())
+	if b.upsert != nil {
+		clause, err := sqlProxy.getNormalizer().upsertClause(b.upsert.conflict, b.upsert.update)
+		if err != nil {
+			return nil, err
+		}
+		query = strings.TrimRight(query, cutset) + " " + clause
+	}
+	if len(b.returning) > 0 {
+		query = strings.TrimRight(query, cutset) + " RETURNING " + b.returning
+	}
+	if sqlProxy.debugEnabled() {
+		sqlProxy.debugPrint("[%s] %s", b.stmt.Id, query)
+	}
+	params, err := bindValues(sqlProxy, params)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := sqlProxy.trackRunning(bindCtx, b.stmt.Id)
+	defer done()
+	if len(b.returning) > 0 {
+		return b.queryInsertReturning(ctx, sqlProxy, query, params)
+	}
+	start := time.Now()
+	result, err := proxyExec(ctx, sqlProxy, b.stmt.Id, query, params...)
+	sqlProxy.recordExcution(b.stmt.Id, bindElapsed(ctx, start), start)
+	if err != nil {
+		return nil, err
+	}
+	recordResultAffected(sqlProxy, b.stmt.Id, result)
+	return result, nil
+}
+
+// queryInsertReturning executes insert with RETURNING clause and collects returned ids.
+// affected row count is the number of returned rows
+func (b *querymanBulk) queryInsertReturning(ctx context.Context, sqlProxy SqlProxy, query string, params []interface{}) (sql.Result, error) {
+	start := time.Now()
+	rows, pstmt, err := proxyQuery(ctx, sqlProxy, b.stmt.Id, query, params...)
+	sqlProxy.recordExcution(b.stmt.Id, bindElapsed(ctx, start), start)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		rows.Close()
+		if pstmt != nil {
+			pstmt.Close()
+		}
+	}()
+
+	result := ExecMultiResult{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("fail to scan returned id : %s", err.Error())
+		}
+		(&result).addInsertId(id)
+		result.rowAffected++
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	recordResultAffected(sqlProxy, b.stmt.Id, result)
+	return result, nil
+}
+
+// buildInsertQuery repeats VALUES clause for rows and resolves placeholders in dialect
+func (b *querymanBulk) buildInsertQuery(sqlProxy SqlProxy, rowCount int) string {
+	if len(b.stmt.HoldedQuery) == 0 {
+		bulkInsertQuery := findValuesClauseInInsert(b.stmt.Query)
+		return bulkInsertQuery.buildMultiValueQuery(rowCount)
+	}
+
+	bulkInsertQuery := findValuesClauseInInsert(b.stmt.HoldedQuery)
+	return sqlProxy.getNormalizer().resolveHolding(bulkInsertQuery.buildMultiValueQuery(rowCount))
 }
 
 func (b *querymanBulk) executeUpdate() (sql.Result, error) {
@@ -116,11 +460,15 @@ func (b *querymanBulk) executeUpdate() (sql.Result, error) {
 	return nil, fmt.Errorf("not support yet (bulk update)")
 }
 
-func (b *querymanBulk) addParams(param ...interface{}) {
-	for _, p := range param {
-		b.params = append(b.params, p)
+func (b *querymanBulk) addParams(param ...interface{}) error {
+	row := make([]interface{}, len(param))
+	copy(row, param)
+	row, err := modifyBindValues(b.stmt, row)
+	if err != nil {
+		return err
 	}
-	b.execCount = b.execCount + 1
+	b.rows = append(b.rows, row)
+	return nil
 }
 
 func (b *querymanBulk) addList(val interface{}) error {
@@ -147,16 +495,21 @@ func (b *querymanBulk) addWithObject(parameter interface{}) error {
 func (b *querymanBulk) addWithMap(m map[string]interface{}) error {
 	passing := make([]interface{}, 0)
 	for _, v := range b.stmt.columnMention {
-		found, ok := m[v.Name()]
+		found, ok, err := findBindValue(b.sqlProxy.getPreference(), m, v.Name())
+		if err != nil {
+			return err
+		}
 		if !ok {
-			return fmt.Errorf("addWithMap : not found \"%s\" from parameter values", v)
+			// with DynamicColumns, missing key is an absent column like nil
+			if !b.dynamicColumns {
+				return fmt.Errorf("addWithMap : not found \"%s\" from parameter values", v)
+			}
+			found = nil
 		}
 		passing = append(passing, found)
 	}
 
-	b.addParams(passing...)
-
-	return nil
+	return b.addParams(passing...)
 }
 
 func (b *querymanBulk) addWithList(args []interface{}) error {
@@ -173,23 +526,25 @@ func (b *querymanBulk) addWithList(args []interface{}) error {
 		val = reflect.ValueOf(val).Elem().Interface()
 	}
 
-	// check nested list
+	// check nested list. slice of modified bind(e.g. json) is a single parameter
 	switch atype.Kind() {
 	case reflect.Slice:
-		return b.addWithNestedList(args)
+		if !b.stmt.firstArgsHasModifier() && !isBindConverted(args[0]) {
+			return b.addWithNestedList(args)
+		}
 	case reflect.Struct:
-		if _, is := val.(driver.Valuer); !is {
+		if !isValueStruct(val) {
 			return b.addWithStructList(args)
 		}
 	case reflect.Map:
 		return b.addWithNestedMap(args)
 	}
 
-	if len(b.stmt.columnMention) > len(args) {
+	if bindCountMismatch(b.sqlProxy.getPreference(), b.stmt, len(args)) {
 		return fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", len(b.stmt.columnMention), len(args))
 	}
 
-	return nil
+	return b.addParams(expandRepeatedBind(b.stmt, args)...)
 }
 
 func (b *querymanBulk) addWithNestedList(args []interface{}) error {
@@ -198,14 +553,16 @@ func (b *querymanBulk) addWithNestedList(args []interface{}) error {
 		if reflect.TypeOf(v).Kind() != reflect.Slice && reflect.TypeOf(v).Kind() != reflect.Array {
 			return fmt.Errorf("nested listing structure should have slice type data only. %d=%s", i, reflect.TypeOf(v).String())
 		}
-		if len(b.stmt.columnMention) > reflect.ValueOf(v).Len() {
+		if bindCountMismatch(b.sqlProxy.getPreference(), b.stmt, reflect.ValueOf(v).Len()) {
 			return fmt.Errorf("binding parameter count mismatch. defined=%d, args[%d]=%d", len(b.stmt.columnMention), i, reflect.ValueOf(v).Len())
 		}
 	}
 
 	for _, v := range args {
-		passing := flattenToList(v)
-		b.addParams(passing...)
+		passing := expandRepeatedBind(b.stmt, flattenToList(v))
+		if err := b.addParams(passing...); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -229,13 +586,18 @@ func (b *querymanBulk) addWithStructList(args []interface{}) error {
 		passing := make([]interface{}, 0)
 
 		for _, v := range b.stmt.columnMention {
-			found, ok := m[v.Name()]
+			found, ok, err := findBindValue(b.sqlProxy.getPreference(), m, v.Name())
+			if err != nil {
+				return err
+			}
 			if !ok {
 				return fmt.Errorf("addWithStructList : not found \"%s\" from parameter values", v)
 			}
 			passing = append(passing, found)
 		}
-		b.addParams(passing...)
+		if err := b.addParams(passing...); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -260,14 +622,19 @@ func (b *querymanBulk) addWithNestedMap(args []interface{}) error {
 
 		passing := make([]interface{}, 0)
 		for _, v2 := range b.stmt.columnMention {
-			found, ok := m[v2.Name()]
+			found, ok, err := findBindValue(b.sqlProxy.getPreference(), m, v2.Name())
+			if err != nil {
+				return err
+			}
 			if !ok {
 				return fmt.Errorf("not found \"%s\" from map", v)
 			}
 			passing = append(passing, found)
 		}
 
-		b.addParams(passing...)
+		if err := b.addParams(passing...); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// executeInsertDynamic groups rows by present (not nil) parameters and inserts each group
+// with the column list of the present parameters
+func (b *querymanBulk) executeInsertDynamic() (sql.Result, error) {
+	if b.commitEvery > 0 || len(b.seqs) > 0 {
+		return nil, fmt.Errorf("DynamicColumns is not available with CommitEvery or AddSeq")
+	}
+
+	insert, err := parseDynamicInsert(b.stmt.HoldedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]string, 0)
+	groups := make(map[string][][]interface{})
+	for _, row := range b.rows {
+		signature := presentSignature(row)
+		if _, ok := groups[signature]; !ok {
+			order = append(order, signature)
+		}
+		groups[signature] = append(groups[signature], row)
+	}
+
+	result := ExecMultiResult{}
+	done := 0
+	for _, signature := range order {
+		rows := groups[signature]
+		for len(rows) > 0 {
+			n := len(rows)
+			if b.batchSize > 0 && n > b.batchSize {
+				n = b.batchSize
+			}
+			if limit := b.rowsPerStatement(); n > limit {
+				n = limit
+			}
+
+			query, params := insert.build(rows[:n])
+			res, err := b.execInsertQuery(b.sqlProxy, b.sqlProxy.getNormalizer().resolveHolding(query), params)
+			if err != nil {
+				return result, err
+			}
+			(&result).merge(res)
+			done += n
+			b.reportProgress(done)
+			rows = rows[n:]
+		}
+	}
+
+	b.finishProgress(done)
+	return result, nil
+}
+
+// presentSignature marks present parameters of row. e.g) "101" for [a, nil, c]
+func presentSignature(row []interface{}) string {
+	signature := make([]byte, len(row))
+	for i, v := range row {
+		signature[i] = '1'
+		if v == nil {
+			signature[i] = '0'
+		}
+	}
+	return string(signature)
+}
+
+// dynamicInsert is INSERT INTO t (c1, c2) VALUES (e1, e2) suffix split by column
+type dynamicInsert struct {
+	prefix  string   // INSERT INTO t
+	columns []string // c1, c2
+	values  []string // e1, e2 (holded)
+	binds   []int    // bind index of values. -1 when value has no bind
+	suffix  string
+}
+
+// parseDynamicInsert parses holded insert query. each value should have one bind at most
+func parseDynamicInsert(holdedQuery string) (dynamicInsert, error) {
+	insert := dynamicInsert{}
+	columnStart := strings.Index(holdedQuery, "(")
+	columnEnd := -1
+	if columnStart >= 0 {
+		columnEnd = findClosingParen(holdedQuery, columnStart)
+	}
+	if columnEnd < 0 {
+		return insert, fmt.Errorf("DynamicColumns needs column list : %s", holdedQuery)
+	}
+
+	valuesIndex := indexKeyword(strings.ToLower(holdedQuery), "values", columnEnd+1)
+	if valuesIndex < 0 {
+		return insert, fmt.Errorf("DynamicColumns needs VALUES clause : %s", holdedQuery)
+	}
+	insert.prefix = holdedQuery[:columnStart]
+	insert.columns = splitTopLevel(holdedQuery[columnStart+1 : columnEnd])
+
+	valueStart := strings.Index(holdedQuery[valuesIndex:], "(") + valuesIndex
+	valueEnd := findClosingParen(holdedQuery, valueStart)
+	if valueStart < valuesIndex || valueEnd < 0 {
+		return insert, fmt.Errorf("invalid VALUES clause : %s", holdedQuery)
+	}
+	insert.values = splitTopLevel(holdedQuery[valueStart+1 : valueEnd])
+	insert.suffix = holdedQuery[valueEnd+1:]
+
+	if len(insert.columns) != len(insert.values) {
+		return insert, fmt.Errorf("column count %d and value count %d are different", len(insert.columns), len(insert.values))
+	}
+
+	bind := 0
+	for _, v := range insert.values {
+		switch strings.Count(v, string(holdByte)) {
+		case 0:
+			insert.binds = append(insert.binds, -1)
+		case 1:
+			insert.binds = append(insert.binds, bind)
+			bind++
+		default:
+			return insert, fmt.Errorf("DynamicColumns needs one parameter per column : %s", v)
+		}
+	}
+	return insert, nil
+}
+
+// build builds holded multi-row insert for rows of the same present signature
+func (d dynamicInsert) build(rows [][]interface{}) (string, []interface{}) {
+	present := rows[0]
+	keep := make([]int, 0, len(d.columns))
+	for i, bind := range d.binds {
+		if bind < 0 || present[bind] != nil {
+			keep = append(keep, i)
+		}
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(d.prefix)
+	buffer.WriteString("(")
+	for i, k := range keep {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(d.columns[k])
+	}
+	buffer.WriteString(") VALUES ")
+
+	params := make([]interface{}, 0, len(rows)*len(keep))
+	for r, row := range rows {
+		if r > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("(")
+		for i, k := range keep {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteString(d.values[k])
+			if d.binds[k] >= 0 {
+				params = append(params, row[d.binds[k]])
+			}
+		}
+		buffer.WriteString(")")
+	}
+	buffer.WriteString(d.suffix)
+	return buffer.String(), params
+}
+
+// splitTopLevel splits s by comma outside of parentheses and trims each part
+func splitTopLevel(s string) []string {
+	parts := make([]string, 0)
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// indexKeyword returns index of keyword (lower case) in lower from the index as a whole word. -1 when not found
+func indexKeyword(lower string, keyword string, from int) int {
+	for i := from; i+len(keyword) <= len(lower); i++ {
+		if lower[i:i+len(keyword)] != keyword {
+			continue
+		}
+		if i > 0 && isKeywordChar(lower[i-1]) {
+			continue
+		}
+		if end := i + len(keyword); end < len(lower) && isKeywordChar(lower[end]) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func findClosingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
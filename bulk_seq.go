@@ -0,0 +1,36 @@
+//go:build go1.23
+
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import "iter"
+
+// BulkSeq adapts typed iter.Seq[T] to the seq of Bulk.AddSeq. e.g) bulk.AddSeq(queryman.BulkSeq(cities))
+func BulkSeq[T any](seq iter.Seq[T]) func(yield func(interface{}) bool) {
+	return func(yield func(interface{}) bool) {
+		for item := range seq {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
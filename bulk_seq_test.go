@@ -0,0 +1,57 @@
+//go:build go1.23
+
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBulkSeq(t *testing.T) {
+	type city struct {
+		Name string
+		Age  int
+	}
+	cities := func(yield func(city) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(city{Name: fmt.Sprintf("city%d", i), Age: i}) {
+				return
+			}
+		}
+	}
+
+	items := make([]interface{}, 0)
+	BulkSeq(cities)(func(item interface{}) bool {
+		items = append(items, item)
+		return len(items) < 2
+	})
+	if len(items) != 2 || items[1].(city).Name != "city2" {
+		t.Fatalf("invalid items of seq : %v", items)
+	}
+
+	b := newQuerymanBulk(nil, QueryStatement{})
+	b.AddSeq(BulkSeq(cities))
+	if len(b.seqs) != 1 {
+		t.Fatalf("seq should be added")
+	}
+}
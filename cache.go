@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats is cumulative counters of user query statement cache
+type CacheStats struct {
+	Size      int
+	Capacity  int
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+type cacheEntry struct {
+	query string
+	stmt  QueryStatement
+}
+
+// statementCache keeps normalized user query statements in LRU order
+type statementCache struct {
+	mutex     sync.Mutex
+	capacity  int
+	entries   map[string]*list.Element
+	order     *list.List
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func newStatementCache(capacity int) *statementCache {
+	c := &statementCache{}
+	c.capacity = capacity
+	c.entries = make(map[string]*list.Element)
+	c.order = list.New()
+	return c
+}
+
+func (c *statementCache) get(query string) (QueryStatement, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[query]
+	if !ok {
+		c.misses++
+		return QueryStatement{}, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).stmt, true
+}
+
+func (c *statementCache) put(query string, stmt QueryStatement) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.entries[query]; ok {
+		e.Value.(*cacheEntry).stmt = stmt
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[query] = c.order.PushFront(&cacheEntry{query: query, stmt: stmt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).query)
+		c.evictions++
+	}
+}
+
+func (c *statementCache) stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return CacheStats{
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// lifecycleConnector wraps driver connector to notify connection lifecycle events of pool
+type lifecycleConnector struct {
+	connector driver.Connector
+	pref      QuerymanPreference
+}
+
+// dsnConnector is the connector of driver which does not implement driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func hasConnLifecycle(pref QuerymanPreference) bool {
+	return pref.OnConnect != nil || pref.OnConnClose != nil || pref.OnConnError != nil
+}
+
+// openLifecycleDB opens db whose connections are wrapped to call OnConnect, OnConnClose and OnConnError
+func openLifecycleDB(pref QuerymanPreference, dataSourceUrl string) (*sql.DB, error) {
+	db, err := sql.Open(pref.DriverName, dataSourceUrl)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dataSourceUrl, driver: drv}
+	if driverContext, ok := drv.(driver.DriverContext); ok {
+		connector, err = driverContext.OpenConnector(dataSourceUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.OpenDB(&lifecycleConnector{connector: connector, pref: pref}), nil
+}
+
+func (c *lifecycleConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if c.pref.OnConnect != nil {
+		c.pref.OnConnect(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lifecycleConn{conn: conn, pref: c.pref}, nil
+}
+
+func (c *lifecycleConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// lifecycleConn forwards optional interfaces of driver connection with the fallback database/sql expects
+type lifecycleConn struct {
+	conn driver.Conn
+	pref QuerymanPreference
+}
+
+func (c *lifecycleConn) notify(err error) error {
+	if c.pref.OnConnError != nil && errors.Is(err, driver.ErrBadConn) {
+		c.pref.OnConnError(err)
+	}
+	return err
+}
+
+func (c *lifecycleConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.conn.Prepare(query)
+	return stmt, c.notify(err)
+}
+
+func (c *lifecycleConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err := preparer.PrepareContext(ctx, query)
+		return stmt, c.notify(err)
+	}
+	return c.Prepare(query)
+}
+
+func (c *lifecycleConn) Close() error {
+	err := c.conn.Close()
+	if c.pref.OnConnClose != nil {
+		c.pref.OnConnClose(err)
+	}
+	return err
+}
+
+func (c *lifecycleConn) Begin() (driver.Tx, error) {
+	tx, err := c.conn.Begin()
+	return tx, c.notify(err)
+}
+
+func (c *lifecycleConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err := beginner.BeginTx(ctx, opts)
+		return tx, c.notify(err)
+	}
+	if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("driver does not support transaction options")
+	}
+	return c.Begin()
+}
+
+func (c *lifecycleConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.conn.(driver.ExecerContext); ok {
+		result, err := execer.ExecContext(ctx, query, args)
+		return result, c.notify(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *lifecycleConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.conn.(driver.QueryerContext); ok {
+		rows, err := queryer.QueryContext(ctx, query, args)
+		return rows, c.notify(err)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *lifecycleConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return c.notify(pinger.Ping(ctx))
+	}
+	return nil
+}
+
+func (c *lifecycleConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return c.notify(resetter.ResetSession(ctx))
+	}
+	return nil
+}
+
+func (c *lifecycleConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *lifecycleConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
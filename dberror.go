@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrorClassifier maps driver error to error kind (ErrUniqueViolation, ErrDeadlock, ...) and driver error code.
+// nil kind means the error is not classified
+type ErrorClassifier func(err error) (kind error, code string)
+
+// DBError wraps driver error classified by ErrorClassifier.
+// errors.Is(err, ErrUniqueViolation) or errors.As(err, &dbError) works on errors returned by queryman
+type DBError struct {
+	Kind error  // ErrUniqueViolation, ErrForeignKeyViolation, ...
+	Code string // driver error code. e.g) 1062 of mysql, 23505 of postgresql
+	Err  error  // driver error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DBError) Is(target error) bool {
+	return e.Kind == target
+}
+
+var mysqlErrorKinds = map[uint64]error{
+	1062: ErrUniqueViolation,     // ER_DUP_ENTRY
+	1451: ErrForeignKeyViolation, // ER_ROW_IS_REFERENCED_2
+	1452: ErrForeignKeyViolation, // ER_NO_REFERENCED_ROW_2
+	1048: ErrNotNullViolation,    // ER_BAD_NULL_ERROR
+	1213: ErrDeadlock,            // ER_LOCK_DEADLOCK
+	1205: ErrLockTimeout,         // ER_LOCK_WAIT_TIMEOUT
+}
+
+var postgresErrorKinds = map[string]error{
+	"23505": ErrUniqueViolation,     // unique_violation
+	"23503": ErrForeignKeyViolation, // foreign_key_violation
+	"23502": ErrNotNullViolation,    // not_null_violation
+	"40P01": ErrDeadlock,            // deadlock_detected
+	"55P03": ErrLockTimeout,         // lock_not_available
+}
+
+// newErrorClassifier returns ErrorClassifier of pref or the default classifier of driver
+func newErrorClassifier(pref QuerymanPreference) ErrorClassifier {
+	if pref.ErrorClassifier != nil {
+		return pref.ErrorClassifier
+	}
+
+	switch strings.ToLower(pref.DriverName) {
+	case "mysql":
+		return classifyMysqlError
+	case "postgres", "postgresql", "pgx":
+		return classifyPostgresError
+	}
+	return nil
+}
+
+func classifyMysqlError(err error) (error, string) {
+	number, ok := mysqlErrorNumber(err)
+	if !ok {
+		return nil, ""
+	}
+	return mysqlErrorKinds[number], fmt.Sprintf("%d", number)
+}
+
+func classifyPostgresError(err error) (error, string) {
+	var stater interface{ SQLState() string }
+	if !errors.As(err, &stater) {
+		return nil, ""
+	}
+	return postgresErrorKinds[stater.SQLState()], stater.SQLState()
+}
+
+// mysqlErrorNumber finds Number of mysql driver error without importing the driver
+func mysqlErrorNumber(err error) (uint64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		val := reflect.ValueOf(err)
+		if val.Kind() == reflect.Ptr {
+			val = val.Elem()
+		}
+		if val.Kind() != reflect.Struct {
+			continue
+		}
+
+		number := val.FieldByName("Number")
+		switch number.Kind() {
+		case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return number.Uint(), true
+		case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+			return uint64(number.Int()), true
+		}
+	}
+	return 0, false
+}
+
+// classifyError wraps driver error with DBError when classifier of the driver knows it
+func classifyError(sqlProxy SqlProxy, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var dbError *DBError
+	if errors.As(err, &dbError) {
+		return err
+	}
+
+	pref := sqlProxy.getPreference()
+	if pref == nil {
+		return err
+	}
+
+	classifier := newErrorClassifier(*pref)
+	if classifier == nil {
+		return err
+	}
+
+	kind, code := classifier(err)
+	if kind == nil {
+		return err
+	}
+	return &DBError{Kind: kind, Code: code, Err: err}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	mysqlDeadlockErrorNumber = 1213
+	postgresDeadlockSqlState = "40P01"
+)
+
+// newDeadlockDetector returns DeadlockDetector of pref or the default detector of driver
+func newDeadlockDetector(pref QuerymanPreference) func(err error) bool {
+	if pref.DeadlockDetector != nil {
+		return pref.DeadlockDetector
+	}
+
+	switch strings.ToLower(pref.DriverName) {
+	case "mysql":
+		return isMysqlDeadlock
+	case "postgres", "postgresql", "pgx":
+		return isPostgresDeadlock
+	}
+	return nil
+}
+
+// isMysqlDeadlock checks error number 1213 of mysql driver error without importing the driver
+func isMysqlDeadlock(err error) bool {
+	number, ok := mysqlErrorNumber(err)
+	return ok && number == mysqlDeadlockErrorNumber
+}
+
+// isPostgresDeadlock checks sql state 40P01 of lib/pq or pgx error
+func isPostgresDeadlock(err error) bool {
+	var stater interface{ SQLState() string }
+	if errors.As(err, &stater) && stater.SQLState() == postgresDeadlockSqlState {
+		return true
+	}
+	return false
+}
+
+// retryOnDeadlock runs fn again while it fails with deadlock up to RetryOnDeadlock times.
+// statement in transaction is never retried because the transaction is already rolled back
+func retryOnDeadlock(ctx context.Context, sqlProxy SqlProxy, stmtId string, fn func() error) error {
+	err := fn()
+	pref := sqlProxy.getPreference()
+	if err == nil || pref == nil || pref.RetryOnDeadlock <= 0 || sqlProxy.isTransaction() {
+		return err
+	}
+
+	detector := newDeadlockDetector(*pref)
+	if detector == nil {
+		return err
+	}
+
+	for attempt := 1; attempt <= pref.RetryOnDeadlock && detector(err); attempt++ {
+		sqlProxy.debugPrint("[%s] deadlock detected. retry %d/%d", stmtId, attempt, pref.RetryOnDeadlock)
+		if pref.DeadlockBackoff > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(pref.DeadlockBackoff * time.Duration(attempt)):
+			}
+		}
+		err = fn()
+	}
+	return err
+}
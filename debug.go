@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	DebugFormatText = "text"
+	DebugFormatJson = "json"
+)
+
+// DebugEvent is a statement execution logged as json with DebugFormat json
+type DebugEvent struct {
+	StmtId        string        `json:"stmtId"`
+	Query         string        `json:"query"`
+	Params        []interface{} `json:"params,omitempty"`
+	ElapsedMillis float64       `json:"elapsedMillis"`
+	RowsAffected  *int64        `json:"rowsAffected,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	Caller        string        `json:"caller,omitempty"`
+}
+
+func isDebugJson(pref *QuerymanPreference) bool {
+	return pref != nil && pref.Debug && pref.DebugFormat == DebugFormatJson
+}
+
+// emitDebugEvent logs executed statement as json. result is nil for query
+func emitDebugEvent(sqlProxy SqlProxy, stmtId string, query string, params []interface{}, start time.Time, result sql.Result, err error) {
+	pref := sqlProxy.getPreference()
+	if !isDebugJson(pref) {
+		return
+	}
+
+	event := DebugEvent{}
+	event.StmtId = stmtId
+	event.Query = query
+	event.Params = maskParams(pref, stmtId, params)
+	event.ElapsedMillis = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		event.Error = err.Error()
+	} else if result != nil {
+		if affected, affectedErr := result.RowsAffected(); affectedErr == nil {
+			event.RowsAffected = &affected
+		}
+	}
+	if pref.DebugCallerLocation {
+		event.Caller = findCallerLocation()
+	}
+
+	b, marshalErr := json.Marshal(event)
+	if marshalErr != nil {
+		// parameter may not be marshaled
+		event.Params = nil
+		b, _ = json.Marshal(event)
+	}
+	pref.debugLogger().Printf("%s", b)
+}
+
+// maskParams applies ParamMasker to params for logging
+func maskParams(pref *QuerymanPreference, stmtId string, params []interface{}) []interface{} {
+	if pref == nil || pref.ParamMasker == nil || len(params) == 0 {
+		return params
+	}
+
+	masked := make([]interface{}, len(params))
+	for i, v := range params {
+		masked[i] = pref.ParamMasker(stmtId, i, v)
+	}
+	return masked
+}
@@ -22,6 +22,7 @@ package queryman
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -35,6 +36,7 @@ const (
 	eleTypeUpdate
 	eleTypeSelect
 	eleTypeIf
+	eleTypeOrderBy
 )
 
 type declareElementType uint8
@@ -49,6 +51,8 @@ func (d declareElementType) String() string {
 		return "SELECT"
 	case eleTypeIf:
 		return "IF"
+	case eleTypeOrderBy:
+		return "ORDERBY"
 	}
 	return "UNKNOWN"
 }
@@ -72,38 +76,68 @@ func buildElementType(stmt string) declareElementType {
 		return eleTypeUpdate
 	case "if":
 		return eleTypeIf
+	case "orderby":
+		return eleTypeOrderBy
 	}
 	return eleTypeUnknown
 }
 
 var (
-	ErrInterfaceIsNotSupported    = errors.New("not supported type : interface")
-	ErrPtrIsNotSupported          = errors.New("not supported type : ptr")
-	ErrNeedStructSliceParam       = errors.New("parameter not supported type : struct slice/array only")
-	ErrInvalidMapKeyType          = errors.New("map key should be string")
-	ErrInvalidMapType             = errors.New("map only accepted [string]interface{} type")
-	ErrExecutionInvalidSqlType    = errors.New("invalid execution for sql. only insert or update permitted")
-	ErrQueryInvalidSqlType        = errors.New("invalid query for sql. only select permitted")
-	ErrQueryInsufficientParameter = errors.New("insufficient query parameter for select result")
-	ErrQueryNeedsPtrParameter     = errors.New("when you select in query, you have to pass parameter as ptr")
-	ErrNilPtr                     = errors.New("destination pointer is nil")
-	ErrNoRows                     = errors.New("sql: no rows in result set")
-	ErrNoInsertId                 = errors.New("sql: no insert id")
+	ErrInterfaceIsNotSupported       = errors.New("not supported type : interface")
+	ErrPtrIsNotSupported             = errors.New("not supported type : ptr")
+	ErrNeedStructSliceParam          = errors.New("parameter not supported type : struct slice/array only")
+	ErrInvalidMapKeyType             = errors.New("map key should be string")
+	ErrInvalidMapType                = errors.New("map only accepted [string]interface{} type")
+	ErrExecutionInvalidSqlType       = errors.New("invalid execution for sql. only insert or update permitted")
+	ErrQueryInvalidSqlType           = errors.New("invalid query for sql. only select permitted")
+	ErrQueryInsufficientParameter    = errors.New("insufficient query parameter for select result")
+	ErrQueryNeedsPtrParameter        = errors.New("when you select in query, you have to pass parameter as ptr")
+	ErrNilPtr                        = errors.New("destination pointer is nil")
+	ErrNilTransaction                = errors.New("transaction is nil")
+	ErrNoRows                        = errors.New("sql: no rows in result set")
+	ErrNoInsertId                    = errors.New("sql: no insert id")
+	ErrPartialUpdateNoKey            = errors.New("partial update needs at least one key field")
+	ErrPartialUpdateNeedStruct       = errors.New("partial update only accepts struct or struct ptr")
+	ErrPartialUpdateNoColumn         = errors.New("partial update has no field to set")
+	ErrInsertStructNeedStruct        = errors.New("insert struct only accepts struct or struct ptr")
+	ErrInsertStructNoColumn          = errors.New("insert struct has no field to insert")
+	ErrUpdateStructNeedStruct        = errors.New("update struct only accepts struct or struct ptr")
+	ErrUpdateStructNoColumn          = errors.New("update struct has no field to set")
+	ErrUpdateStructNoKey             = errors.New("update struct needs at least one field tagged with pk. e.g) db:\"id,pk\"")
+	ErrInsertReturningInvalidSqlType = errors.New("invalid insert returning for sql. only insert permitted")
+	ErrBoundParamsType               = errors.New("bound params only accepts map or struct")
+	ErrProcedureNotSupported         = errors.New("stored procedure call is not supported by the driver")
+	ErrScanPositionalNeedStruct      = errors.New("positional scan only accepts struct ptr")
+	ErrMultiStatement                = errors.New("user query has several statements. use a transaction or MultiStatementExec preference")
+	ErrScanAllNeedSlice              = errors.New("scan all only accepts slice ptr")
+	ErrMaxScanRowsExceeded           = errors.New("rows exceed MaxScanRows")
+	ErrUniqueViolation               = errors.New("unique constraint violation")
+	ErrForeignKeyViolation           = errors.New("foreign key constraint violation")
+	ErrNotNullViolation              = errors.New("not null constraint violation")
+	ErrDeadlock                      = errors.New("deadlock detected")
+	ErrLockTimeout                   = errors.New("lock wait timeout")
+	ErrRowResultClosed               = errors.New("row result is already closed")
+	ErrIdentifierNotAllowed          = errors.New("identifier is not in identifiers of the statement")
+	ErrTooManyPlaceholders           = errors.New("parameters exceed placeholder limit of the database")
 )
 
 type SqlProxy interface {
-	exec(query string, args ...interface{}) (sql.Result, error)
-	query(query string, args ...interface{}) (*sql.Rows, error)
-	queryRow(query string, args ...interface{}) *sql.Row
-	prepare(query string) (*sql.Stmt, error)
+	exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
+	prepare(ctx context.Context, query string) (*sql.Stmt, error)
 	isTransaction() bool
+	getPreference() *QuerymanPreference
+	getNormalizer() QueryNormalizer
 	SqlDebugger
 }
 
 type SqlDebugger interface {
 	debugEnabled() bool
 	debugPrint(string, ...interface{})
-	recordExcution(stmtId string, start time.Time)
+	recordExcution(stmtId string, bind time.Duration, start time.Time)
+	recordAffected(stmtId string, affected int64)
+	trackRunning(ctx context.Context, stmtId string) (context.Context, context.CancelFunc)
 }
 
 type QueryStatementFinder interface {
@@ -117,6 +151,13 @@ type QueryStatement struct {
 	clause        []IfClause `xml:"if"`
 	columnMention []ColumnBind
 	HoldedQuery   string
+	timeout       time.Duration // declared by timeout attribute. e.g) timeout="30s"
+	target        string        // pool declared by target attribute. e.g) target="primary"
+	dialect       string        // dialect declared by dialect attribute. empty is the default of all dialects
+	softDelete    bool          // {softDeleteFilter} marker is SoftDeleteFilter. declared by softDelete attribute
+	cacheTTL      time.Duration // rows are cached by parameters for the duration. declared by cacheTTL attribute
+	identifiers   []string      // allowlist of {{name}} identifier binds. declared by identifiers attribute
+	userQuery     bool          // built from user query (not declared in xml). only ? of user query is rewritten
 }
 
 func (q QueryStatement) hasArrayBind() bool {
@@ -142,6 +183,12 @@ func (q QueryStatement) firstArgsIsArray() bool {
 	return false
 }
 
+// firstArgsHasModifier reports whether the first bind takes a slice parameter as single value (e.g. {Tags:json})
+// so that the slice is not a list of parameters
+func (q QueryStatement) firstArgsHasModifier() bool {
+	return len(q.columnMention) > 0 && q.columnMention[0].modifier == bindModifierJson
+}
+
 func (q QueryStatement) String() string {
 	return fmt.Sprintf("eleType=[%s], id=[%s], query=[%s], caluse=[%v], columns=[%v], hold=[%s]",
 		q.eleType, q.Id, q.Query, q.clause, q.columnMention, q.HoldedQuery)
@@ -168,6 +215,8 @@ type ColumnBind struct {
 	name     string
 	holdPos  int
 	bindType columnBindType
+	modifier string // e.g) json for {Payload:json}
+	expect   string // type annotation. e.g) int for {Age:int}
 }
 
 func NewColumnBind(name string, pos int) ColumnBind {
@@ -186,6 +235,15 @@ func NewColumnBindArray(name string, pos int) ColumnBind {
 	return b
 }
 
+func (q QueryStatement) hasBindModifier() bool {
+	for _, v := range q.columnMention {
+		if len(v.modifier) > 0 || len(v.expect) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (c ColumnBind) Name() string {
 	return c.name
 }
@@ -200,6 +258,8 @@ func (stmt QueryStatement) clone() QueryStatement {
 	clone.Id = stmt.Id
 	clone.Query = stmt.Query
 	clone.HoldedQuery = stmt.HoldedQuery
+	clone.timeout = stmt.timeout
+	clone.userQuery = stmt.userQuery
 	clone.clause = make([]IfClause, 0)
 	for _, v := range stmt.clause {
 		clone.clause = append(clone.clause, v)
@@ -224,8 +284,9 @@ func (stmt QueryStatement) Debug(param ...interface{}) string {
 	return buffer.String()
 }
 
+// HasCondition reports the query is resolved with parameters on each execution (if clauses or identifier binds)
 func (stmt QueryStatement) HasCondition() bool {
-	if len(stmt.clause) > 0 {
+	if len(stmt.clause) > 0 || len(stmt.identifiers) > 0 {
 		return true
 	}
 	return false
@@ -233,30 +294,48 @@ func (stmt QueryStatement) HasCondition() bool {
 
 // if condition 처리를 통해 SQL 을 재구성한다
 func (stmt QueryStatement) RefineStatement(params map[string]interface{}) (QueryStatement, error) {
+	return stmt.refine(queryNormalizer, params)
+}
+
+func (stmt QueryStatement) refine(normalizer QueryNormalizer, params map[string]interface{}) (QueryStatement, error) {
 	refined := stmt.clone()
-	for _, v := range stmt.clause {
-		if params == nil {
-			refined.Query = strings.Replace(refined.Query, v.id, "", -1)
-			continue
-		}
+	var buffer bytes.Buffer
+	writeIfClause(&buffer, stmt.Query, stmt.clause, params)
+	query, err := bindIdentifiers(normalizer, buffer.String(), stmt.identifiers, params)
+	if err != nil {
+		return refined, err
+	}
+	refined.Query = query
+	err = normalizer.normalize(&refined)
+	return refined, err
+}
 
-		_, ok := params[v.key]
-		if v.exist {
-			if ok {
-				refined.Query = strings.Replace(refined.Query, v.id, v.query, -1)
-			} else {
-				refined.Query = strings.Replace(refined.Query, v.id, "", -1)
-			}
-		} else {
-			if !ok {
-				refined.Query = strings.Replace(refined.Query, v.id, v.query, -1)
-			} else {
-				refined.Query = strings.Replace(refined.Query, v.id, "", -1)
+// writeIfClause writes query into buffer in a single pass. each if clause id in query is replaced with
+// its own query (nested clauses resolved recursively) when accepted by params or removed
+func writeIfClause(buffer *bytes.Buffer, query string, clause []IfClause, params map[string]interface{}) {
+	for {
+		start := strings.Index(query, ifClauseWrappingKey)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(query[start+1:], ifClauseWrappingKey)
+		if end < 0 {
+			break
+		}
+		end = start + 1 + end + 1
+
+		buffer.WriteString(query[:start])
+		for _, v := range clause {
+			if v.id == query[start:end] {
+				if v.accept(params) {
+					writeIfClause(buffer, v.query, v.clause, params)
+				}
+				break
 			}
 		}
+		query = query[end:]
 	}
-	err := queryNormalizer.normalize(&refined)
-	return refined, err
+	buffer.WriteString(query)
 }
 
 func (stmt *QueryStatement) appendIf(clause IfClause) {
@@ -264,10 +343,20 @@ func (stmt *QueryStatement) appendIf(clause IfClause) {
 }
 
 type IfClause struct {
-	id    string
-	key   string
-	query string
-	exist bool
+	id     string
+	key    string
+	query  string
+	exist  bool
+	clause []IfClause // nested if clauses referred by id in query
+}
+
+func (c IfClause) accept(params map[string]interface{}) bool {
+	if params == nil {
+		return false
+	}
+
+	_, ok := params[c.key]
+	return ok == c.exist
 }
 
 func newIfClause(key string, sql string, exist string) IfClause {
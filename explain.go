@@ -0,0 +1,233 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type explainPrefixKey struct{}
+
+// estimatePlanMode is how the dialect delivers estimated row count of the planner
+type estimatePlanMode uint8
+
+const (
+	estimatePlanUnsupported estimatePlanMode = iota
+	estimatePlanRows                         // mysql : rows and filtered columns of EXPLAIN
+	estimatePlanJson                         // postgresql : "Plan Rows" of EXPLAIN (FORMAT JSON)
+)
+
+// Explain returns execution plan of statement resolved with parameters v as text.
+// the first line is column names and each plan row follows. columns are separated by tab
+func (man *QueryMan) Explain(stmtIdOrUserQuery string, v ...interface{}) (string, error) {
+	return man.explain(context.Background(), false, stmtIdOrUserQuery, v...)
+}
+
+// ExplainAnalyze is Explain with EXPLAIN ANALYZE which really executes the query. only select is permitted
+func (man *QueryMan) ExplainAnalyze(stmtIdOrUserQuery string, v ...interface{}) (string, error) {
+	return man.explain(context.Background(), true, stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) explain(ctx context.Context, analyze bool, stmtIdOrUserQuery string, v ...interface{}) (string, error) {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return "", err
+	}
+
+	if analyze && stmt.eleType != eleTypeSelect {
+		return "", ErrQueryInvalidSqlType
+	}
+
+	prefix, err := man.getNormalizer().explainPrefix(analyze)
+	if err != nil {
+		return "", err
+	}
+
+	result := queryMultiRow(context.WithValue(ctx, explainPrefixKey{}, prefix), man.proxyFor(stmt), stmt, v...)
+	defer result.Close()
+	if result.err != nil {
+		return "", result.err
+	}
+
+	columns, err := result.rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Join(columns, "\t"))
+	record := make([]string, len(columns))
+	for result.rows.Next() {
+		values, err := scanRowValues(result.rows, len(columns))
+		if err != nil {
+			return "", err
+		}
+		for i, value := range values {
+			record[i] = "NULL"
+			if value != nil {
+				record[i] = asString(value)
+			}
+		}
+		buffer.WriteByte('\n')
+		buffer.WriteString(strings.Join(record, "\t"))
+	}
+
+	return buffer.String(), result.rows.Err()
+}
+
+// explainQuery prepends EXPLAIN prefix when the query runs for Explain
+func explainQuery(ctx context.Context, query string) string {
+	if prefix, ok := ctx.Value(explainPrefixKey{}).(string); ok {
+		return prefix + query
+	}
+	return query
+}
+
+// EstimateCount returns row count of select statement estimated by the query planner without reading rows.
+// the estimate comes from table statistics, so it may differ from COUNT(*) a lot
+func (man *QueryMan) EstimateCount(stmtIdOrUserQuery string, v ...interface{}) (int64, error) {
+	return man.EstimateCountContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) EstimateCountContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) (int64, error) {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	if stmt.eleType != eleTypeSelect {
+		return 0, ErrQueryInvalidSqlType
+	}
+
+	mode := man.getNormalizer().estimatePlan()
+	prefix := "EXPLAIN "
+	switch mode {
+	case estimatePlanRows:
+	case estimatePlanJson:
+		prefix = "EXPLAIN (FORMAT JSON) "
+	default:
+		return 0, fmt.Errorf("estimate count is not supported by the driver")
+	}
+
+	result := queryMultiRow(context.WithValue(ctx, explainPrefixKey{}, prefix), man.proxyFor(stmt), stmt, v...)
+	defer result.Close()
+	if result.err != nil {
+		return 0, result.err
+	}
+
+	columns, err := result.rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	records := make([][]interface{}, 0)
+	for result.rows.Next() {
+		values, err := scanRowValues(result.rows, len(columns))
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, values)
+	}
+	if err = result.rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if mode == estimatePlanJson {
+		if len(records) == 0 || len(records[0]) == 0 {
+			return 0, fmt.Errorf("empty plan")
+		}
+		return estimateFromPlanJson([]byte(asString(records[0][0])))
+	}
+	return estimateFromPlanRows(columns, records)
+}
+
+// estimateFromPlanRows multiplies rows * filtered% of the tables joined in the outermost select of mysql EXPLAIN
+func estimateFromPlanRows(columns []string, records [][]interface{}) (int64, error) {
+	idIndex, rowsIndex, filteredIndex := -1, -1, -1
+	for i, c := range columns {
+		switch strings.ToLower(c) {
+		case "id":
+			idIndex = i
+		case "rows":
+			rowsIndex = i
+		case "filtered":
+			filteredIndex = i
+		}
+	}
+	if rowsIndex < 0 {
+		return 0, fmt.Errorf("not found rows column in plan")
+	}
+	if len(records) == 0 {
+		return 0, fmt.Errorf("empty plan")
+	}
+
+	estimate := 1.0
+	counted := false
+	for _, record := range records {
+		if idIndex >= 0 && asString(record[idIndex]) != asString(records[0][idIndex]) {
+			continue
+		}
+		if record[rowsIndex] == nil {
+			// e.g) impossible WHERE
+			continue
+		}
+		rows, err := strconv.ParseFloat(asString(record[rowsIndex]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid rows of plan : %s", err.Error())
+		}
+		if filteredIndex >= 0 && record[filteredIndex] != nil {
+			filtered, err := strconv.ParseFloat(asString(record[filteredIndex]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid filtered of plan : %s", err.Error())
+			}
+			rows = rows * filtered / 100
+		}
+		estimate *= rows
+		counted = true
+	}
+
+	if !counted {
+		return 0, nil
+	}
+	return int64(math.Round(estimate)), nil
+}
+
+// estimateFromPlanJson returns "Plan Rows" of the top plan node of postgresql EXPLAIN (FORMAT JSON)
+func estimateFromPlanJson(data []byte) (int64, error) {
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return 0, fmt.Errorf("fail to parse plan : %s", err.Error())
+	}
+	if len(plans) == 0 {
+		return 0, fmt.Errorf("empty plan")
+	}
+	return int64(math.Round(plans[0].Plan.Rows)), nil
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const defaultCSVFlushRows = 100
+
+type CSVOptions struct {
+	Context    context.Context // stop exporting when done
+	Comma      rune            // field delimiter. default ','
+	NullValue  string          // text for NULL column. default empty
+	NoHeader   bool            // skip column header line
+	FlushEvery int             // flush after every n rows. default 100
+}
+
+// WriteCSV streams the remaining rows to w as CSV without buffering the result set
+func (r *QueryResult) WriteCSV(w io.Writer, opts CSVOptions) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	flushEvery := opts.FlushEvery
+	if flushEvery <= 0 {
+		flushEvery = defaultCSVFlushRows
+	}
+
+	if !opts.NoHeader {
+		if err = writer.Write(columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(columns))
+	count := 0
+	for r.rows.Next() {
+		if opts.Context != nil {
+			if err = opts.Context.Err(); err != nil {
+				return err
+			}
+		}
+
+		values, err := scanRowValues(r.rows, len(columns))
+		if err != nil {
+			return err
+		}
+
+		for i, v := range values {
+			if v == nil {
+				record[i] = opts.NullValue
+				continue
+			}
+			record[i] = csvString(v)
+		}
+
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+
+		count++
+		if count%flushEvery == 0 {
+			writer.Flush()
+			if err = writer.Error(); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+
+	return r.rows.Err()
+}
+
+func csvString(v interface{}) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return asString(v)
+}
+
+// scanRowValues scans current row into driver values
+func scanRowValues(rows resultRows, columnCount int) ([]interface{}, error) {
+	values := make([]interface{}, columnCount)
+	scanners := make([]interface{}, columnCount)
+	for i := range values {
+		scanners[i] = &values[i]
+	}
+
+	if err := rows.Scan(scanners...); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+type JSONOptions struct {
+	CamelCaseKeys bool                       // key by field name of FieldNameConverter in lower camel case. e.g) user_name -> userName
+	KeyMapper     func(column string) string // maps column name to key. overrides CamelCaseKeys
+}
+
+// WriteJSON streams the remaining rows to w as JSON array of objects keyed by column name
+func (r *QueryResult) WriteJSON(w io.Writer) error {
+	return r.WriteJSONWithOptions(w, JSONOptions{})
+}
+
+// WriteJSONWithOptions streams the remaining rows to w as JSON array of objects keyed by mapped column name
+func (r *QueryResult) WriteJSONWithOptions(w io.Writer, opts JSONOptions) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	columnTypes, err := databaseTypeNames(r.rows)
+	if err != nil {
+		return err
+	}
+
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		keys[i], err = json.Marshal(r.jsonKey(c, opts))
+		if err != nil {
+			return err
+		}
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	first := true
+	for r.rows.Next() {
+		values, err := scanRowValues(r.rows, len(columns))
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			buffer.WriteByte(',')
+		}
+		first = false
+
+		buffer.WriteByte('{')
+		for i, v := range values {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			buffer.Write(keys[i])
+			buffer.WriteByte(':')
+			encoded, err := jsonValue(columnTypes[i], v)
+			if err != nil {
+				return fmt.Errorf("fail to encode column %s : %s", columns[i], err.Error())
+			}
+			buffer.Write(encoded)
+		}
+		buffer.WriteByte('}')
+
+		if _, err = w.Write(buffer.Bytes()); err != nil {
+			return err
+		}
+		buffer.Reset()
+	}
+
+	if err = r.rows.Err(); err != nil {
+		return err
+	}
+
+	buffer.WriteByte(']')
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+// jsonKey returns object key of column
+func (r *QueryResult) jsonKey(column string, opts JSONOptions) string {
+	if opts.KeyMapper != nil {
+		return opts.KeyMapper(column)
+	}
+	if !opts.CamelCaseKeys {
+		return column
+	}
+
+	converter := r.fieldNameConverter
+	if converter == nil {
+		converter = CamelConvertStrategy{}
+	}
+	field := []rune(converter.convertFieldName(column))
+	if len(field) > 0 {
+		field[0] = unicode.ToLower(field[0])
+	}
+	return string(field)
+}
+
+// jsonValue encodes driver value. numeric column delivered as text is written as number when it is
+// a valid json number (NaN, Infinity are not), binary column as base64 and other text as string
+func jsonValue(databaseTypeName string, v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return json.Marshal(v)
+	}
+
+	typeName := strings.ToUpper(databaseTypeName)
+	if isNumericColumnType(typeName) {
+		number := json.Number(strings.TrimSpace(string(b)))
+		if _, err := number.Float64(); err == nil && json.Valid([]byte(number)) {
+			return []byte(number), nil
+		}
+	}
+
+	if isBinaryColumnType(typeName) {
+		return json.Marshal(b)
+	}
+
+	return json.Marshal(string(b))
+}
+
+// columnValue coerces text delivered driver value by database type name of the column
+func columnValue(databaseTypeName string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+
+	typeName := strings.ToUpper(databaseTypeName)
+	switch {
+	case isIntegerColumnType(typeName):
+		text := strings.TrimSpace(string(b))
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return n
+		}
+	case isFloatColumnType(typeName):
+		if f, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64); err == nil {
+			return f
+		}
+	case isBinaryColumnType(typeName):
+		return b
+	}
+	return string(b)
+}
+
+func isIntegerColumnType(typeName string) bool {
+	switch strings.TrimPrefix(typeName, "UNSIGNED ") {
+	case "INT", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8", "YEAR":
+		return true
+	}
+	return false
+}
+
+func isFloatColumnType(typeName string) bool {
+	switch typeName {
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "DOUBLE PRECISION":
+		return true
+	}
+	return false
+}
+
+func isNumericColumnType(typeName string) bool {
+	if isIntegerColumnType(typeName) || isFloatColumnType(typeName) {
+		return true
+	}
+	switch strings.TrimPrefix(typeName, "UNSIGNED ") {
+	case "DECIMAL", "NUMERIC", "NUMBER", "BINARY_FLOAT", "BINARY_DOUBLE":
+		return true
+	}
+	return false
+}
+
+func isBinaryColumnType(typeName string) bool {
+	for _, t := range []string{"BLOB", "BINARY", "BYTEA", "RAW"} {
+		if strings.Contains(typeName, t) {
+			return true
+		}
+	}
+	return false
+}
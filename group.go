@@ -0,0 +1,208 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ScanGrouped reads the remaining rows of one-to-many join into dest (*[]Parent or *[]*Parent).
+// rows are grouped by keyColumn : parent fields are scanned once per group and each row is scanned into
+// a child appended to the slice field childField of the parent. row whose child columns are all NULL
+// (e.g. LEFT JOIN without child) appends no child. duplicated column name (e.g. id of both tables) is the
+// parent's at the first occurrence and the child's at the last. with StripColumnQualifier, users.id and
+// orders.id are the same column name id
+func (r *QueryResult) ScanGrouped(dest interface{}, childField string, keyColumn string) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return ErrScanAllNeedSlice
+	}
+	slice := rv.Elem()
+	parentType := structElemType(slice.Type().Elem())
+	if parentType == nil {
+		return fmt.Errorf("element of %s is not struct", slice.Type().String())
+	}
+
+	field, ok := parentType.FieldByName(childField)
+	if !ok || len(field.PkgPath) > 0 || field.Type.Kind() != reflect.Slice {
+		return fmt.Errorf("%s has no exported slice field %s", parentType.String(), childField)
+	}
+	childType := structElemType(field.Type.Elem())
+	if childType == nil {
+		return fmt.Errorf("element of field %s is not struct", childField)
+	}
+
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return err
+	}
+	keyIndex := -1
+	for i, c := range columns {
+		if strings.EqualFold(c, keyColumn) || (r.stripQualifier && strings.EqualFold(unqualifiedColumn(c), keyColumn)) {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("not found key column %s", keyColumn)
+	}
+	if r.stripQualifier {
+		stripped := make([]string, len(columns))
+		for i, c := range columns {
+			stripped[i] = unqualifiedColumn(c)
+		}
+		columns = stripped
+	}
+
+	converter := r.fieldNameConverter
+	if converter == nil {
+		converter = CamelConvertStrategy{}
+	}
+	parent := reflect.New(parentType).Elem()
+	parentScanner, err := newStructureScanner(converter, groupColumns(columns, true), &parent, false)
+	if err != nil {
+		return err
+	}
+	child := reflect.New(childType).Elem()
+	childScanner, err := newStructureScanner(converter, groupColumns(columns, false), &child, false)
+	if err != nil {
+		return err
+	}
+
+	childOnly := make([]int, 0)
+	for i := range columns {
+		if childScanner.fieldIndex[i] != nil && parentScanner.fieldIndex[i] == nil {
+			childOnly = append(childOnly, i)
+		}
+	}
+
+	groups := make(map[interface{}]int)
+	count := 0
+	for r.rows.Next() {
+		if err = r.checkScanRows(count); err != nil {
+			return err
+		}
+		count++
+
+		values, err := scanRowValues(r.rows, len(columns))
+		if err != nil {
+			return err
+		}
+
+		key := groupKey(values[keyIndex])
+		index, exists := groups[key]
+		if !exists {
+			parent = reflect.New(parentType).Elem()
+			if err = scanGroupValues(parentScanner, &parent, values); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, structElem(slice.Type().Elem(), parent))
+			index = slice.Len() - 1
+			groups[key] = index
+		}
+
+		if allNull(values, childOnly) {
+			continue
+		}
+		child = reflect.New(childType).Elem()
+		if err = scanGroupValues(childScanner, &child, values); err != nil {
+			return err
+		}
+		target := reflect.Indirect(slice.Index(index)).FieldByName(childField)
+		target.Set(reflect.Append(target, structElem(field.Type.Elem(), child)))
+	}
+	rv.Elem().Set(slice)
+	return r.rows.Err()
+}
+
+// structElemType returns struct type of slice element t (struct or pointer of struct). nil if not
+func structElemType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// structElem returns v as slice element of elemType
+func structElem(elemType reflect.Type, v reflect.Value) reflect.Value {
+	if elemType.Kind() == reflect.Ptr {
+		return v.Addr()
+	}
+	return v
+}
+
+// groupColumns blanks duplicated column names except the first (parent) or the last (child) occurrence
+func groupColumns(columns []string, parent bool) []string {
+	list := make([]string, len(columns))
+	seen := make(map[string]bool)
+	for k := range columns {
+		i := k
+		if !parent {
+			i = len(columns) - 1 - k
+		}
+		name := strings.ToLower(columns[i])
+		if !seen[name] {
+			list[i] = columns[i]
+			seen[name] = true
+		}
+	}
+	return list
+}
+
+// groupKey returns comparable key of driver value
+func groupKey(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func allNull(values []interface{}, columns []int) bool {
+	if len(columns) == 0 {
+		return false
+	}
+	for _, i := range columns {
+		if values[i] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// scanGroupValues scans driver values of a row into val with scanner
+func scanGroupValues(ss *StructureScanner, val *reflect.Value, values []interface{}) error {
+	ss.reset(val)
+	for _, v := range values {
+		if err := ss.Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	identifierBindStart = "{{"
+	identifierBindStop  = "}}"
+)
+
+// parseIdentifiers parses identifiers attribute (comma separated allowlist of {{name}} identifier binds).
+// each identifier is a plain name or schema qualified name. e.g) city, stat.city_2023
+func parseIdentifiers(attr string) ([]string, error) {
+	identifiers := make([]string, 0)
+	for _, v := range strings.Split(attr, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+		if !isPlainIdentifier(v) {
+			return nil, fmt.Errorf("invalid identifier [%s]", v)
+		}
+		identifiers = append(identifiers, v)
+	}
+	return identifiers, nil
+}
+
+func isPlainIdentifier(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if len(part) == 0 {
+			return false
+		}
+		for i, c := range part {
+			switch {
+			case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			case i > 0 && (c >= '0' && c <= '9' || c == '$'):
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bindIdentifiers replaces {{name}} of query with the identifier of params[name] quoted by the dialect.
+// identifier not in allowed is ErrIdentifierNotAllowed
+func bindIdentifiers(normalizer QueryNormalizer, query string, allowed []string, params map[string]interface{}) (string, error) {
+	if !strings.Contains(query, identifierBindStart) {
+		return query, nil
+	}
+
+	var buffer bytes.Buffer
+	backslashEscape := backslashEscapes(normalizer.dialectName())
+	queryLen := len(query)
+	for i := 0; i < queryLen; i++ {
+		if skip := skipLiteralOrComment(query, i, backslashEscape); skip > i {
+			buffer.WriteString(query[i:skip])
+			i = skip - 1
+			continue
+		}
+		if !strings.HasPrefix(query[i:], identifierBindStart) {
+			buffer.WriteByte(query[i])
+			continue
+		}
+
+		stopIndex := strings.Index(query[i+len(identifierBindStart):], identifierBindStop)
+		if stopIndex < 1 {
+			return "", fmt.Errorf("incompleted identifier closer : %s", query)
+		}
+		name := strings.TrimSpace(query[i+len(identifierBindStart) : i+len(identifierBindStart)+stopIndex])
+		v, ok, err := findBindValue(nil, params, name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("not found identifier \"%s\" from parameter values", name)
+		}
+
+		identifier, _ := v.(string)
+		if !containsIdentifier(allowed, identifier) {
+			return "", fmt.Errorf("%w : %s=%v", ErrIdentifierNotAllowed, name, v)
+		}
+		buffer.WriteString(normalizer.quoteIdentifier(identifier))
+		i = i + len(identifierBindStart) + stopIndex + len(identifierBindStop) - 1
+	}
+	return buffer.String(), nil
+}
+
+func containsIdentifier(allowed []string, identifier string) bool {
+	for _, v := range allowed {
+		if v == identifier {
+			return true
+		}
+	}
+	return false
+}
@@ -22,6 +22,7 @@ package queryman
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/xml"
 	"fmt"
@@ -31,7 +32,10 @@ import (
 	"math"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -44,15 +48,17 @@ type queryExecution struct {
 	close  bool
 	start  time.Time
 	elased time.Duration
+	bind   time.Duration // resolving binds before the driver call
 	stmtId string
 }
 
-func newQueryExecution(stmtId string, start time.Time) queryExecution {
+func newQueryExecution(stmtId string, bind time.Duration, start time.Time) queryExecution {
 	e := queryExecution{}
 	e.close = false
 	e.stmtId = stmtId
 	e.start = start
 	e.elased = time.Duration(time.Now().UnixNano() - start.UnixNano())
+	e.bind = bind
 	return e
 }
 
@@ -61,7 +67,7 @@ func (s queryExecution) String() string {
 		return ""
 	}
 
-	return fmt.Sprintf("[%s] elased %d milliseconds", s.stmtId, s.elased/1000000)
+	return fmt.Sprintf("[%s] elased %d milliseconds (bind %d microseconds)", s.stmtId, s.elased/1000000, s.bind/1000)
 }
 
 type defaultLogger struct{}
@@ -72,18 +78,67 @@ func (defaultLogger) Printf(format string, a ...interface{}) {
 }
 
 type QuerymanPreference struct {
-	queryFilePath     string
-	Fileset           string
-	DriverName        string
-	dataSourceUrl     string
-	ConnMaxLifetime   time.Duration
-	MaxIdleConns      int
-	MaxOpenConns      int
-	Debug             bool
-	DebugLogger       Logger
-	SlowQueryDuration time.Duration
-	SlowQueryFunc     func(stmtId string, start time.Time, elapsed time.Duration)
-	fieldNameConvert  fieldNameConvertMethod
+	queryFilePath         string
+	Fileset               string
+	DriverName            string
+	dataSourceUrl         string
+	ReplicaDataSourceUrl  string // select statements run on this pool when set. see target attribute
+	ConnMaxLifetime       time.Duration
+	MaxIdleConns          int
+	MaxOpenConns          int
+	Debug                 bool
+	DebugLogger           Logger
+	DebugCallerLocation   bool   // prefix debug output with caller file:line
+	DebugFormat           string // text(default) or json. json logs DebugEvent of each executed statement
+	SlowQueryDuration     time.Duration
+	SlowQueryFunc         func(stmtId string, start time.Time, elapsed time.Duration)
+	StringerAsValue       bool               // bind fmt.Stringer parameters (not driver.Valuer) as String()
+	PartialOmitZero       bool               // UpdatePartial skips zero value (non pointer) fields too
+	UpdateStructAll       bool               // UpdateStruct sets all non key fields including zero value and nil pointer (NULL)
+	MapKeyCaseInsensitive bool               // look up map parameter key case insensitively when exact key is not found
+	PreserveIdCase        bool               // statement ids are case sensitive. default folds ids to upper case
+	AlwaysPrepare         bool               // execute single Execute/Query with parameters through Prepare + Exec/Query + Close
+	CollectStats          bool               // collect execution time and affected rows by statement id. see QueryMan.Stats()
+	FieldNameConverter    FieldNameConverter // column <-> struct field name mapping. nil means snake_case <-> CamelCase
+	CollectLoadErrors     bool               // keep loading on statement failure and report all of them as *LoadErrors
+	UserQueryCacheSize    int                // cache normalized user query statements up to the size. 0 means no cache
+	ResultCacheSize       int                // cache rows of cacheTTL statements up to the size of entries (LRU). 0 means no cache
+	StrictArity           bool               // positional parameters more than column binds are error too
+	StrictColumns         bool               // selected column without struct field is error instead of ignored
+	StripColumnQualifier  bool               // match table qualified column (e.g. users.id) to struct field by the name after the last dot
+	MaxScanRows           int                // ScanAll and ScanMapSlice fail when rows exceed the count. 0 means no limit
+	QueryBatchConcurrency int                // statements of QueryBatch running at once
+	BulkDebugSampleRate   int                // debug print parameters of 1 in n rows of list execution. 0 or 1 prints every row
+	MaxPlaceholders       int                // placeholders in a statement. 0 is the default of the driver (e.g. 65535 for mysql). bulk insert is chunked under the limit
+	SoftDeleteFilter      string             // condition replacing {softDeleteFilter} of softDelete="true" statement
+	UnwrapSingleBatch     bool               // return driver sql.Result instead of ExecMultiResult for single element list
+	RetryOnDeadlock       int                // retry single statement execution (not in transaction) failed by deadlock up to the count
+	DeadlockBackoff       time.Duration      // wait before n-th deadlock retry is n * DeadlockBackoff
+	TrackRunning          bool               // track executing statements for RunningQueries() and CancelRunning()
+	MultiStatementExec    string             // user query having several statements : as-is(default), transaction or reject
+	// ParamMasker replaces parameter value in debug output. e.g) password -> "***"
+	ParamMasker func(stmtId string, index int, v interface{}) interface{}
+	// OnConnect is called after each connection attempt of pool with its error (nil on success)
+	OnConnect func(err error)
+	// OnConnClose is called when pool closes a connection
+	OnConnClose func(err error)
+	// OnConnError is called when a connection fails with driver.ErrBadConn
+	OnConnError func(err error)
+	// DeadlockDetector reports deadlock error. nil means default of driver (mysql 1213, postgres 40P01)
+	DeadlockDetector func(err error) bool
+	// ErrorClassifier maps driver error to DBError kind. nil means default of driver (mysql, postgres)
+	ErrorClassifier ErrorClassifier
+	// QueryRewriter inspects or rewrites resolved query right before the driver call. error aborts the call
+	QueryRewriter    func(ctx context.Context, stmtId string, query string) (string, error)
+	fieldNameConvert fieldNameConvertMethod
+}
+
+// debugLogger returns DebugLogger. nil logger falls back to standard log (stderr)
+func (pref *QuerymanPreference) debugLogger() Logger {
+	if pref.DebugLogger == nil {
+		return defaultLogger{}
+	}
+	return pref.DebugLogger
 }
 
 func NewQuerymanPreference(filepath string, dataSourceUrl string) QuerymanPreference {
@@ -98,53 +153,133 @@ func NewQuerymanPreference(filepath string, dataSourceUrl string) QuerymanPrefer
 	pref.Debug = false
 	pref.SlowQueryDuration = 0
 	pref.DebugLogger = defaultLogger{}
+	pref.DebugCallerLocation = false
+	pref.DebugFormat = DebugFormatText
+	pref.StringerAsValue = false
+	pref.PartialOmitZero = false
+	pref.UpdateStructAll = false
+	pref.MapKeyCaseInsensitive = false
+	pref.PreserveIdCase = false
+	pref.AlwaysPrepare = false
+	pref.CollectStats = false
+	pref.CollectLoadErrors = false
+	pref.UserQueryCacheSize = 0
+	pref.ResultCacheSize = defaultResultCacheSize
+	pref.StrictArity = false
+	pref.StrictColumns = false
+	pref.StripColumnQualifier = true
+	pref.MaxScanRows = 0
+	pref.QueryBatchConcurrency = defaultQueryBatchConcurrency
+	pref.BulkDebugSampleRate = 0
+	pref.MaxPlaceholders = 0
+	pref.SoftDeleteFilter = defaultSoftDeleteFilter
+	pref.UnwrapSingleBatch = false
+	pref.RetryOnDeadlock = 0
+	pref.DeadlockBackoff = time.Millisecond * 50
+	pref.TrackRunning = false
+	pref.MultiStatementExec = MultiStatementAsIs
 	pref.fieldNameConvert = fieldNameConvertToCamel
 
 	return pref
 }
 
 func NewQueryman(pref QuerymanPreference) (*QueryMan, error) {
+	manager, err := openQueryman(pref, pref.dataSourceUrl, make(map[string]QueryStatement), &sync.RWMutex{})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pref.ReplicaDataSourceUrl) > 0 {
+		manager.replica, err = openPool(pref, pref.ReplicaDataSourceUrl)
+		if err != nil {
+			manager.Close()
+			return nil, fmt.Errorf("fail to open replica sql : %s", err.Error())
+		}
+	}
+
+	err = loadXmlFile(manager, pref.queryFilePath, pref.Fileset)
+	if err != nil {
+		manager.Close()
+		return nil, fmt.Errorf("fail to load xml file : %w [path=%s,fileset=%s]", err, pref.queryFilePath, pref.Fileset)
+	}
+
+	return manager, nil
+}
+
+// openQueryman opens db pool for dataSourceUrl with statementMap (and its lock) which may be shared with other QueryMan
+func openQueryman(pref QuerymanPreference, dataSourceUrl string, statementMap map[string]QueryStatement, statementMutex *sync.RWMutex) (*QueryMan, error) {
 	manager := &QueryMan{}
 	manager.preference = pref
-	manager.statementMap = make(map[string]QueryStatement)
+	manager.preference.dataSourceUrl = dataSourceUrl
+	manager.statementMap = statementMap
+	manager.statementMutex = statementMutex
 
-	db, err := sql.Open(pref.DriverName, pref.dataSourceUrl)
+	db, err := openPool(pref, dataSourceUrl)
 	if err != nil {
 		return nil, fmt.Errorf("fail to open sql : %s", err.Error())
 	}
-
 	manager.db = db
-	manager.db.SetConnMaxLifetime(pref.ConnMaxLifetime)
-	manager.db.SetMaxOpenConns(pref.MaxOpenConns)
-	manager.db.SetMaxIdleConns(pref.MaxIdleConns)
-	manager.fieldNameConverter = newFieldNameConverter(pref.fieldNameConvert)
+	manager.fieldNameConverter = newFieldNameConverter(pref)
 
-	err = loadXmlFile(manager, pref.queryFilePath, pref.Fileset)
-	if err != nil {
-		return nil, fmt.Errorf("fail to load xml file : %s [path=%s,fileset=%s]", err.Error(), pref.queryFilePath, pref.Fileset)
+	runtime.SetFinalizer(manager, closeQueryman)
+
+	if pref.CollectStats {
+		manager.stats = newStatsCollector()
 	}
 
-	runtime.SetFinalizer(manager, closeQueryman)
+	if pref.TrackRunning {
+		manager.running = newRunningRegistry()
+	}
+
+	if pref.UserQueryCacheSize > 0 {
+		manager.userQueryCache = newStatementCache(pref.UserQueryCacheSize)
+	}
+	if pref.ResultCacheSize > 0 {
+		manager.resultCache = newResultCache(pref.ResultCacheSize)
+	}
 
 	if manager.preference.SlowQueryDuration > 0 && manager.preference.SlowQueryFunc != nil {
 		manager.execRecordChan = make(chan queryExecution, int(math.MaxUint16))
-		go func() {
-			r := <-manager.execRecordChan
-			if r.close {
-				return
-			}
-
-			if r.elased > manager.preference.SlowQueryDuration {
-				manager.preference.SlowQueryFunc(r.stmtId, r.start, r.elased)
+		go func(records chan queryExecution) {
+			for r := range records {
+				if r.close {
+					return
+				}
+
+				if r.elased > pref.SlowQueryDuration {
+					pref.SlowQueryFunc(r.stmtId, r.start, r.elased)
+				}
 			}
-		}()
+		}(manager.execRecordChan)
 	}
 
 	return manager, nil
 }
 
-func newFieldNameConverter(fieldNameConvert fieldNameConvertMethod) FieldNameConvertStrategy {
-	switch fieldNameConvert {
+func openPool(pref QuerymanPreference, dataSourceUrl string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	if hasConnLifecycle(pref) {
+		db, err = openLifecycleDB(pref, dataSourceUrl)
+	} else {
+		db, err = sql.Open(pref.DriverName, dataSourceUrl)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxLifetime(pref.ConnMaxLifetime)
+	db.SetMaxOpenConns(pref.MaxOpenConns)
+	db.SetMaxIdleConns(pref.MaxIdleConns)
+	return db, nil
+}
+
+func newFieldNameConverter(pref QuerymanPreference) FieldNameConvertStrategy {
+	if pref.FieldNameConverter != nil {
+		return userConvertStrategy{converter: pref.FieldNameConverter}
+	}
+
+	switch pref.fieldNameConvert {
 	case fieldNameConvertToUnderstore:
 		return UnderstoreConvertStrategy{}
 	}
@@ -152,6 +287,40 @@ func newFieldNameConverter(fieldNameConvert fieldNameConvertMethod) FieldNameCon
 	return CamelConvertStrategy{}
 }
 
+// StatementError is a failure of loading a statement (or a file when Id is empty)
+type StatementError struct {
+	File string
+	Id   string
+	Err  error
+}
+
+func (e StatementError) Error() string {
+	if len(e.Id) == 0 {
+		return fmt.Sprintf("[%s] %s", e.File, e.Err.Error())
+	}
+	return fmt.Sprintf("[%s] %s : %s", e.File, e.Id, e.Err.Error())
+}
+
+// LoadErrors reports all failures while loading with CollectLoadErrors preference
+type LoadErrors struct {
+	Errors []StatementError
+	Loaded []string // successfully loaded statement ids
+}
+
+func (e *LoadErrors) Error() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%d statements failed to load (%d loaded)", len(e.Errors), len(e.Loaded)))
+	for _, v := range e.Errors {
+		buffer.WriteString("\n")
+		buffer.WriteString(v.Error())
+	}
+	return buffer.String()
+}
+
+func (e *LoadErrors) add(file string, id string, err error) {
+	e.Errors = append(e.Errors, StatementError{File: file, Id: id, Err: err})
+}
+
 func loadXmlFile(manager *QueryMan, filePath string, fileSet string) error {
 	var buffer bytes.Buffer
 	buffer.WriteString(filePath)
@@ -162,6 +331,8 @@ func loadXmlFile(manager *QueryMan, filePath string, fileSet string) error {
 		return fmt.Errorf("fail to search xml file : %s [glob=%s]", err.Error(), buffer.String())
 	}
 
+	collect := manager.preference.CollectLoadErrors
+	loadErrors := &LoadErrors{}
 	for _, file := range matches {
 		if !strings.HasSuffix(file, "xml") {
 			continue
@@ -169,19 +340,55 @@ func loadXmlFile(manager *QueryMan, filePath string, fileSet string) error {
 
 		data, err := ioutil.ReadFile(file)
 		if err != nil {
+			if collect {
+				loadErrors.add(file, "", err)
+				continue
+			}
 			return fmt.Errorf("fail to read file[%s] : %s", file, err.Error())
 		}
 
 		err = loadWithSax(manager, data)
 		if err != nil {
-			return err
+			if !collect {
+				return err
+			}
+			if stmtErrors, ok := err.(*LoadErrors); ok {
+				for _, v := range stmtErrors.Errors {
+					loadErrors.add(file, v.Id, v.Err)
+				}
+				continue
+			}
+			loadErrors.add(file, "", err)
 		}
 	}
 
-	return nil
+	if len(loadErrors.Errors) == 0 {
+		return nil
+	}
+
+	lock := manager.statementLock()
+	lock.RLock()
+	loaded := make(map[string]bool)
+	for _, v := range manager.statementMap {
+		if !loaded[v.Id] {
+			loaded[v.Id] = true
+			loadErrors.Loaded = append(loadErrors.Loaded, v.Id)
+		}
+	}
+	lock.RUnlock()
+	sort.Strings(loadErrors.Loaded)
+	return loadErrors
 }
 
+// saxMutex serializes loadWithSax since parsing state is kept in package variables
+var saxMutex sync.Mutex
+
 func loadWithSax(manager *QueryMan, data []byte) error {
+	saxMutex.Lock()
+	defer saxMutex.Unlock()
+
+	collect := manager.preference.CollectLoadErrors
+	loadErrors := &LoadErrors{}
 	stmtList = make([]QueryStatement, 0)
 	buf := bytes.NewBuffer(data)
 	dec := xml.NewDecoder(buf)
@@ -201,7 +408,65 @@ func loadWithSax(manager *QueryMan, data []byte) error {
 			currentEleType = buildElementType(t.Name.Local)
 			if currentEleType.IsSql() {
 				currentStmt = newQueryStatement(currentEleType)
-				traverseIf(dec)
+				attrErrs := make([]error, 0)
+				if timeout := getAttr(t.Attr, attrTimeout); len(timeout) > 0 {
+					d, err := time.ParseDuration(timeout)
+					if err != nil || d < 0 {
+						attrErrs = append(attrErrs, fmt.Errorf("invalid timeout [%s] of statement %s", timeout, currentId))
+					}
+					currentStmt.timeout = d
+				}
+				if cacheTTL := getAttr(t.Attr, attrCacheTTL); len(cacheTTL) > 0 {
+					d, err := time.ParseDuration(cacheTTL)
+					if err != nil || d < 0 {
+						attrErrs = append(attrErrs, fmt.Errorf("invalid cacheTTL [%s] of statement %s", cacheTTL, currentId))
+					} else if currentEleType != eleTypeSelect {
+						attrErrs = append(attrErrs, fmt.Errorf("cacheTTL of statement %s is only for select", currentId))
+					}
+					currentStmt.cacheTTL = d
+				}
+				if identifiers := getAttr(t.Attr, attrIdentifiers); len(identifiers) > 0 {
+					list, err := parseIdentifiers(identifiers)
+					if err != nil {
+						attrErrs = append(attrErrs, fmt.Errorf("%s of statement %s", err.Error(), currentId))
+					}
+					currentStmt.identifiers = list
+				}
+				if target := getAttr(t.Attr, attrTarget); len(target) > 0 {
+					if !isKnownPool(target) {
+						attrErrs = append(attrErrs, fmt.Errorf("invalid target [%s] of statement %s", target, currentId))
+					}
+					currentStmt.target = target
+				}
+				if softDelete := getAttr(t.Attr, attrSoftDelete); len(softDelete) > 0 {
+					on, err := strconv.ParseBool(softDelete)
+					if err != nil {
+						attrErrs = append(attrErrs, fmt.Errorf("invalid softDelete [%s] of statement %s", softDelete, currentId))
+					}
+					currentStmt.softDelete = on
+				}
+				if dialect := getAttr(t.Attr, attrDialect); len(dialect) > 0 {
+					currentStmt.dialect = dialectOf(dialect)
+					if len(currentStmt.dialect) == 0 {
+						attrErrs = append(attrErrs, fmt.Errorf("invalid dialect [%s] of statement %s", dialect, currentId))
+					}
+				}
+				if len(attrErrs) > 0 && !collect {
+					return attrErrs[0]
+				}
+				if err := traverseIf(dec, manager.preference.DriverName); err != nil {
+					if !collect {
+						return err
+					}
+					attrErrs = append(attrErrs, err)
+				}
+				if len(attrErrs) > 0 {
+					// skip the statement. every invalid attribute is reported
+					for _, err := range attrErrs {
+						loadErrors.add("", currentStmt.Id, err)
+					}
+					stmtList = stmtList[:len(stmtList)-1]
+				}
 			}
 		case xml.CharData:
 			if len(currentId) == 0 {
@@ -219,10 +484,16 @@ func loadWithSax(manager *QueryMan, data []byte) error {
 	for _, v := range stmtList {
 		err := manager.registStatement(v)
 		if err != nil {
-			return err
+			if !collect {
+				return err
+			}
+			loadErrors.add("", v.Id, err)
 		}
 	}
 
+	if len(loadErrors.Errors) > 0 {
+		return loadErrors
+	}
 	return nil
 }
 
@@ -236,10 +507,19 @@ func newQueryStatement(sqlType declareElementType) QueryStatement {
 }
 
 const (
-	attrId    = "id"
-	attrKey   = "key"
-	attrExist = "exist"
-	cutset    = "\r\t\n "
+	attrId          = "id"
+	attrKey         = "key"
+	attrExist       = "exist"
+	attrTimeout     = "timeout"
+	attrTarget      = "target"
+	attrDialect     = "dialect"
+	attrSoftDelete  = "softDelete"
+	attrCacheTTL    = "cacheTTL"
+	attrIdentifiers = "identifiers"
+	attrColumn      = "column"
+	attrDesc        = "desc"
+	attrNulls       = "nulls"
+	cutset          = "\r\t\n "
 )
 
 var (
@@ -258,11 +538,19 @@ func getAttr(attr []xml.Attr, name string) string {
 	return ""
 }
 
-func traverseIf(dec *xml.Decoder) {
-	var innerElement declareElementType
-	var innerSql = ""
-	var innerKey = ""
-	var innerExist = "true"
+// ifElement is <if> being parsed. nested <if> is kept as clause of its parent
+type ifElement struct {
+	key    string
+	exist  string
+	sql    string
+	clause []IfClause
+}
+
+// traverseIf reads body of statement. the first error (e.g. invalid orderby element) is returned
+// after the statement is read to the end
+func traverseIf(dec *xml.Decoder, driverName string) error {
+	ifStack := make([]*ifElement, 0)
+	var bodyErr error
 
 	for {
 		t, tokenErr := dec.Token()
@@ -275,30 +563,80 @@ func traverseIf(dec *xml.Decoder) {
 
 		switch t := t.(type) {
 		case xml.StartElement:
-			innerKey = getAttr(t.Attr, attrKey)
-			innerExist = getAttr(t.Attr, attrExist)
-			innerElement = buildElementType(t.Name.Local)
+			switch buildElementType(t.Name.Local) {
+			case eleTypeIf:
+				ifStack = append(ifStack, &ifElement{key: getAttr(t.Attr, attrKey), exist: getAttr(t.Attr, attrExist)})
+			case eleTypeOrderBy:
+				term, err := orderByTerm(t.Attr, driverName)
+				if err != nil {
+					if bodyErr == nil {
+						bodyErr = fmt.Errorf("%s of statement %s", err.Error(), currentStmt.Id)
+					}
+					break
+				}
+				if len(ifStack) > 0 {
+					inner := ifStack[len(ifStack)-1]
+					inner.sql = inner.sql + " " + term
+				} else {
+					currentStmt.Query = currentStmt.Query + term
+				}
+			}
 		case xml.CharData:
-			if innerElement == eleTypeIf {
-				innerSql = innerSql + " " + strings.Trim(string(t), cutset)
+			if len(ifStack) > 0 {
+				inner := ifStack[len(ifStack)-1]
+				inner.sql = inner.sql + " " + strings.Trim(string(t), cutset)
 			} else {
 				currentStmt.Query = currentStmt.Query + string(t)
 			}
 		case xml.EndElement:
-			if innerElement == eleTypeIf {
-				ifclause := newIfClause(innerKey, innerSql, innerExist)
+			if buildElementType(t.Name.Local) == eleTypeOrderBy {
+				continue
+			}
+			if len(ifStack) > 0 {
+				inner := ifStack[len(ifStack)-1]
+				ifStack = ifStack[:len(ifStack)-1]
+				ifclause := newIfClause(inner.key, inner.sql, inner.exist)
+				ifclause.clause = inner.clause
+				if len(ifStack) > 0 {
+					parent := ifStack[len(ifStack)-1]
+					parent.sql = fmt.Sprintf("%s %s", parent.sql, ifclause.id)
+					parent.clause = append(parent.clause, ifclause)
+					continue
+				}
 				currentStmt.Query = fmt.Sprintf("%s %s", currentStmt.Query, ifclause.id)
 				currentStmt.appendIf(ifclause)
-				innerSql = ""
-				innerElement = eleTypeUnknown
 			} else if currentEleType.IsSql() {
 				currentStmt.Query = strings.Trim(currentStmt.Query, cutset)
 				stmtList = append(stmtList, currentStmt)
-				return
+				return bodyErr
 			}
 			currentId = ""
 		}
 	}
+	return bodyErr
+}
+
+// orderByTerm returns ORDER BY term of orderby element in the form of the statement dialect (or driverName).
+// e.g) <orderby column="age" desc="true" nulls="last"/>
+func orderByTerm(attr []xml.Attr, driverName string) (string, error) {
+	column := strings.TrimSpace(getAttr(attr, attrColumn))
+	if len(column) == 0 {
+		return "", fmt.Errorf("orderby needs column")
+	}
+
+	desc := false
+	if v := getAttr(attr, attrDesc); len(v) > 0 {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid orderby desc [%s]", v)
+		}
+		desc = on
+	}
+
+	if len(currentStmt.dialect) > 0 {
+		driverName = dialectDriverName(currentStmt.dialect)
+	}
+	return newNormalizer(driverName).orderByNulls(column, desc, getAttr(attr, attrNulls))
 }
 
 func closeQueryman(manager *QueryMan) {
@@ -22,11 +22,21 @@ package queryman
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 var testData = []byte(`
@@ -75,7 +85,7 @@ func TestLoaderSimple(t *testing.T) {
 			currentEleType = buildElementType(t.Name.Local)
 			if currentEleType.IsSql() {
 				currentStmt = newQueryStatement(currentEleType)
-				traverseIf(dec)
+				traverseIf(dec, "mysql")
 			}
 		case xml.CharData:
 			if len(currentId) == 0 {
@@ -181,7 +191,7 @@ func TestLoaderComplicated(t *testing.T) {
 			currentEleType = buildElementType(t.Name.Local)
 			if currentEleType.IsSql() {
 				currentStmt = newQueryStatement(currentEleType)
-				traverseIf(dec)
+				traverseIf(dec, "mysql")
 			}
 		case xml.CharData:
 			if len(currentId) == 0 {
@@ -869,3 +879,2923 @@ update tb_chnl c , (
     </update>
 </query>
 `)
+
+func TestRefineWithNormalizer(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE age > {Age} AND name IN ({Names})"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	refined, err := refineConditional(newNormalizer("postgresql"), stmt)
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	if refined.Query != "SELECT * FROM city WHERE age > $1 AND name IN ($2)" {
+		t.Fatalf("invalid postgresql query : %s", refined.Query)
+	}
+
+	query, param, err := resolveColumnBindInList(newNormalizer("postgresql"), refined, []interface{}{10, []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatalf("fail to resolve : %s", err.Error())
+	}
+	if query != "SELECT * FROM city WHERE age > $1 AND name IN ($2,$3,$4)" || len(param) != 4 {
+		t.Fatalf("invalid postgresql IN expansion : %s", query)
+	}
+
+	if stmt.Query != "SELECT * FROM city WHERE age > ? AND name IN (?)" {
+		t.Fatalf("original statement should not be touched : %s", stmt.Query)
+	}
+}
+
+func TestFlattenArrayExpansion(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE age > {Age} AND id IN ({Ids})"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	ids := []int{1, 2, 3}
+	for _, arr := range []interface{}{ids, &ids, []interface{}{1, 2, 3}} {
+		param, cnt := flattenArray(arr)
+		if cnt != 3 || len(param) != 3 {
+			t.Fatalf("[%T] expect 3 elements but cnt=%d, param=%d", arr, cnt, len(param))
+		}
+
+		query, param, err := resolveColumnBindInList(queryNormalizer, stmt, []interface{}{10, arr})
+		if err != nil {
+			t.Fatalf("fail to resolve : %s", err.Error())
+		}
+		if query != "SELECT * FROM city WHERE age > ? AND id IN (?,?,?)" || len(param) != 4 {
+			t.Fatalf("[%T] invalid IN expansion : %s, param=%v", arr, query, param)
+		}
+	}
+
+	param, cnt := flattenArray([]interface{}{7})
+	if cnt != 1 || len(param) != 1 {
+		t.Fatalf("expect 1 element but cnt=%d, param=%d", cnt, len(param))
+	}
+}
+
+func TestFlattenArrayPositional(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE id IN ({Ids}) AND age > {Age} AND name IN ({Names})"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	// each IN array consumes one slice parameter
+	query, param, err := resolveColumnBindInList(queryNormalizer, stmt, []interface{}{[]int{1, 2, 3}, 10, []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("fail to resolve : %s", err.Error())
+	}
+	if query != "SELECT * FROM city WHERE id IN (?,?,?) AND age > ? AND name IN (?,?)" {
+		t.Fatalf("invalid IN expansion : %s", query)
+	}
+	if !reflect.DeepEqual(param, []interface{}{1, 2, 3, 10, "a", "b"}) {
+		t.Fatalf("invalid params : %v", param)
+	}
+
+	// scalar is a single element
+	query, param, err = resolveColumnBindInList(queryNormalizer, stmt, []interface{}{7, 10, []string{"a", "b"}})
+	if err != nil || query != "SELECT * FROM city WHERE id IN (?) AND age > ? AND name IN (?,?)" || len(param) != 4 {
+		t.Fatalf("invalid scalar IN : %s, %v, %v", query, param, err)
+	}
+
+	_, _, err = resolveColumnBindInList(queryNormalizer, stmt, []interface{}{[]int{1, 2}, 10})
+	if err == nil || !strings.Contains(err.Error(), "defined=3, args=2") {
+		t.Fatalf("expect count mismatch but %v", err)
+	}
+
+	_, _, err = resolveColumnBindInList(queryNormalizer, stmt, []interface{}{[]int{1, 2}, 10, []string{}})
+	if err == nil || !strings.Contains(err.Error(), "Names (parameter 3)") {
+		t.Fatalf("expect empty IN array error but %v", err)
+	}
+}
+
+type testGrade int
+
+func (g testGrade) Value() (driver.Value, error) {
+	return []string{"bronze", "silver", "gold"}[g], nil
+}
+
+func TestFlattenArrayValuer(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE grade IN ({Grades}) AND age > {Age}"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	grades := []testGrade{0, 2}
+	query, param, err := resolveColumnBindInList(queryNormalizer, stmt, []interface{}{grades, 10})
+	if err != nil {
+		t.Fatalf("fail to resolve : %s", err.Error())
+	}
+	if query != "SELECT * FROM city WHERE grade IN (?,?) AND age > ?" || len(param) != 3 {
+		t.Fatalf("invalid IN expansion : %s, param=%v", query, param)
+	}
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	param, err = bindValues(manager, param)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, expect := range []string{"bronze", "gold"} {
+		valuer, ok := param[i].(driver.Valuer)
+		if !ok {
+			t.Fatalf("[%d] expect driver.Valuer but %T", i, param[i])
+		}
+		v, _ := valuer.Value()
+		if v != expect {
+			t.Fatalf("[%d] expect %s but %v", i, expect, v)
+		}
+	}
+}
+
+func TestNormalizeSkipLiteralAndComment(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = `SELECT '{notabind}' AS literal, "{quoted}" AS quoted, 'it''s {x}' AS escaped
+		FROM city
+		WHERE name = {Name} -- AND age = {x}
+		/* AND id = {Id} */ AND age > {Age}`
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	if len(stmt.columnMention) != 2 {
+		t.Fatalf("expect 2 column binds but %v", stmt.columnMention)
+	}
+	if stmt.columnMention[0].Name() != "Name" || stmt.columnMention[1].Name() != "Age" {
+		t.Fatalf("invalid column binds : %v", stmt.columnMention)
+	}
+	if strings.Count(stmt.Query, "?") != 2 || !strings.Contains(stmt.Query, "'{notabind}'") || !strings.Contains(stmt.Query, "-- AND age = {x}") {
+		t.Fatalf("invalid normalized query : %s", stmt.Query)
+	}
+
+	stmt.Query = `SELECT * FROM city WHERE memo = 'it\'s {x}' AND name = {Name}`
+	if err = queryNormalizer.normalize(&stmt); err != nil || len(stmt.columnMention) != 1 {
+		t.Fatalf("backslash of mysql literal should escape quote : %v, %v", stmt.columnMention, err)
+	}
+
+	postgres := newNormalizer("postgresql")
+	stmt.Query = `SELECT * FROM file WHERE path = 'C:\' AND name = {Name} AND memo = E'it\'s {x}' AND age > {Age}`
+	if err = postgres.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if len(stmt.columnMention) != 2 || stmt.columnMention[0].Name() != "Name" || stmt.columnMention[1].Name() != "Age" {
+		t.Fatalf("bind following 'C:\\' should be found : %v", stmt.columnMention)
+	}
+	if stmt.Query != `SELECT * FROM file WHERE path = 'C:\' AND name = $1 AND memo = E'it\'s {x}' AND age > $2` {
+		t.Fatalf("invalid normalized query : %s", stmt.Query)
+	}
+}
+
+func TestJsonBindModifier(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeUpdate, Id: "updatePayload"}
+	stmt.Query = "UPDATE city SET payload = {Payload:json} WHERE id IN ({Ids})"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if stmt.columnMention[0].Name() != "Payload" || stmt.columnMention[0].bindType != columnBindTypeNormal {
+		t.Fatalf("invalid json bind : %v", stmt.columnMention[0])
+	}
+
+	query, param, err := resolveColumnBindInList(queryNormalizer, stmt, []interface{}{[]string{"a", "b"}, []int{1, 2}})
+	if err != nil {
+		t.Fatalf("fail to resolve : %s", err.Error())
+	}
+	if query != "UPDATE city SET payload = ? WHERE id IN (?,?)" || len(param) != 3 || param[0] != `["a","b"]` {
+		t.Fatalf("invalid json binding : %s, param=%v", query, param)
+	}
+
+	stmt = QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE id IN ({Ids:json})"
+	err = queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if stmt.hasArrayBind() {
+		t.Fatalf("json bind should not be expanded : %v", stmt.columnMention)
+	}
+
+	stmt = QueryStatement{eleType: eleTypeSelect, Id: "selectCity"}
+	stmt.Query = "SELECT * FROM city WHERE id = {Id:unknown}"
+	if queryNormalizer.normalize(&stmt) == nil {
+		t.Fatalf("unknown modifier should be rejected")
+	}
+}
+
+func TestTypedBindModifier(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeUpdate, Id: "updateAge"}
+	stmt.Query = "UPDATE city SET age = {Age:int}, name = {Name:string}, updated = {Updated:time} WHERE id = {Id}"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	age := 42
+	var nilAge *int
+	valid := [][]interface{}{
+		{42, "seoul", time.Now(), 1},
+		{&age, sql.NullString{String: "seoul", Valid: true}, nil, "any"},
+		{nilAge, nil, &time.Time{}, 1},
+		{uint8(1), "seoul", sql.NullTime{}, 1},
+	}
+	for _, args := range valid {
+		_, param, err := resolveColumnBindInList(queryNormalizer, stmt, args)
+		if err != nil {
+			t.Fatalf("fail to resolve %v : %s", args, err.Error())
+		}
+		if param[0] != args[0] {
+			t.Fatalf("typed bind should not change parameter : %v", param)
+		}
+	}
+
+	_, _, err = resolveColumnBindInList(queryNormalizer, stmt, []interface{}{"42", "seoul", time.Now(), 1})
+	if err == nil || !strings.Contains(err.Error(), "Age") || !strings.Contains(err.Error(), "expect int but string") {
+		t.Fatalf("string for int should be rejected but %v", err)
+	}
+
+	_, _, err = resolveColumnBindInList(queryNormalizer, stmt, []interface{}{42, 7, time.Now(), 1})
+	if err == nil {
+		t.Fatalf("int for string should be rejected")
+	}
+
+	for _, c := range []struct {
+		modifier string
+		v        interface{}
+		ok       bool
+	}{
+		{bindModifierFloat, 1.5, true},
+		{bindModifierFloat, 1, true},
+		{bindModifierInt, 1.5, false},
+		{bindModifierBool, true, true},
+		{bindModifierBool, 1, false},
+		{bindModifierBytes, []byte("a"), true},
+		{bindModifierBytes, []int{1}, false},
+		{bindModifierTime, "2023-01-01", false},
+	} {
+		_, err = bindTypes[c.modifier](c.v)
+		if (err == nil) != c.ok {
+			t.Fatalf("%s of %v : expect ok=%v but %v", c.modifier, c.v, c.ok, err)
+		}
+	}
+
+	if stmt.firstArgsHasModifier() {
+		t.Fatalf("type annotation should not disable list execution")
+	}
+
+	in := QueryStatement{eleType: eleTypeSelect, Id: "selectIds"}
+	in.Query = "SELECT * FROM city WHERE id IN ({Ids:int}) AND name LIKE {Name:like}"
+	if err = queryNormalizer.normalize(&in); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if !in.hasArrayBind() || in.firstArgsHasModifier() {
+		t.Fatalf("typed IN bind should be array bind")
+	}
+	query, param, err := resolveColumnBindInList(queryNormalizer, in, []interface{}{[]int{1, 2}, "seoul"})
+	if err != nil || len(param) != 3 || query != "SELECT * FROM city WHERE id IN (?,?) AND name LIKE ?" {
+		t.Fatalf("invalid typed IN bind : %s, %v, %v", query, param, err)
+	}
+	_, _, err = resolveColumnBindInList(queryNormalizer, in, []interface{}{[]interface{}{1, "2"}, "seoul"})
+	if err == nil || !strings.Contains(err.Error(), "Ids[1]") {
+		t.Fatalf("element of typed IN bind should be verified : %v", err)
+	}
+}
+
+func TestLoaderNestedIf(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectNestedIf">
+		SELECT * FROM city WHERE 1=1
+		<if key="Name">
+			AND name = {Name}
+			<if key="Age">
+				AND age = {Age}
+			</if>
+			AND name IS NOT NULL
+		</if>
+		<if key="Id">
+			AND id = {Id}
+			<if key="Deleted" exist="false">
+				AND deleted = 0
+			</if>
+		</if>
+		ORDER BY id
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, err := manager.find("selectNestedIf")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(stmt.clause) != 2 {
+		t.Fatalf("expect 2 top level if clause but %d", len(stmt.clause))
+	}
+
+	collapse := func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	}
+
+	refined, err := stmt.RefineStatement(map[string]interface{}{"Name": "seoul", "Age": 10, "Id": 1})
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	expect := "SELECT * FROM city WHERE 1=1 AND name = ? AND age = ? AND name IS NOT NULL AND id = ? AND deleted = 0 ORDER BY id"
+	if collapse(refined.Query) != expect {
+		t.Fatalf("invalid refined query : %s", refined.Query)
+	}
+
+	refined, err = stmt.RefineStatement(map[string]interface{}{"Age": 10, "Id": 1, "Deleted": true})
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	expect = "SELECT * FROM city WHERE 1=1 AND id = ? ORDER BY id"
+	if collapse(refined.Query) != expect || len(refined.columnMention) != 1 {
+		t.Fatalf("invalid refined query : %s", refined.Query)
+	}
+
+	refined, err = stmt.RefineStatement(nil)
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	if collapse(refined.Query) != "SELECT * FROM city WHERE 1=1 ORDER BY id" {
+		t.Fatalf("invalid refined query : %s", refined.Query)
+	}
+}
+
+func TestRepeatedBindInIfClause(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectByName">
+		SELECT * FROM city WHERE name = {Name}
+		<if key="WithAlias">
+			OR alias = {Name}
+		</if>
+		AND age > {Age}
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, _ := manager.find("selectByName")
+	refined, err := stmt.refine(queryNormalizer, map[string]interface{}{"Name": "seoul", "WithAlias": true, "Age": 10})
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	if len(refined.columnMention) != 3 {
+		t.Fatalf("expect 3 binds but %v", refined.columnMention)
+	}
+
+	_, param, bindErr := resolveColumnBindInMap(manager, refined, map[string]interface{}{"Name": "seoul", "WithAlias": true, "Age": 10})
+	if bindErr != nil || len(param) != 3 || param[1] != "seoul" || param[2] != 10 {
+		t.Fatalf("invalid map binding : %v", param)
+	}
+
+	// repeated name is given once in positional list
+	if bindCountMismatch(&manager.preference, refined, 2) {
+		t.Fatalf("distinct bind count should be accepted")
+	}
+	_, param, err = resolveColumnBindInList(queryNormalizer, refined, []interface{}{"seoul", 10})
+	if err != nil || len(param) != 3 || param[0] != "seoul" || param[1] != "seoul" || param[2] != 10 {
+		t.Fatalf("invalid list binding : %v, %v", param, err)
+	}
+
+	// each bind is given
+	_, param, err = resolveColumnBindInList(queryNormalizer, refined, []interface{}{"seoul", "busan", 10})
+	if err != nil || len(param) != 3 || param[1] != "busan" {
+		t.Fatalf("invalid list binding : %v, %v", param, err)
+	}
+}
+
+func TestRefinePreview(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, testData)
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	query, err := manager.RefinePreview("selectWhere", map[string]interface{}{"VarB": 1})
+	if err != nil {
+		t.Fatalf("fail to preview : %s", err.Error())
+	}
+	if !strings.Contains(query, "b=?") || !strings.Contains(query, "k=?") {
+		t.Fatalf("invalid preview : %s", query)
+	}
+
+	query, err = manager.RefinePreview("selectWhere", map[string]interface{}{"VarK": 1})
+	if err != nil {
+		t.Fatalf("fail to preview : %s", err.Error())
+	}
+	if strings.Contains(query, "b=?") || strings.Contains(query, "k=?") {
+		t.Fatalf("invalid preview : %s", query)
+	}
+
+	query, err = manager.RefinePreview("selectDual", nil)
+	if err != nil || query != "SELECT 1 FROM dual" {
+		t.Fatalf("invalid preview : %s, %v", query, err)
+	}
+
+	if _, err = manager.RefinePreview("notExist", nil); err == nil {
+		t.Fatalf("not exist statement should be error")
+	}
+}
+
+type testSqlStateError string
+
+func (e testSqlStateError) Error() string    { return "sql state " + string(e) }
+func (e testSqlStateError) SQLState() string { return string(e) }
+
+func TestCallProcedure(t *testing.T) {
+	vars := findSessionVars("CALL calc_total({Name}, '@literal', @total, @@sql_mode, @avg_age)")
+	if !reflect.DeepEqual(vars, []string{"@total", "@avg_age"}) {
+		t.Fatalf("invalid session vars : %v", vars)
+	}
+
+	for driver, expect := range map[string]procedureOutMode{
+		"mysql":      procedureOutSessionVar,
+		"postgresql": procedureOutResultRow,
+		"oci8":       procedureOutUnsupported,
+	} {
+		if mode := newNormalizer(driver).procedureOut(); mode != expect {
+			t.Fatalf("%s : expect %d but %d", driver, expect, mode)
+		}
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+	err := loadWithSax(manager, []byte(`<query>
+	<update id="calcTotal">
+		CALL calc_total({Name}, @total)
+	</update>
+	<select id="selectCity">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	total := 0
+	err = manager.WithNormalizer("oci8").CallProcedure("calcTotal", []interface{}{"seoul"}, []interface{}{&total})
+	if err != ErrProcedureNotSupported {
+		t.Fatalf("expect ErrProcedureNotSupported but %v", err)
+	}
+	err = manager.CallProcedure("selectCity", nil, nil)
+	if err != ErrExecutionInvalidSqlType {
+		t.Fatalf("expect ErrExecutionInvalidSqlType but %v", err)
+	}
+	err = manager.CallProcedure("calcTotal", []interface{}{"seoul"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("expect OUT parameter count mismatch but %v", err)
+	}
+}
+
+func TestExplainPrefix(t *testing.T) {
+	for driver, expect := range map[string]string{"mysql": "EXPLAIN ", "postgresql": "EXPLAIN "} {
+		prefix, err := newNormalizer(driver).explainPrefix(false)
+		if err != nil || prefix != expect {
+			t.Fatalf("%s : invalid explain prefix [%s], %v", driver, prefix, err)
+		}
+	}
+	if prefix, _ := newNormalizer("postgresql").explainPrefix(true); prefix != "EXPLAIN ANALYZE " {
+		t.Fatalf("invalid explain analyze prefix [%s]", prefix)
+	}
+	if _, err := newNormalizer("oci8").explainPrefix(false); err == nil {
+		t.Fatalf("oracle explain should be rejected")
+	}
+
+	ctx := context.WithValue(context.Background(), explainPrefixKey{}, "EXPLAIN ")
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	query, err := rewriteQuery(ctx, manager, "selectCity", "SELECT * FROM city WHERE id = ?")
+	if err != nil || query != "EXPLAIN SELECT * FROM city WHERE id = ?" {
+		t.Fatalf("invalid explain query : %s, %v", query, err)
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	manager.statementMap = make(map[string]QueryStatement)
+	_, err = manager.ExplainAnalyze("UPDATE city SET age = {Age}", 42)
+	if err != ErrQueryInvalidSqlType {
+		t.Fatalf("expect ErrQueryInvalidSqlType but %v", err)
+	}
+}
+
+func TestRetryOnDeadlock(t *testing.T) {
+	deadlock := fmt.Errorf("fail to exec : %w", &mysql.MySQLError{Number: 1213, Message: "Deadlock found"})
+	if !isMysqlDeadlock(deadlock) || isMysqlDeadlock(&mysql.MySQLError{Number: 1062}) {
+		t.Fatalf("invalid mysql deadlock detection")
+	}
+	if !isPostgresDeadlock(testSqlStateError("40P01")) || isPostgresDeadlock(testSqlStateError("23505")) {
+		t.Fatalf("invalid postgres deadlock detection")
+	}
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.RetryOnDeadlock = 2
+	manager.preference.DeadlockBackoff = time.Millisecond
+
+	attempts := 0
+	err := retryOnDeadlock(context.Background(), manager, "insertCity", func() error {
+		attempts++
+		if attempts < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("expect success at 3rd attempt but attempts=%d, err=%v", attempts, err)
+	}
+
+	attempts = 0
+	err = retryOnDeadlock(context.Background(), manager, "insertCity", func() error {
+		attempts++
+		return deadlock
+	})
+	if err == nil || attempts != 3 {
+		t.Fatalf("expect give up after 2 retries but attempts=%d, err=%v", attempts, err)
+	}
+
+	attempts = 0
+	_ = retryOnDeadlock(context.Background(), manager, "insertCity", func() error {
+		attempts++
+		return &mysql.MySQLError{Number: 1062}
+	})
+	if attempts != 1 {
+		t.Fatalf("not deadlock error should not be retried. attempts=%d", attempts)
+	}
+}
+
+func TestArrayFieldScan(t *testing.T) {
+	type tagged struct {
+		Tags   []string
+		Scores []int
+		Raw    []byte
+	}
+
+	var v tagged
+	val := reflect.ValueOf(&v).Elem()
+	ss, err := newStructureScanner(CamelConvertStrategy{}, []string{"tags", "scores", "raw"}, &val, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, src := range []interface{}{[]byte(`{a,"b c","x\"y",NULL}`), "{1,2,3}", []byte("raw")} {
+		if err := ss.cloneScannerList()[i].(*StructureScanner).Scan(src); err != nil {
+			t.Fatalf("fail to scan : %s", err.Error())
+		}
+	}
+
+	if !reflect.DeepEqual(v.Tags, []string{"a", "b c", `x"y`, ""}) {
+		t.Fatalf("invalid string array : %#v", v.Tags)
+	}
+	if !reflect.DeepEqual(v.Scores, []int{1, 2, 3}) {
+		t.Fatalf("invalid int array : %#v", v.Scores)
+	}
+	if string(v.Raw) != "raw" {
+		t.Fatalf("[]byte field should not be parsed as array : %s", v.Raw)
+	}
+
+	if _, err := parseArrayLiteral("{{1,2},{3,4}}"); err == nil {
+		t.Fatalf("multi dimensional array should be error")
+	}
+	elements, err := parseArrayLiteral("{}")
+	if err != nil || len(elements) != 0 {
+		t.Fatalf("invalid empty array : %v, %v", elements, err)
+	}
+}
+
+func TestBulkDynamicInsert(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	stmt := QueryStatement{eleType: eleTypeInsert, Id: "insertCity"}
+	stmt.Query = "INSERT INTO city (name, age, create_time) VALUES ({Name}, {Age}, NOW()) ON DUPLICATE KEY UPDATE age = VALUES(age)"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	insert, err := parseDynamicInsert(stmt.HoldedQuery)
+	if err != nil {
+		t.Fatalf("fail to parse : %s", err.Error())
+	}
+
+	rows := [][]interface{}{{"seoul", nil}, {"busan", nil}}
+	if presentSignature(rows[0]) != "10" {
+		t.Fatalf("invalid signature : %s", presentSignature(rows[0]))
+	}
+	query, params := insert.build(rows)
+	query = queryNormalizer.resolveHolding(query)
+	expect := "INSERT INTO city (name, create_time) VALUES (?, NOW()),(?, NOW()) ON DUPLICATE KEY UPDATE age = VALUES(age)"
+	if query != expect || len(params) != 2 || params[1] != "busan" {
+		t.Fatalf("invalid dynamic insert : %s, params=%v", query, params)
+	}
+
+	stmt.Query = "INSERT INTO city (name, age) VALUES ({Name}, CONCAT({Name}, {Age}))"
+	err = queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if _, err = parseDynamicInsert(stmt.HoldedQuery); err == nil {
+		t.Fatalf("value with two parameters should be rejected")
+	}
+
+	stmt.Query = "INSERT INTO metric_values (name, age) VALUES ({Name}, {Age})"
+	if err = queryNormalizer.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	insert, err = parseDynamicInsert(stmt.HoldedQuery)
+	if err != nil || insert.prefix != "INSERT INTO metric_values " || len(insert.columns) != 2 {
+		t.Fatalf("table name containing values should be parsed : %v, %v", insert, err)
+	}
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	b := newQuerymanBulk(manager, stmt)
+	b.DynamicColumns()
+	if err = b.AddBatch(map[string]interface{}{"Name": "seoul"}); err != nil {
+		t.Fatalf("missing key should be absent column : %s", err.Error())
+	}
+	if err = b.AddBatch(map[string]interface{}{"Name": "busan", "Age": 42}); err != nil {
+		t.Fatalf("fail to add map : %s", err.Error())
+	}
+	if presentSignature(b.rows[0]) != "10" || presentSignature(b.rows[1]) != "11" {
+		t.Fatalf("invalid present columns : %v", b.rows)
+	}
+
+	b = newQuerymanBulk(manager, stmt)
+	if err = b.AddBatch(map[string]interface{}{"Name": "seoul"}); err == nil {
+		t.Fatalf("missing key without DynamicColumns should be error")
+	}
+}
+
+func TestBulkUpsertClause(t *testing.T) {
+	clause, err := newNormalizer("mysql").upsertClause([]string{"id"}, []string{"score", "name"})
+	if err != nil || clause != "ON DUPLICATE KEY UPDATE score = VALUES(score), name = VALUES(name)" {
+		t.Fatalf("invalid mysql upsert : %s, %v", clause, err)
+	}
+	if _, err = newNormalizer("mysql").upsertClause([]string{"id"}, nil); err == nil {
+		t.Fatalf("mysql upsert without update columns should be rejected")
+	}
+
+	clause, err = newNormalizer("postgresql").upsertClause([]string{"id", "seq"}, []string{"score"})
+	if err != nil || clause != "ON CONFLICT (id, seq) DO UPDATE SET score = EXCLUDED.score" {
+		t.Fatalf("invalid postgresql upsert : %s, %v", clause, err)
+	}
+	clause, err = newNormalizer("postgresql").upsertClause([]string{"id"}, nil)
+	if err != nil || clause != "ON CONFLICT (id) DO NOTHING" {
+		t.Fatalf("invalid postgresql upsert : %s, %v", clause, err)
+	}
+
+	if _, err = newNormalizer("oci8").upsertClause([]string{"id"}, []string{"score"}); err == nil {
+		t.Fatalf("oracle upsert should be rejected")
+	}
+}
+
+func TestRunningRegistry(t *testing.T) {
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	if _, done := manager.trackRunning(context.Background(), "selectCity"); len(manager.RunningQueries()) != 0 {
+		t.Fatalf("running queries should not be tracked without TrackRunning")
+	} else {
+		done()
+	}
+
+	manager.running = newRunningRegistry()
+	ctx1, done1 := manager.trackRunning(context.Background(), "selectCity")
+	_, done2 := manager.trackRunning(context.Background(), "updateCity")
+
+	running := manager.RunningQueries()
+	if len(running) != 2 || running[0].StmtId != "selectCity" || running[1].StmtId != "updateCity" {
+		t.Fatalf("invalid running queries : %v", running)
+	}
+
+	if !manager.CancelRunning(running[0].Id) {
+		t.Fatalf("fail to cancel running query")
+	}
+	if ctx1.Err() != context.Canceled {
+		t.Fatalf("context should be canceled but %v", ctx1.Err())
+	}
+
+	done1()
+	done2()
+	if len(manager.RunningQueries()) != 0 || manager.CancelRunning(running[1].Id) {
+		t.Fatalf("completed queries should be deregistered")
+	}
+}
+
+func TestStrictColumns(t *testing.T) {
+	type partialCity struct {
+		Name string
+	}
+
+	var v partialCity
+	val := reflect.ValueOf(&v).Elem()
+	ss, err := newStructureScanner(CamelConvertStrategy{}, []string{"name", "legacy_code"}, &val, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, src := range []interface{}{[]byte("seoul"), []byte("X1")} {
+		if err := ss.Scan(src); err != nil {
+			t.Fatalf("column without field should be ignored : %s", err.Error())
+		}
+	}
+	if v.Name != "seoul" {
+		t.Fatalf("invalid scan : %+v", v)
+	}
+
+	ss, err = newStructureScanner(CamelConvertStrategy{}, []string{"name", "legacy_code"}, &val, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ss.Scan([]byte("busan")); err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = ss.Scan([]byte("X1"))
+	if err == nil || !strings.Contains(err.Error(), "legacy_code") {
+		t.Fatalf("expect error with column name but %v", err)
+	}
+}
+
+func TestColumnOrderScanner(t *testing.T) {
+	type city struct {
+		Id   int
+		Name string
+		Age  int
+	}
+
+	for _, columns := range [][]string{{"id", "name", "age"}, {"age", "id", "name"}, {"name", "memo", "age", "id"}} {
+		var v city
+		val := reflect.ValueOf(&v).Elem()
+		ss, err := newStructureScanner(CamelConvertStrategy{}, columns, &val, false)
+		if err != nil {
+			t.Fatalf("fail to plan %v : %s", columns, err.Error())
+		}
+
+		values := map[string]interface{}{"id": int64(7), "name": []byte("seoul"), "age": int64(42), "memo": []byte("x")}
+		scanners := ss.cloneScannerList()
+		if len(scanners) != len(columns) {
+			t.Fatalf("expect %d scanners but %d", len(columns), len(scanners))
+		}
+		for i, c := range columns {
+			if err = scanners[i].(sql.Scanner).Scan(values[c]); err != nil {
+				t.Fatalf("fail to scan %s : %s", c, err.Error())
+			}
+		}
+		if v.Id != 7 || v.Name != "seoul" || v.Age != 42 {
+			t.Fatalf("invalid scan of %v : %+v", columns, v)
+		}
+		if ss.Scan(nil) == nil {
+			t.Fatalf("scan more than columns should fail")
+		}
+	}
+
+	var v city
+	val := reflect.ValueOf(&v).Elem()
+	_, err := newStructureScanner(CamelConvertStrategy{}, []string{"id", "name", "NAME"}, &val, false)
+	if err == nil || !strings.Contains(err.Error(), "same field Name") {
+		t.Fatalf("duplicated alias should fail but %v", err)
+	}
+	_, err = newStructureScanner(CamelConvertStrategy{}, []string{"age", "AGE"}, &val, false)
+	if err == nil {
+		t.Fatalf("duplicated alias should fail")
+	}
+}
+
+func TestPositionalScanner(t *testing.T) {
+	type summary struct {
+		Total  int64
+		hidden string
+		Count  int
+		Tags   []string
+	}
+
+	result := summary{}
+	val := reflect.ValueOf(&result).Elem()
+	ss, err := newPositionalScanner(3, &val)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, v := range []interface{}{int64(42), []byte("7"), []byte("{a,b}")} {
+		if err = ss.Scan(v); err != nil {
+			t.Fatalf("fail to scan : %s", err.Error())
+		}
+	}
+	if result.Total != 42 || result.Count != 7 || !reflect.DeepEqual(result.Tags, []string{"a", "b"}) {
+		t.Fatalf("invalid positional scan : %+v", result)
+	}
+
+	if _, err = newPositionalScanner(4, &val); err == nil {
+		t.Fatalf("more columns than exported fields should be rejected")
+	}
+	if _, err = positionalDest(result); err != ErrQueryNeedsPtrParameter {
+		t.Fatalf("expect ErrQueryNeedsPtrParameter but %v", err)
+	}
+	count := 0
+	if _, err = positionalDest(&count); err != ErrScanPositionalNeedStruct {
+		t.Fatalf("expect ErrScanPositionalNeedStruct but %v", err)
+	}
+}
+
+func TestBoundParams(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, testData)
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	type where struct {
+		VarA string
+		VarB *int
+		VarC int
+	}
+	params, err := NewBoundParams(&where{VarA: "a", VarC: 3})
+	if err != nil {
+		t.Fatalf("fail to bind : %s", err.Error())
+	}
+
+	stmt, _ := manager.find("selectWhere")
+	refined, err := refineConditional(queryNormalizer, stmt, params)
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	// VarB exists as nil pointer field
+	if strings.Count(refined.Query, "?") != 5 {
+		t.Fatalf("invalid refined query : %s", refined.Query)
+	}
+
+	if _, err = NewBoundParams([]int{1}); err != ErrBoundParamsType {
+		t.Fatalf("slice should be rejected : %v", err)
+	}
+	if _, err = NewBoundParams(map[int]interface{}{1: 1}); err != ErrInvalidMapKeyType {
+		t.Fatalf("non string key map should be rejected : %v", err)
+	}
+}
+
+type testBindPoint struct {
+	X, Y int
+}
+
+func TestMergedMapParams(t *testing.T) {
+	pathVars := map[string]string{"Id": "7", "Name": "path"}
+	body := map[string]interface{}{"Name": "body", "Age": 42}
+
+	m, ok := findNamedParams([]interface{}{pathVars, body})
+	if !ok {
+		t.Fatalf("several maps should be merged")
+	}
+	if m["Id"] != "7" || m["Name"] != "body" || m["Age"] != 42 {
+		t.Fatalf("invalid merged params : %v", m)
+	}
+
+	if _, ok = findNamedParams([]interface{}{body}); ok {
+		t.Fatalf("single map is not merged")
+	}
+	if _, ok = findNamedParams([]interface{}{body, 42}); ok {
+		t.Fatalf("maps mixed with positional parameter should not be merged")
+	}
+	if _, ok = findNamedParams([]interface{}{body, map[int]string{1: "a"}}); ok {
+		t.Fatalf("map of non string key should not be merged")
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectCity">
+		SELECT * FROM city WHERE id = {Id}
+		<if key="Age">AND age = {Age}</if>
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	query, err := manager.RefinePreview("selectCity", nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	stmt, _ := manager.find("selectCity")
+	refined, err := refineConditional(queryNormalizer, stmt, pathVars, body)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if refined.Query == query || !strings.Contains(refined.Query, "age = ?") {
+		t.Fatalf("if clause of the later map should be included : %s", refined.Query)
+	}
+}
+
+func TestSplitMultiStatement(t *testing.T) {
+	statements := splitStatements("INSERT INTO log (msg) VALUES ('a;b'); -- done;\nUPDATE city SET age = {Age} ; ", true)
+	if len(statements) != 2 || statements[0] != "INSERT INTO log (msg) VALUES ('a;b')" {
+		t.Fatalf("invalid split : %q", statements)
+	}
+	if statements = splitStatements(`INSERT INTO log (path) VALUES ('C:\'); DELETE FROM log`, false); len(statements) != 2 {
+		t.Fatalf("backslash of standard sql literal should not escape quote : %q", statements)
+	}
+
+	pref := NewQuerymanPreference("", "")
+	if _, ok := findMultiStatement(&pref, "DELETE FROM a; DELETE FROM b"); ok {
+		t.Fatalf("multi statement should be sent as it is by default")
+	}
+	pref.MultiStatementExec = MultiStatementTransaction
+	if _, ok := findMultiStatement(&pref, "DELETE FROM a;"); ok {
+		t.Fatalf("single statement with trailing ';' is not multi statement")
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	manager := &QueryMan{}
+	manager.preference = pref
+	manager.statementMap = make(map[string]QueryStatement)
+	queries := []string{"UPDATE city SET age = {Age} WHERE id = {Id}", "DELETE FROM log WHERE id = {Id}"}
+	args, err := distributeStatementArgs(manager, queries, []interface{}{42, 1, 2})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(args, [][]interface{}{{42, 1}, {2}}) {
+		t.Fatalf("invalid positional distribution : %v", args)
+	}
+	if _, err = distributeStatementArgs(manager, queries, []interface{}{42, 1, 2, 3}); err == nil {
+		t.Fatalf("extra parameter should be error")
+	}
+
+	named := map[string]interface{}{"Age": 42, "Id": 1}
+	args, _ = distributeStatementArgs(manager, queries, []interface{}{named})
+	if len(args[0]) != 1 || len(args[1]) != 1 {
+		t.Fatalf("named parameter should be passed to every statement : %v", args)
+	}
+
+	manager.preference.MultiStatementExec = MultiStatementReject
+	if _, err = manager.ExecuteWithStmt("DELETE FROM a; DELETE FROM b"); err != ErrMultiStatement {
+		t.Fatalf("expect ErrMultiStatement but %v", err)
+	}
+}
+
+func TestNestedBindName(t *testing.T) {
+	type address struct {
+		City string
+		Zip  *string
+	}
+	type customer struct {
+		Name    string
+		Address address
+		Billing *address
+		Extra   map[string]interface{}
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	stmt := QueryStatement{eleType: eleTypeInsert, Id: "insertCustomer"}
+	stmt.Query = "INSERT INTO customer (name, city, zip, billing_city, grade) VALUES ({Name}, {address.city}, {Address.Zip}, {Billing.City}, {Extra.grade})"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	zip := "04524"
+	c := customer{Name: "jin", Address: address{City: "seoul", Zip: &zip}, Extra: map[string]interface{}{"grade": 3}}
+	m := flattenStructToMap(c)
+	expect := []interface{}{"jin", "seoul", "04524", nil, 3}
+	for i, bind := range stmt.columnMention {
+		found, ok, err := findBindValue(nil, m, bind.Name())
+		if err != nil || !ok {
+			t.Fatalf("fail to find %s : %v", bind.Name(), err)
+		}
+		if found != expect[i] {
+			t.Fatalf("%s : expect %v but %v", bind.Name(), expect[i], found)
+		}
+	}
+
+	if _, ok, _ := findBindValue(nil, m, "Address.Street"); ok {
+		t.Fatalf("unknown nested field should not be found")
+	}
+
+	stmt.Query = "INSERT INTO customer (name, city) VALUES ({name}, {address.city})"
+	if err = queryNormalizer.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	b := newQuerymanBulk(manager, stmt)
+	if err = b.AddBatch([]customer{c, {Name: "kim", Address: address{City: "busan"}}}); err == nil {
+		t.Fatalf("{name} should not be found without MapKeyCaseInsensitive")
+	}
+
+	manager.preference.MapKeyCaseInsensitive = true
+	b = newQuerymanBulk(manager, stmt)
+	if err = b.AddBatch([]customer{c, {Name: "kim", Address: address{City: "busan"}}}); err != nil {
+		t.Fatalf("fail to add struct list : %s", err.Error())
+	}
+	if len(b.rows) != 2 || b.rows[0][1] != "seoul" || b.rows[1][0] != "kim" || b.rows[1][1] != "busan" {
+		t.Fatalf("invalid nested rows of struct list : %v", b.rows)
+	}
+}
+
+func TestDebugNilLogger(t *testing.T) {
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.Debug = true
+	manager.preference.DebugLogger = nil
+
+	manager.debugPrint("debug without logger : %s", "selectCity")
+
+	manager.preference.DebugFormat = DebugFormatJson
+	emitDebugEvent(manager, "selectCity", "SELECT 1", nil, time.Now(), nil, nil)
+}
+
+func TestBindConverter(t *testing.T) {
+	RegisterBindConverter(reflect.TypeOf(time.Duration(0)), func(v interface{}) (interface{}, error) {
+		return v.(time.Duration).Milliseconds(), nil
+	})
+	RegisterBindConverter(reflect.TypeOf(testBindPoint{}), func(v interface{}) (interface{}, error) {
+		p := v.(testBindPoint)
+		return fmt.Sprintf("POINT(%d %d)", p.X, p.Y), nil
+	})
+	defer RegisterBindConverter(reflect.TypeOf(time.Duration(0)), nil)
+	defer RegisterBindConverter(reflect.TypeOf(testBindPoint{}), nil)
+
+	v, err := bindValue(false, 3*time.Second)
+	if err != nil || v != int64(3000) {
+		t.Fatalf("invalid duration binding : %v, %v", v, err)
+	}
+
+	if !isValueStruct(testBindPoint{1, 2}) {
+		t.Fatalf("struct with bind converter should be single value")
+	}
+	v, err = bindValue(false, testBindPoint{1, 2})
+	if err != nil || v != "POINT(1 2)" {
+		t.Fatalf("invalid struct binding : %v, %v", v, err)
+	}
+
+	v, err = bindValue(false, 7)
+	if err != nil || v != 7 {
+		t.Fatalf("value without converter should not be changed : %v, %v", v, err)
+	}
+}
+
+type testColor int
+
+func (c testColor) String() string {
+	return []string{"red", "green", "blue"}[c]
+}
+
+func TestStringerAsValue(t *testing.T) {
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+
+	var nilColor *testColor
+	now := time.Now()
+	params := []interface{}{testColor(2), nilColor, now, 7}
+	bound, err := bindValues(manager, params)
+	if err != nil || bound[0] != testColor(2) || bound[1] != nilColor {
+		t.Fatalf("stringer should not be changed without StringerAsValue : %v, %v", bound, err)
+	}
+
+	manager.preference.StringerAsValue = true
+	bound, err = bindValues(manager, params)
+	if err != nil {
+		t.Fatalf("fail to bind : %s", err.Error())
+	}
+	if bound[0] != "blue" || bound[1] != nil || bound[2] != now || bound[3] != 7 {
+		t.Fatalf("stringer should be bound as String() : %v", bound)
+	}
+	if params[0] != testColor(2) {
+		t.Fatalf("caller params should not be modified")
+	}
+}
+
+func TestLoaderTimeout(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="bigReport" timeout="30s">
+		SELECT * FROM city WHERE age > {Age}
+	</select>
+	<select id="noTimeout">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, err := manager.find("bigReport")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if stmt.timeout != time.Second*30 {
+		t.Fatalf("with %s, but %s", time.Second*30, stmt.timeout)
+	}
+	stmt, _ = manager.find("noTimeout")
+	if stmt.timeout != 0 {
+		t.Fatalf("timeout should not be set : %s", stmt.timeout)
+	}
+
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="badTimeout" timeout="30 seconds">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "badTimeout") {
+		t.Fatalf("expect timeout error with statement id but %v", err)
+	}
+}
+
+func TestLoaderTarget(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="readAfterWrite" target="primary">
+		SELECT * FROM city WHERE id = {Id}
+	</select>
+	<select id="readCity">
+		SELECT * FROM city
+	</select>
+	<update id="tempWork" target="replica">
+		CREATE TEMPORARY TABLE tmp_city AS SELECT * FROM city
+	</update>
+	<update id="updateCity">
+		UPDATE city SET age = {Age}
+	</update>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	expect := map[string]bool{"readAfterWrite": false, "readCity": true, "tempWork": true, "updateCity": false}
+
+	// without replica, every statement runs on primary
+	for id := range expect {
+		stmt, _ := manager.find(id)
+		if _, ok := manager.proxyFor(stmt).(*QueryMan); !ok {
+			t.Fatalf("%s should run on primary without replica", id)
+		}
+	}
+
+	manager.replica = &sql.DB{}
+	for id, onReplica := range expect {
+		stmt, _ := manager.find(id)
+		_, ok := manager.proxyFor(stmt).(replicaProxy)
+		if ok != onReplica {
+			t.Fatalf("%s : expect replica=%v", id, onReplica)
+		}
+	}
+
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="badTarget" target="secondary">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "badTarget") {
+		t.Fatalf("expect target error with statement id but %v", err)
+	}
+}
+
+func TestLoaderCollectErrors(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.CollectLoadErrors = true
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="goodSelect">
+		SELECT * FROM city WHERE age > {Age}
+	</select>
+	<select id="goodSelect">
+		SELECT * FROM city
+	</select>
+	<select id="badTimeout" timeout="soon" softDelete="maybe">
+		SELECT * FROM city
+	</select>
+	<update id="shortQuery">
+		x
+	</update>
+	<update id="unclosedBind">
+		UPDATE city SET age = {Age WHERE id = 1
+	</update>
+</query>`))
+
+	loadErrors, ok := err.(*LoadErrors)
+	if !ok {
+		t.Fatalf("expect *LoadErrors but %v", err)
+	}
+	if len(loadErrors.Errors) != 5 {
+		t.Fatalf("expect 5 errors but %s", loadErrors.Error())
+	}
+	ids := make([]string, 0)
+	for _, v := range loadErrors.Errors {
+		ids = append(ids, v.Id)
+	}
+	if strings.Join(ids, ",") != "badTimeout,badTimeout,goodSelect,shortQuery,unclosedBind" {
+		t.Fatalf("unexpected failed statements : %v", ids)
+	}
+	if !strings.Contains(loadErrors.Errors[0].Error(), "timeout") || !strings.Contains(loadErrors.Errors[1].Error(), "softDelete") {
+		t.Fatalf("every invalid attribute should be reported : %s", loadErrors.Error())
+	}
+
+	if _, err = manager.find("goodSelect"); err != nil {
+		t.Fatalf("successful statement should be loaded : %s", err.Error())
+	}
+
+	manager.preference.CollectLoadErrors = false
+	manager.statementMap = make(map[string]QueryStatement)
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="badTimeout" timeout="soon" softDelete="maybe">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Fatalf("the first invalid attribute should be returned but %v", err)
+	}
+}
+
+func TestRegisterStatement(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := manager.Register(QueryStatement{Id: "selectPluginItem", Query: "\n\tSELECT * FROM plugin_item WHERE id = {Id}\n"})
+	if err != nil {
+		t.Fatalf("fail to register : %s", err.Error())
+	}
+
+	stmt, err := manager.find("SELECTPLUGINITEM")
+	if err != nil {
+		t.Fatalf("registered statement not found : %s", err.Error())
+	}
+	if stmt.eleType != eleTypeSelect {
+		t.Fatalf("expect select but %s", stmt.eleType)
+	}
+	if stmt.Query != "SELECT * FROM plugin_item WHERE id = ?" {
+		t.Fatalf("statement is not normalized : %s", stmt.Query)
+	}
+
+	err = manager.Register(QueryStatement{Id: "SelectPluginItem", Query: "SELECT 1 FROM plugin_item"})
+	if err == nil {
+		t.Fatalf("duplicated id should be rejected")
+	}
+
+	err = manager.RegisterXML(strings.NewReader(`<query>
+	<update id="updatePluginItem">
+		UPDATE plugin_item SET name = {Name} WHERE id = {Id}
+	</update>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to register xml : %s", err.Error())
+	}
+	if manager.GetSqlCount() != 2 {
+		t.Fatalf("expect 2 statements but %d", manager.GetSqlCount())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("selectPluginItem%d", i)
+			if err := manager.Register(QueryStatement{Id: id, Query: "SELECT * FROM plugin_item WHERE id = {Id}"}); err != nil {
+				t.Errorf("fail to register %s : %s", id, err.Error())
+			}
+			if _, err := manager.find("updatePluginItem"); err != nil {
+				t.Errorf("fail to find : %s", err.Error())
+			}
+		}(i)
+	}
+	wg.Wait()
+	if manager.GetSqlCount() != 12 {
+		t.Fatalf("expect 12 statements but %d", manager.GetSqlCount())
+	}
+
+	other := &QueryMan{}
+	other.statementMutex = &sync.RWMutex{}
+	if other.statementLock() == manager.statementLock() {
+		t.Fatalf("QueryMan with its own lock should not share the default lock")
+	}
+
+	if err = (&ShardedQueryMan{}).Register(QueryStatement{Id: "selectNoShard", Query: "SELECT 1"}); err == nil {
+		t.Fatalf("register without shard should be error")
+	}
+	if err = (&ShardedQueryMan{}).RegisterXML(strings.NewReader("<query></query>")); err == nil {
+		t.Fatalf("register xml without shard should be error")
+	}
+}
+
+func TestEstimateFromPlan(t *testing.T) {
+	columns := []string{"id", "select_type", "table", "type", "rows", "filtered", "Extra"}
+	records := [][]interface{}{
+		{int64(1), []byte("SIMPLE"), []byte("city"), []byte("ALL"), []byte("1000"), []byte("10.00"), nil},
+		{int64(1), []byte("SIMPLE"), []byte("country"), []byte("eq_ref"), []byte("1"), []byte("100.00"), nil},
+		{int64(2), []byte("SUBQUERY"), []byte("stat"), []byte("ALL"), []byte("500"), []byte("100.00"), nil},
+	}
+	estimate, err := estimateFromPlanRows(columns, records)
+	if err != nil {
+		t.Fatalf("fail to estimate : %s", err.Error())
+	}
+	if estimate != 100 {
+		t.Fatalf("expect 100 but %d", estimate)
+	}
+
+	impossible := [][]interface{}{{int64(1), []byte("SIMPLE"), nil, nil, nil, nil, []byte("Impossible WHERE")}}
+	estimate, err = estimateFromPlanRows(columns, impossible)
+	if err != nil || estimate != 0 {
+		t.Fatalf("expect 0 for impossible where but %d, %v", estimate, err)
+	}
+
+	if _, err = estimateFromPlanRows([]string{"QUERY PLAN"}, records); err == nil {
+		t.Fatalf("plan without rows column should fail")
+	}
+
+	estimate, err = estimateFromPlanJson([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "city", "Plan Rows": 4213, "Plan Width": 40}}]`))
+	if err != nil {
+		t.Fatalf("fail to estimate json plan : %s", err.Error())
+	}
+	if estimate != 4213 {
+		t.Fatalf("expect 4213 but %d", estimate)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+
+	err := classifyError(manager, fmt.Errorf("fail to exec : %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	if !errors.Is(err, ErrUniqueViolation) || errors.Is(err, ErrDeadlock) {
+		t.Fatalf("expect unique violation but %v", err)
+	}
+	var dbError *DBError
+	if !errors.As(err, &dbError) || dbError.Code != "1062" {
+		t.Fatalf("expect DBError of 1062 but %v", err)
+	}
+	var mysqlError *mysql.MySQLError
+	if !errors.As(err, &mysqlError) {
+		t.Fatalf("driver error should be unwrapped")
+	}
+
+	for number, kind := range map[uint16]error{1451: ErrForeignKeyViolation, 1452: ErrForeignKeyViolation, 1213: ErrDeadlock, 1205: ErrLockTimeout} {
+		if err = classifyError(manager, &mysql.MySQLError{Number: number}); !errors.Is(err, kind) {
+			t.Fatalf("expect %v of %d but %v", kind, number, err)
+		}
+	}
+
+	syntax := &mysql.MySQLError{Number: 1064}
+	if err = classifyError(manager, syntax); err != syntax {
+		t.Fatalf("unknown error should not be wrapped : %#v", err)
+	}
+	if err = classifyError(manager, driver.ErrBadConn); err != driver.ErrBadConn {
+		t.Fatalf("ErrBadConn should not be wrapped : %#v", err)
+	}
+
+	manager.preference.DriverName = "postgres"
+	if err = classifyError(manager, testSqlStateError("23503")); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Fatalf("expect foreign key violation but %v", err)
+	}
+
+	manager.preference.ErrorClassifier = func(err error) (error, string) {
+		return ErrLockTimeout, "custom"
+	}
+	if err = classifyError(manager, testSqlStateError("23503")); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("ErrorClassifier of preference should be used but %v", err)
+	}
+}
+
+func TestBuildStructInsert(t *testing.T) {
+	type cityCreate struct {
+		Name       string
+		Age        int
+		IsMan      bool `db:"is_man"`
+		UpdateTime *time.Time
+		Memo       string `db:"-"`
+		internal   string
+	}
+
+	query, params, err := buildStructInsert("city", &cityCreate{Name: "seoul", internal: "x"}, CamelConvertStrategy{}.convertColumnName)
+	if err != nil {
+		t.Fatalf("fail to build : %s", err.Error())
+	}
+	if query != "INSERT INTO city (name, age, is_man) VALUES ({Name}, {Age}, {IsMan})" {
+		t.Fatalf("invalid insert : %s", query)
+	}
+	if len(params) != 3 || params["Name"] != "seoul" || params["Age"] != 0 || params["IsMan"] != false {
+		t.Fatalf("invalid params : %v", params)
+	}
+
+	now := time.Now()
+	query, params, err = buildStructInsert("city", cityCreate{UpdateTime: &now}, CamelConvertStrategy{}.convertColumnName)
+	if err != nil || !strings.Contains(query, "update_time") || params["UpdateTime"] != now {
+		t.Fatalf("present pointer field should be inserted : %s, %v, %v", query, params, err)
+	}
+
+	if _, _, err = buildStructInsert("city", map[string]interface{}{"Name": "seoul"}, CamelConvertStrategy{}.convertColumnName); err != ErrInsertStructNeedStruct {
+		t.Fatalf("expect ErrInsertStructNeedStruct but %v", err)
+	}
+	type memoOnly struct {
+		Memo string `db:"-"`
+	}
+	if _, _, err = buildStructInsert("city", memoOnly{}, CamelConvertStrategy{}.convertColumnName); err != ErrInsertStructNoColumn {
+		t.Fatalf("expect ErrInsertStructNoColumn but %v", err)
+	}
+}
+
+func TestBuildStructUpdate(t *testing.T) {
+	type cityUpdate struct {
+		Id         int `db:"id,pk"`
+		Name       string
+		Age        int
+		Percentage *float32
+		Memo       string `db:"-"`
+	}
+
+	columnOf := CamelConvertStrategy{}.convertColumnName
+	query, params, err := buildStructUpdate("city", &cityUpdate{Id: 7, Name: "seoul", Memo: "skip"}, false, columnOf)
+	if err != nil {
+		t.Fatalf("fail to build : %s", err.Error())
+	}
+	if query != "UPDATE city SET name = {Name} WHERE id = {Id}" || len(params) != 2 || params["Id"] != 7 {
+		t.Fatalf("invalid update : %s, params=%v", query, params)
+	}
+
+	query, params, err = buildStructUpdate("city", cityUpdate{Id: 7}, true, columnOf)
+	if err != nil {
+		t.Fatalf("fail to build : %s", err.Error())
+	}
+	if query != "UPDATE city SET name = {Name}, age = {Age}, percentage = {Percentage} WHERE id = {Id}" {
+		t.Fatalf("invalid update of all fields : %s", query)
+	}
+	if v, ok := params["Percentage"]; !ok || v != nil {
+		t.Fatalf("nil pointer should be set NULL : %v", params)
+	}
+
+	type compositeKey struct {
+		CityId int    `db:",pk"`
+		Lang   string `db:"lang_code,pk"`
+		Label  string
+	}
+	query, _, err = buildStructUpdate("city_label", compositeKey{CityId: 1, Lang: "ko", Label: "서울"}, false, columnOf)
+	if err != nil || query != "UPDATE city_label SET label = {Label} WHERE city_id = {CityId} AND lang_code = {Lang}" {
+		t.Fatalf("invalid composite key update : %s, %v", query, err)
+	}
+
+	type noKey struct {
+		Name string
+	}
+	if _, _, err = buildStructUpdate("city", noKey{Name: "seoul"}, false, columnOf); err != ErrUpdateStructNoKey {
+		t.Fatalf("expect ErrUpdateStructNoKey but %v", err)
+	}
+	if _, _, err = buildStructUpdate("city", cityUpdate{Id: 7}, false, columnOf); err != ErrUpdateStructNoColumn {
+		t.Fatalf("expect ErrUpdateStructNoColumn but %v", err)
+	}
+}
+
+func TestLikeBindModifier(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgresql"} {
+		normalizer := newNormalizer(driver)
+		stmt := QueryStatement{eleType: eleTypeSelect, Id: "searchCity"}
+		stmt.Query = "SELECT * FROM city WHERE name LIKE {Q:like} AND age > {Age}"
+		if err := normalizer.normalize(&stmt); err != nil {
+			t.Fatalf("fail to normalize : %s", err.Error())
+		}
+		if strings.Contains(stmt.Query, "ESCAPE") {
+			t.Fatalf("%s escapes with backslash by default : %s", driver, stmt.Query)
+		}
+
+		_, param, err := resolveColumnBindInList(normalizer, stmt, []interface{}{`10%_off\`, 3})
+		if err != nil {
+			t.Fatalf("fail to resolve : %s", err.Error())
+		}
+		if param[0] != `%10\%\_off\\%` || param[1] != 3 {
+			t.Fatalf("invalid like pattern : %v", param)
+		}
+	}
+
+	normalizer := newNormalizer("oci8")
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "searchCity"}
+	stmt.Query = "SELECT * FROM city WHERE name LIKE {Q:like} AND age > {Age}"
+	if err := normalizer.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if stmt.Query != `SELECT * FROM city WHERE name LIKE :val0 ESCAPE '\' AND age > :val1` {
+		t.Fatalf("oracle needs ESCAPE clause : %s", stmt.Query)
+	}
+
+	for _, driver := range []string{"sqlite3", "sqlserver"} {
+		stmt = QueryStatement{eleType: eleTypeSelect, Id: "searchCity"}
+		stmt.Query = "SELECT * FROM city WHERE name LIKE {Q:like} AND age > {Age}"
+		if err := newNormalizer(driver).normalize(&stmt); err != nil {
+			t.Fatalf("fail to normalize : %s", err.Error())
+		}
+		if stmt.Query != `SELECT * FROM city WHERE name LIKE ? ESCAPE '\' AND age > ?` {
+			t.Fatalf("%s falling back to mysql strategy needs ESCAPE clause : %s", driver, stmt.Query)
+		}
+	}
+
+	var empty *string
+	for v, expect := range map[interface{}]interface{}{nil: nil, empty: nil, 42: "%42%", "seoul": "%seoul%"} {
+		pattern, err := likeBindValue(v)
+		if err != nil || pattern != expect {
+			t.Fatalf("expect %v of %v but %v, %v", expect, v, pattern, err)
+		}
+	}
+}
+
+func TestColumnValue(t *testing.T) {
+	for _, c := range []struct {
+		typeName string
+		v        interface{}
+		expect   interface{}
+	}{
+		{"BIGINT", []byte("42"), int64(42)},
+		{"UNSIGNED BIGINT", []byte("18446744073709551615"), uint64(18446744073709551615)},
+		{"int4", []byte("-7"), int64(-7)},
+		{"DOUBLE", []byte("1.5"), 1.5},
+		{"DECIMAL", []byte("10.10"), "10.10"},
+		{"VARCHAR", []byte("seoul"), "seoul"},
+		{"INTERVAL", []byte("1 day"), "1 day"},
+		{"DATETIME", time.Time{}, time.Time{}},
+		{"INT", int64(3), int64(3)},
+		{"INT", nil, nil},
+	} {
+		if v := columnValue(c.typeName, c.v); v != c.expect {
+			t.Fatalf("%s of %v : expect %#v but %#v", c.typeName, c.v, c.expect, v)
+		}
+	}
+
+	if v, ok := columnValue("BLOB", []byte{0x1, 0x2}).([]byte); !ok || len(v) != 2 {
+		t.Fatalf("binary should stay []byte : %v", v)
+	}
+}
+
+func TestOrdinalPlaceholder(t *testing.T) {
+	postgres := newNormalizer("postgresql")
+	stmt := QueryStatement{eleType: eleTypeUpdate, Id: "UPDATE city SET age = ? WHERE name = ? AND memo <> '?'", userQuery: true}
+	stmt.Query = stmt.Id
+	if err := postgres.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if stmt.Query != "UPDATE city SET age = $1 WHERE name = $2 AND memo <> '?'" {
+		t.Fatalf("? should be rewritten for postgresql : %s", stmt.Query)
+	}
+
+	stmt = QueryStatement{eleType: eleTypeSelect, Id: "SELECT * FROM city WHERE tags ?? 'capital' AND age > ? -- age?", userQuery: true}
+	stmt.Query = stmt.Id
+	if err := postgres.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+	if stmt.Query != "SELECT * FROM city WHERE tags ? 'capital' AND age > $1 -- age?" {
+		t.Fatalf("?? should be literal ? : %s", stmt.Query)
+	}
+
+	stmt = QueryStatement{eleType: eleTypeSelect, Id: "SELECT * FROM city WHERE name = {Name} AND age > ?", userQuery: true}
+	stmt.Query = stmt.Id
+	if postgres.normalize(&stmt) == nil {
+		t.Fatalf("mixing ? and {} bind should be rejected")
+	}
+
+	mysqlNormalizer := newNormalizer("mysql")
+	stmt = QueryStatement{eleType: eleTypeSelect, Id: "selectTag"}
+	stmt.Query = "SELECT * FROM city WHERE name = {Name} AND age > ?"
+	if err := mysqlNormalizer.normalize(&stmt); err != nil || stmt.Query != "SELECT * FROM city WHERE name = ? AND age > ?" {
+		t.Fatalf("mysql query should not be changed : %s, %v", stmt.Query, err)
+	}
+
+	queryNormalizer = postgres
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.DriverName = "postgresql"
+	manager.statementMap = make(map[string]QueryStatement)
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectByKey">
+		SELECT * FROM city WHERE data ? 'key' AND name = {Name}
+	</select>
+</query>`))
+	queryNormalizer = mysqlNormalizer
+	if err != nil {
+		t.Fatalf("? of xml statement should be kept : %s", err.Error())
+	}
+	stmt, err = manager.find("selectByKey")
+	if err != nil || stmt.Query != "SELECT * FROM city WHERE data ? 'key' AND name = $1" {
+		t.Fatalf("jsonb operator of xml statement should not be rewritten : %s, %v", stmt.Query, err)
+	}
+}
+
+func TestMaxScanRows(t *testing.T) {
+	result := &QueryResult{maxScanRows: 2}
+	if err := result.checkScanRows(1); err != nil {
+		t.Fatalf("2nd row is in the limit : %s", err.Error())
+	}
+	err := result.checkScanRows(2)
+	if !errors.Is(err, ErrMaxScanRowsExceeded) || !strings.Contains(err.Error(), "2") {
+		t.Fatalf("3rd row should exceed the limit but %v", err)
+	}
+	if err = (&QueryResult{}).checkScanRows(1000000); err != nil {
+		t.Fatalf("0 means no limit : %s", err.Error())
+	}
+
+	var city struct{ Name string }
+	if err = result.ScanAll(&city); err != ErrScanAllNeedSlice {
+		t.Fatalf("expect ErrScanAllNeedSlice but %v", err)
+	}
+}
+
+func TestDialectStatement(t *testing.T) {
+	data := []byte(`<query>
+	<select id="selectNow">
+		SELECT NOW()
+	</select>
+	<select id="selectNow" dialect="postgres">
+		SELECT CURRENT_TIMESTAMP
+	</select>
+	<select id="selectDate" dialect="mysql">
+		SELECT DATE_FORMAT(create_time, '%Y') FROM city
+	</select>
+	<select id="selectDate" dialect="postgresql">
+		SELECT TO_CHAR(create_time, 'YYYY') FROM city
+	</select>
+	<select id="selectDate">
+		SELECT create_time FROM city
+	</select>
+	<select id="selectAge">
+		SELECT age FROM city
+	</select>
+</query>`)
+
+	for driver, expect := range map[string][]string{
+		"mysql":      {"SELECT NOW()", "SELECT DATE_FORMAT(create_time, '%Y') FROM city"},
+		"postgresql": {"SELECT CURRENT_TIMESTAMP", "SELECT TO_CHAR(create_time, 'YYYY') FROM city"},
+		"oci8":       {"SELECT NOW()", "SELECT create_time FROM city"},
+	} {
+		queryNormalizer = newNormalizer(driver)
+		manager := &QueryMan{}
+		manager.preference = NewQuerymanPreference("", "")
+		manager.preference.DriverName = driver
+		manager.statementMap = make(map[string]QueryStatement)
+
+		if err := loadWithSax(manager, data); err != nil {
+			t.Fatalf("fail to load for %s : %s", driver, err.Error())
+		}
+		for i, id := range []string{"selectNow", "selectDate"} {
+			stmt, err := manager.find(id)
+			if err != nil || stmt.Query != expect[i] {
+				t.Fatalf("%s of %s : expect %s but %s, %v", id, driver, expect[i], stmt.Query, err)
+			}
+		}
+		if manager.GetSqlCount() != 3 {
+			t.Fatalf("expect 3 statements for %s but %d", driver, manager.GetSqlCount())
+		}
+
+		view := manager.WithNormalizer("postgresql")
+		stmt, err := view.find("selectDate")
+		if err != nil || stmt.Query != "SELECT TO_CHAR(create_time, 'YYYY') FROM city" {
+			t.Fatalf("view of postgresql on %s should pick its variant : %s, %v", driver, stmt.Query, err)
+		}
+		stmt, err = manager.WithNormalizer("oci8").find("selectDate")
+		if err != nil || stmt.Query != "SELECT create_time FROM city" {
+			t.Fatalf("view without variant should pick the default : %s, %v", stmt.Query, err)
+		}
+	}
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectNow" dialect="mysql">SELECT NOW()</select>
+	<select id="selectNow" dialect="mysql">SELECT SYSDATE()</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "duplicated") {
+		t.Fatalf("same dialect should be duplicated but %v", err)
+	}
+
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="selectToday" dialect="sqlserver">SELECT GETDATE()</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "invalid dialect") {
+		t.Fatalf("unknown dialect should be rejected but %v", err)
+	}
+}
+
+func TestDerefArgList(t *testing.T) {
+	args := []interface{}{"seoul", []int{1, 2}}
+	v := []interface{}{&args, 3}
+	derefed := derefArgList(v)
+	if !reflect.DeepEqual(derefed, []interface{}{args, 3}) {
+		t.Fatalf("*[]interface{} should be dereferenced : %v", derefed)
+	}
+	if _, ok := v[0].(*[]interface{}); !ok {
+		t.Fatalf("caller args should not be modified")
+	}
+
+	var nilList *[]interface{}
+	plain := []interface{}{args, nilList, &[]int{1}}
+	if derefed = derefArgList(plain); &derefed[0] != &plain[0] {
+		t.Fatalf("args without *[]interface{} should be returned as it is")
+	}
+}
+
+func TestExecMultiResultMerge(t *testing.T) {
+	result := ExecMultiResult{}
+	(&result).merge(ExecMultiResult{idList: []int64{11, 12}, rowAffected: 2})
+	(&result).merge(ExecMultiResult{idList: []int64{13}, rowAffected: 1})
+	(&result).merge(driver.RowsAffected(3))
+	if !reflect.DeepEqual(result.GetInsertIdList(), []int64{11, 12, 13}) {
+		t.Fatalf("invalid id list : %v", result.GetInsertIdList())
+	}
+	if affected, _ := result.RowsAffected(); affected != 6 {
+		t.Fatalf("expect 6 affected but %d", affected)
+	}
+	if id, _ := result.LastInsertId(); id != 11 {
+		t.Fatalf("expect first id 11 but %d", id)
+	}
+}
+
+func TestSoftDeleteStatement(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectAliveCity" softDelete="true">
+		SELECT * FROM city WHERE age > {Age} AND {softDeleteFilter}
+		<if key="Name">
+			AND name = {Name}
+		</if>
+	</select>
+	<select id="selectAnyCity">
+		SELECT * FROM city WHERE age > {Age} AND {softDeleteFilter}
+	</select>
+	<select id="selectNamedAliveCity" softDelete="true">
+		SELECT * FROM city WHERE age > {Age}
+		<if key="Name">
+			AND name = {Name} AND {softDeleteFilter}
+		</if>
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, _ := manager.find("selectAliveCity")
+	if !strings.Contains(stmt.Query, "AND deleted_at IS NULL") {
+		t.Fatalf("filter should replace the marker : %s", stmt.Query)
+	}
+	stmt, _ = manager.find("selectAnyCity")
+	if stmt.Query != "SELECT * FROM city WHERE age > ? AND 1 = 1" {
+		t.Fatalf("marker without softDelete should be always true : %s", stmt.Query)
+	}
+	stmt, _ = manager.find("selectNamedAliveCity")
+	refined, err := stmt.refine(queryNormalizer, map[string]interface{}{"Age": 1, "Name": "seoul"})
+	if err != nil || !strings.Contains(refined.Query, "name = ? AND deleted_at IS NULL") {
+		t.Fatalf("marker of if clause should be replaced : %s, %v", refined.Query, err)
+	}
+
+	manager.preference.SoftDeleteFilter = "removed = 0"
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="selectRemovedCity" softDelete="true">
+		SELECT * FROM city WHERE {softDeleteFilter}
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+	stmt, _ = manager.find("selectRemovedCity")
+	if stmt.Query != "SELECT * FROM city WHERE removed = 0" {
+		t.Fatalf("SoftDeleteFilter should replace the marker : %s", stmt.Query)
+	}
+
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="selectNoMarker" softDelete="true">
+		SELECT * FROM city
+	</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "marker") {
+		t.Fatalf("softDelete without marker should fail but %v", err)
+	}
+
+	err = loadWithSax(manager, []byte(`<query>
+	<select id="selectBadAttr" softDelete="yes please">
+		SELECT * FROM city WHERE {softDeleteFilter}
+	</select>
+</query>`))
+	if err == nil || !strings.Contains(err.Error(), "invalid softDelete") {
+		t.Fatalf("invalid softDelete should fail but %v", err)
+	}
+}
+
+// testColumnConverter keeps CamelCase column as field name and records the columns it sees
+type testColumnConverter struct {
+	seen *[]string
+}
+
+func (c testColumnConverter) ColumnToField(column string) string {
+	*c.seen = append(*c.seen, column)
+	return column
+}
+
+func (c testColumnConverter) FieldToColumn(field string) string {
+	return field
+}
+
+func TestAnonymousStructScanner(t *testing.T) {
+	var row struct {
+		ID       int
+		CityName string `db:"name"`
+		Age      int    `db:"-"`
+		Memo     string `db:"remark"`
+	}
+
+	val := reflect.ValueOf(&row).Elem()
+	ss, err := newStructureScanner(CamelConvertStrategy{}, []string{"id", "NAME", "age", "memo"}, &val, false)
+	if err != nil {
+		t.Fatalf("fail to plan : %s", err.Error())
+	}
+	for _, v := range []interface{}{int64(7), []byte("seoul"), int64(42), []byte("x")} {
+		if err = ss.Scan(v); err != nil {
+			t.Fatalf("fail to scan : %s", err.Error())
+		}
+	}
+	if row.ID != 7 || row.CityName != "seoul" || row.Age != 0 || row.Memo != "" {
+		t.Fatalf("invalid scan : %+v", row)
+	}
+
+	var camel struct {
+		CityName string
+		ZipCode  string
+	}
+	seen := make([]string, 0)
+	converter := userConvertStrategy{converter: testColumnConverter{seen: &seen}}
+	val = reflect.ValueOf(&camel).Elem()
+	ss, err = newStructureScanner(converter, []string{"CityName", "ZipCode"}, &val, false)
+	if err != nil {
+		t.Fatalf("fail to plan : %s", err.Error())
+	}
+	if fmt.Sprint(seen) != "[CityName ZipCode]" {
+		t.Fatalf("custom converter should see the column as it is : %v", seen)
+	}
+	for _, v := range []interface{}{[]byte("seoul"), []byte("04524")} {
+		if err = ss.Scan(v); err != nil {
+			t.Fatalf("fail to scan : %s", err.Error())
+		}
+	}
+	if camel.CityName != "seoul" || camel.ZipCode != "04524" {
+		t.Fatalf("invalid scan of camel case columns : %+v", camel)
+	}
+
+	var pair struct {
+		Total int64
+		Count int
+	}
+	val = reflect.ValueOf(&pair).Elem()
+	if _, err = newPositionalScanner(3, &val); err == nil || !strings.Contains(err.Error(), "struct {") {
+		t.Fatalf("error should name the anonymous type but %v", err)
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectCity">
+		SELECT * FROM city WHERE age > {Age} AND name = {Name} OR age &lt; {Age}
+		<if key="Memo">
+			AND memo = {Memo} AND nation = {Nation}
+		</if>
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	missing, err := manager.CheckParams("selectCity", map[string]interface{}{"Age": 1})
+	if err != nil || len(missing) != 1 || missing[0] != "Name" {
+		t.Fatalf("expect [Name] but %v, %v", missing, err)
+	}
+
+	missing, err = manager.CheckParams("selectCity", map[string]interface{}{"Age": 1, "Name": "seoul", "Memo": "x"})
+	if err != nil || len(missing) != 1 || missing[0] != "Nation" {
+		t.Fatalf("accepted if clause should require Nation but %v, %v", missing, err)
+	}
+
+	missing, err = manager.CheckParams("selectCity", map[string]interface{}{"Age": 1, "Name": "seoul"})
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("expect no missing but %v, %v", missing, err)
+	}
+
+	manager.preference.MapKeyCaseInsensitive = true
+	missing, err = manager.CheckParams("selectCity", map[string]interface{}{"age": 1, "name": "seoul"})
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("case insensitive key should be found but %v, %v", missing, err)
+	}
+
+	if _, err = manager.CheckParams("selectNothing", nil); err == nil {
+		t.Fatalf("unknown statement should fail")
+	}
+}
+
+func TestNestedTimeRange(t *testing.T) {
+	type period struct {
+		From time.Time
+		To   *time.Time
+	}
+	type orderFilter struct {
+		Shop   string
+		Period period
+		Closed *period
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	stmt := QueryStatement{eleType: eleTypeSelect, Id: "selectOrder"}
+	stmt.Query = "SELECT * FROM orders WHERE shop = {Shop} AND created >= {period.from:time} AND created < {Period.To} AND closed < {Closed.To}"
+	err := queryNormalizer.normalize(&stmt)
+	if err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+
+	from := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	filter := orderFilter{Shop: "seoul", Period: period{From: from, To: &to}}
+	query, param, result := resolveColumnBindInMap(manager, stmt, flattenStructToMap(filter))
+	if result != nil {
+		t.Fatalf("fail to resolve : %s", result.GetError().Error())
+	}
+	if query != "SELECT * FROM orders WHERE shop = ? AND created >= ? AND created < ? AND closed < ?" {
+		t.Fatalf("invalid query : %s", query)
+	}
+	if len(param) != 4 || param[1] != from || param[2] != to || param[3] != nil {
+		t.Fatalf("time range should be bound as time values : %v", param)
+	}
+}
+
+func TestOrderByNulls(t *testing.T) {
+	expects := map[string][]string{
+		"mysql":      {"age IS NULL ASC, age DESC", "age IS NULL DESC, age ASC", "age ASC"},
+		"postgresql": {"age DESC NULLS LAST", "age ASC NULLS FIRST", "age ASC"},
+		"oci8":       {"age DESC NULLS LAST", "age ASC NULLS FIRST", "age ASC"},
+	}
+	for driver, expect := range expects {
+		normalizer := newNormalizer(driver).(*UserQueryNormalizer)
+		for i, term := range []struct {
+			desc  bool
+			nulls string
+		}{{true, "last"}, {false, "FIRST"}, {false, ""}} {
+			ordered, err := normalizer.orderByNulls("age", term.desc, term.nulls)
+			if err != nil {
+				t.Fatalf("%s : fail to order : %s", driver, err.Error())
+			}
+			if ordered != expect[i] {
+				t.Fatalf("%s : expect [%s] but [%s]", driver, expect[i], ordered)
+			}
+		}
+	}
+
+	if _, err := newNormalizer("mysql").(*UserQueryNormalizer).orderByNulls("age", false, "middle"); err == nil {
+		t.Fatalf("invalid nulls ordering should fail")
+	}
+
+	queryNormalizer = newNormalizer("mysql")
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.DriverName = "mysql"
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectOrderedCity">
+		SELECT * FROM city ORDER BY <orderby column="age" desc="true" nulls="last"/>, name
+	</select>
+	<select id="selectOrderedCityPg" dialect="postgresql">
+		SELECT * FROM city ORDER BY <orderby column="age" nulls="first"/>
+	</select>
+	<select id="selectSortedCity" identifiers="age,name">
+		SELECT * FROM city WHERE 1=1
+		<if key="Name">
+			AND name = {Name} ORDER BY <orderby column="{{Sort}}" nulls="last"/>
+		</if>
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, _ := manager.find("selectOrderedCity")
+	if stmt.Query != "SELECT * FROM city ORDER BY age IS NULL ASC, age DESC, name" {
+		t.Fatalf("invalid orderby of mysql : %s", stmt.Query)
+	}
+	if _, err = manager.find("selectOrderedCityPg"); err == nil {
+		t.Fatalf("statement of other dialect should not be found")
+	}
+
+	stmt, _ = manager.find("selectSortedCity")
+	refined, err := stmt.refine(queryNormalizer, map[string]interface{}{"Name": "seoul", "Sort": "name"})
+	if err != nil {
+		t.Fatalf("fail to refine : %s", err.Error())
+	}
+	if !strings.Contains(refined.Query, "ORDER BY `name` IS NULL ASC, `name` ASC") {
+		t.Fatalf("invalid orderby of identifier : %s", refined.Query)
+	}
+
+	for _, xml := range []string{
+		`<query><select id="selectBadNulls">SELECT * FROM city ORDER BY <orderby column="age" nulls="middle"/></select></query>`,
+		`<query><select id="selectNoColumn">SELECT * FROM city ORDER BY <orderby nulls="last"/></select></query>`,
+	} {
+		if err = loadWithSax(manager, []byte(xml)); err == nil {
+			t.Fatalf("invalid orderby should fail : %s", xml)
+		}
+	}
+}
+
+func TestDeleteInArray(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<delete id="deleteCities">
+		DELETE FROM city WHERE id IN ({Ids})
+	</delete>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	for _, id := range []string{"deleteCities", "DELETE FROM city WHERE id IN ({Ids})"} {
+		stmt, err := manager.find(id)
+		if err != nil {
+			t.Fatalf("fail to find %s : %s", id, err.Error())
+		}
+		if stmt.eleType != eleTypeUpdate || !stmt.firstArgsIsArray() {
+			t.Fatalf("delete with IN array should be single execution : %s", stmt)
+		}
+
+		for _, ids := range []interface{}{[]int{1, 2, 3}, []interface{}{1, 2, 3}} {
+			query, param, err := resolveColumnBindInList(queryNormalizer, stmt, []interface{}{ids})
+			if err != nil {
+				t.Fatalf("fail to resolve : %s", err.Error())
+			}
+			if query != "DELETE FROM city WHERE id IN (?,?,?)" || len(param) != 3 {
+				t.Fatalf("invalid IN expansion : %s, %v", query, param)
+			}
+		}
+	}
+}
+
+func TestPreserveIdCase(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+	data := []byte(`<query>
+	<select id="findUser">
+		SELECT * FROM member WHERE id = {Id}
+	</select>
+	<select id="FindUser">
+		SELECT * FROM member WHERE name = {Name}
+	</select>
+</query>`)
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+	err := loadWithSax(manager, data)
+	if err == nil || !strings.Contains(err.Error(), "duplicated") {
+		t.Fatalf("ids differing by case should collide by default but %v", err)
+	}
+
+	manager.preference.PreserveIdCase = true
+	manager.statementMap = make(map[string]QueryStatement)
+	if err = loadWithSax(manager, data); err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, err := manager.find("findUser")
+	if err != nil || !strings.Contains(stmt.Query, "id = ?") {
+		t.Fatalf("invalid findUser : %v, %v", stmt.Query, err)
+	}
+	stmt, err = manager.find("FindUser")
+	if err != nil || !strings.Contains(stmt.Query, "name = ?") {
+		t.Fatalf("invalid FindUser : %v, %v", stmt.Query, err)
+	}
+	if _, err = manager.find("FINDUSER"); err == nil {
+		t.Fatalf("id should be case sensitive")
+	}
+}
+
+func TestNilForeignTransaction(t *testing.T) {
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+
+	if _, err := manager.ExecuteWithTx(nil, "UPDATE city SET age = 1"); err != ErrNilTransaction {
+		t.Fatalf("expect ErrNilTransaction but %v", err)
+	}
+	if err := manager.QueryWithTx(nil, "SELECT * FROM city").GetError(); err != ErrNilTransaction {
+		t.Fatalf("expect ErrNilTransaction but %v", err)
+	}
+	type city struct {
+		Id   int
+		Name *string
+	}
+	tx := newTransaction(nil, nil, nil, CamelConvertStrategy{}, nil)
+	if _, err := tx.UpdatePartial("city", city{Id: 1}, "Id"); err != ErrPartialUpdateNoColumn {
+		t.Fatalf("expect ErrPartialUpdateNoColumn but %v", err)
+	}
+	if _, err := tx.UpdateStruct("city", city{Id: 1}); err != ErrUpdateStructNoKey {
+		t.Fatalf("expect ErrUpdateStructNoKey but %v", err)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	cached := &cachedResult{}
+	cached.columns = []string{"id", "name", "age"}
+	cached.typeNames = []string{"BIGINT", "VARCHAR", "INT"}
+	cached.values = [][]interface{}{{int64(1), []byte("seoul"), []byte("42")}, {int64(2), []byte("busan"), nil}}
+
+	type city struct {
+		Id   int
+		Name string
+		Age  int
+	}
+
+	cities := make([]city, 0)
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanAll(&cities); err != nil {
+		t.Fatalf("fail to scan cached rows : %s", err.Error())
+	}
+	if len(cities) != 2 || cities[0].Name != "seoul" || cities[0].Age != 42 || cities[1].Id != 2 {
+		t.Fatalf("invalid cached rows : %v", cities)
+	}
+
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}}
+	rows, err := result.ScanMapSlice()
+	if err != nil || len(rows) != 2 || rows[0]["age"] != int64(42) || rows[0]["name"] != "seoul" {
+		t.Fatalf("invalid cached values : %v, %v", rows, err)
+	}
+	if result.GetRows() != nil {
+		t.Fatalf("cached result has no driver rows")
+	}
+
+	var id int
+	var name []byte
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}}
+	result.Next()
+	if err = result.Scan(&id, &name); err == nil {
+		t.Fatalf("scan with fewer destinations should fail")
+	}
+	var age sql.NullInt64
+	if err = result.Scan(&id, &name, &age); err != nil || id != 1 || string(name) != "seoul" || age.Int64 != 42 {
+		t.Fatalf("invalid scan : %d, %s, %v, %v", id, name, age, err)
+	}
+	name[0] = 'S'
+	if string(cached.values[0][1].([]byte)) != "seoul" {
+		t.Fatalf("scan should not share cached bytes")
+	}
+	result.Close()
+	if result.rows != nil {
+		t.Fatalf("closed result should release rows")
+	}
+
+	c := newResultCache(2)
+	now := time.Now()
+	query := "SELECT * FROM city WHERE id = ?"
+	key := resultCacheKey("selectCity", query, []interface{}{1})
+	if key == resultCacheKey("selectCity", query, []interface{}{"1"}) || key == resultCacheKey("selectTown", query, []interface{}{1}) {
+		t.Fatalf("key should differ by statement and parameter type")
+	}
+	cached.expire = now.Add(time.Minute)
+	c.put(key, cached, now)
+	if _, ok := c.get(key, now.Add(time.Second)); !ok {
+		t.Fatalf("cache should hit before ttl")
+	}
+	if _, ok := c.get(key, now.Add(time.Minute)); ok {
+		t.Fatalf("cache should expire after ttl")
+	}
+	if len(c.entries) != 0 {
+		t.Fatalf("expired entry should be dropped")
+	}
+
+	for i := 1; i <= 3; i++ {
+		c.put(resultCacheKey("selectCity", query, []interface{}{i}), cached, now)
+		if i == 2 {
+			c.get(resultCacheKey("selectCity", query, []interface{}{1}), now)
+		}
+	}
+	if c.order.Len() != 2 || len(c.entries) != 2 {
+		t.Fatalf("entries should be kept up to capacity : %d", len(c.entries))
+	}
+	if _, ok := c.get(resultCacheKey("selectCity", query, []interface{}{2}), now); ok {
+		t.Fatalf("least recently used entry should be evicted")
+	}
+	if _, ok := c.get(resultCacheKey("selectCity", query, []interface{}{1}), now); !ok {
+		t.Fatalf("recently used entry should be kept")
+	}
+}
+
+func TestCacheTTLAttribute(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectCode" cacheTTL="60s">
+		SELECT * FROM code WHERE kind = {Kind}
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+	stmt, _ := manager.find("selectCode")
+	if stmt.cacheTTL != time.Minute {
+		t.Fatalf("invalid cacheTTL : %s", stmt.cacheTTL)
+	}
+
+	for _, xml := range []string{
+		`<query><select id="selectBadTTL" cacheTTL="soon">SELECT 1</select></query>`,
+		`<query><update id="updateCode" cacheTTL="60s">UPDATE code SET kind = 1</update></query>`,
+	} {
+		if err = loadWithSax(manager, []byte(xml)); err == nil || !strings.Contains(err.Error(), "cacheTTL") {
+			t.Fatalf("invalid cacheTTL should fail but %v", err)
+		}
+	}
+}
+
+func TestDeclareSqlType(t *testing.T) {
+	cases := map[string]declareElementType{
+		"SELECT 1":                        eleTypeSelect,
+		"  select * FROM city":            eleTypeSelect,
+		"(SELECT 1) UNION (SELECT 2)":     eleTypeSelect,
+		"SHOW TABLES":                     eleTypeSelect,
+		"explain SELECT * FROM city":      eleTypeSelect,
+		"DESCRIBE city":                   eleTypeSelect,
+		"-- list cities\nSELECT * FROM c": eleTypeSelect,
+		"/* hint */ INSERT INTO city (name) VALUES ({Name})":                                       eleTypeInsert,
+		"WITH old AS (SELECT id FROM city WHERE age > 40) SELECT * FROM old":                       eleTypeSelect,
+		"WITH RECURSIVE t(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t) SELECT n FROM t":          eleTypeSelect,
+		"with a AS (SELECT ')' x), b AS (SELECT 1) DELETE FROM city WHERE id IN (SELECT x FROM a)": eleTypeUpdate,
+		"WITH src AS (SELECT 1) INSERT INTO city (id) SELECT * FROM src":                           eleTypeInsert,
+		"UPDATE city SET age = 1":  eleTypeUpdate,
+		"SET":                      eleTypeUpdate,
+		"":                         eleTypeUpdate,
+		"/* only comment":          eleTypeUpdate,
+		"CALL refresh_city_stat()": eleTypeUpdate,
+	}
+	for query, expect := range cases {
+		if declared := getDeclareSqlType(query, true); declared != expect {
+			t.Fatalf("expect %s but %s : %s", expect, declared, query)
+		}
+	}
+}
+
+func TestBulkProgress(t *testing.T) {
+	b := newQuerymanBulk(nil, QueryStatement{})
+	for i := 0; i < 10; i++ {
+		b.rows = append(b.rows, []interface{}{i})
+	}
+	b.reportProgress(3)
+
+	reports := make([]int, 0)
+	b.OnProgress(4, func(done, total int) {
+		if total != 10 {
+			t.Fatalf("expect total 10 but %d", total)
+		}
+		reports = append(reports, done)
+	})
+	for _, done := range []int{2, 4, 6, 8, 10} {
+		b.reportProgress(done)
+	}
+	if fmt.Sprint(reports) != "[4 8 10]" {
+		t.Fatalf("invalid progress reports : %v", reports)
+	}
+
+	b.reported = 0
+	b.AddSeq(func(yield func(interface{}) bool) {})
+	b.OnProgress(0, func(done, total int) {
+		if total != -1 {
+			t.Fatalf("total of seq should be unknown but %d", total)
+		}
+		reports = append(reports, done)
+	})
+	b.reportProgress(1)
+	if len(reports) != 4 {
+		t.Fatalf("every chunk should be reported : %v", reports)
+	}
+
+	b.progressEvery = 4
+	b.reported = 0
+	reports = reports[:0]
+	b.reportProgress(4)
+	b.reportProgress(6)
+	b.finishProgress(6)
+	b.finishProgress(6)
+	if fmt.Sprint(reports) != "[4 6]" {
+		t.Fatalf("last chunk of seq should be reported once : %v", reports)
+	}
+}
+
+// geometryPoint is a custom type a driver accepts by NamedValueChecker
+type geometryPoint struct {
+	X float64
+	Y float64
+}
+
+func TestCustomDriverTypePassThrough(t *testing.T) {
+	type place struct {
+		Name     string
+		Location *geometryPoint
+		Area     geometryPoint
+		Floor    *int
+		Nothing  *geometryPoint
+	}
+
+	floor := 3
+	p := place{Name: "hall", Location: &geometryPoint{X: 1, Y: 2}, Area: geometryPoint{X: 3, Y: 4}, Floor: &floor}
+	m := flattenStructToMap(p)
+	if _, ok := m["Location"].(*geometryPoint); !ok {
+		t.Fatalf("pointer of custom type should reach driver as it is : %T", m["Location"])
+	}
+	if _, ok := m["Area"].(geometryPoint); !ok {
+		t.Fatalf("custom type should reach driver as it is : %T", m["Area"])
+	}
+	if m["Floor"] != 3 || m["Nothing"] != nil {
+		t.Fatalf("basic pointer is bound as value and nil pointer as NULL : %v, %v", m["Floor"], m["Nothing"])
+	}
+
+	query, params, err := buildStructInsert("place", p, func(field string) string { return field })
+	if err != nil {
+		t.Fatalf("fail to build insert : %s", err.Error())
+	}
+	if _, ok := params["Location"].(*geometryPoint); !ok || strings.Contains(query, "Nothing") {
+		t.Fatalf("invalid insert : %s, %T", query, params["Location"])
+	}
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	bound, err := bindValues(manager, []interface{}{p.Location, p.Area})
+	if err != nil || bound[0] != p.Location || bound[1] != p.Area {
+		t.Fatalf("custom type should not be converted : %v, %v", bound, err)
+	}
+}
+
+func TestWriteJSONKeys(t *testing.T) {
+	cached := &cachedResult{}
+	cached.columns = []string{"user_name", "age"}
+	cached.typeNames = []string{"VARCHAR", "INT"}
+	cached.values = [][]interface{}{{[]byte("jin"), []byte("42")}}
+
+	var buffer bytes.Buffer
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.WriteJSON(&buffer); err != nil {
+		t.Fatalf("fail to write json : %s", err.Error())
+	}
+	if buffer.String() != `[{"user_name":"jin","age":42}]` {
+		t.Fatalf("raw column keys expected : %s", buffer.String())
+	}
+
+	buffer.Reset()
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.WriteJSONWithOptions(&buffer, JSONOptions{CamelCaseKeys: true}); err != nil {
+		t.Fatalf("fail to write json : %s", err.Error())
+	}
+	if buffer.String() != `[{"userName":"jin","age":42}]` {
+		t.Fatalf("camel case keys expected : %s", buffer.String())
+	}
+
+	buffer.Reset()
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}}
+	mapper := func(column string) string { return strings.ToUpper(column) }
+	if err := result.WriteJSONWithOptions(&buffer, JSONOptions{CamelCaseKeys: true, KeyMapper: mapper}); err != nil {
+		t.Fatalf("fail to write json : %s", err.Error())
+	}
+	if buffer.String() != `[{"USER_NAME":"jin","AGE":42}]` {
+		t.Fatalf("mapped keys expected : %s", buffer.String())
+	}
+
+	for _, c := range []struct {
+		typeName string
+		v        string
+		expect   string
+	}{
+		{"DOUBLE", "1.5", `1.5`},
+		{"NUMERIC", "NaN", `"NaN"`},
+		{"FLOAT8", "Infinity", `"Infinity"`},
+		{"REAL", "-Inf", `"-Inf"`},
+		{"UNSIGNED DECIMAL", "10.10", `10.10`},
+		{"INTERVAL", "1", `"1"`},
+		{"POINT", "12", `"12"`},
+	} {
+		encoded, err := jsonValue(c.typeName, []byte(c.v))
+		if err != nil || string(encoded) != c.expect || !json.Valid(encoded) {
+			t.Fatalf("%s of %s : expect %s but %s, %v", c.typeName, c.v, c.expect, encoded, err)
+		}
+	}
+}
+
+func TestQueryRowResultClose(t *testing.T) {
+	row := newQueryRowResult(nil, nil)
+	for i := 0; i < 2; i++ {
+		if err := row.Close(); err != nil {
+			t.Fatalf("close should be idempotent : %s", err.Error())
+		}
+	}
+
+	var count int
+	if err := row.Scan(&count); err != ErrRowResultClosed {
+		t.Fatalf("scan after close should be ErrRowResultClosed : %v", err)
+	}
+
+	failed := newQueryRowResultError(ErrNoRows)
+	if err := failed.Close(); err != nil {
+		t.Fatalf("close of error result : %s", err.Error())
+	}
+	if err := failed.Scan(&count); err != ErrNoRows {
+		t.Fatalf("error of result should be kept : %v", err)
+	}
+}
+
+func TestColumnCombiner(t *testing.T) {
+	RegisterColumnCombiner("fullName", func(values []interface{}) (interface{}, error) {
+		if values[0] == nil && values[1] == nil {
+			return nil, nil
+		}
+		return fmt.Sprintf("%s %s", values[0], values[1]), nil
+	})
+	defer RegisterColumnCombiner("fullName", nil)
+
+	type person struct {
+		Id        int
+		FirstName string
+		FullName  string `db:"first_name,last_name" combine:"fullName"`
+	}
+
+	cached := &cachedResult{}
+	cached.columns = []string{"id", "first_name", "last_name"}
+	cached.typeNames = []string{"BIGINT", "VARCHAR", "VARCHAR"}
+	cached.values = [][]interface{}{{int64(1), []byte("gildong"), []byte("hong")}, {int64(2), nil, nil}}
+
+	persons := make([]person, 0)
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}, strictColumns: true}
+	if err := result.ScanAll(&persons); err != nil {
+		t.Fatalf("fail to scan combined field : %s", err.Error())
+	}
+	if len(persons) != 2 || persons[0].FullName != "gildong hong" || persons[0].FirstName != "gildong" || persons[1].FullName != "" {
+		t.Fatalf("invalid combined field : %v", persons)
+	}
+
+	cached.columns = []string{"id", "first_name", "nick"}
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanAll(&persons); err == nil || !strings.Contains(err.Error(), "FullName") {
+		t.Fatalf("missing column of combined field should be error : %v", err)
+	}
+
+	query, _, err := buildStructInsert("person", person{Id: 1, FirstName: "gildong", FullName: "gildong hong"}, toSnakeCase)
+	if err != nil || query != "INSERT INTO person (id, first_name) VALUES ({Id}, {FirstName})" {
+		t.Fatalf("combined field should not be inserted : %s, %v", query, err)
+	}
+}
+
+func TestBulkDebugSampleRate(t *testing.T) {
+	logger := &captureLogger{}
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.preference.Debug = true
+	manager.preference.DebugLogger = logger
+
+	for i := 0; i < 10; i++ {
+		debugRowParams(manager, "insertCity", i, []interface{}{i})
+	}
+	if len(logger.lines) != 10 {
+		t.Fatalf("every row should be printed by default : %d", len(logger.lines))
+	}
+
+	logger.lines = nil
+	manager.preference.BulkDebugSampleRate = 4
+	for i := 0; i < 10; i++ {
+		debugRowParams(manager, "insertCity", i, []interface{}{i})
+	}
+	if len(logger.lines) != 3 || logger.lines[0] != "[insertCity] params : [0] " || logger.lines[2] != "[insertCity] params : [8] " {
+		t.Fatalf("1 in 4 rows should be printed : %v", logger.lines)
+	}
+}
+
+func TestScanGrouped(t *testing.T) {
+	type item struct {
+		Id      int64
+		OrderId int64
+		Name    string
+	}
+	type order struct {
+		Id       int64
+		Customer string
+		Items    []item
+	}
+
+	cached := &cachedResult{}
+	cached.columns = []string{"id", "customer", "id", "order_id", "name"}
+	cached.typeNames = []string{"BIGINT", "VARCHAR", "BIGINT", "BIGINT", "VARCHAR"}
+	cached.values = [][]interface{}{
+		{int64(1), []byte("jin"), int64(10), int64(1), []byte("apple")},
+		{int64(1), []byte("jin"), int64(11), int64(1), []byte("pear")},
+		{int64(2), []byte("kim"), nil, nil, nil},
+		{int64(3), []byte("lee"), int64(12), int64(3), []byte("plum")},
+	}
+
+	orders := make([]order, 0)
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanGrouped(&orders, "Items", "id"); err != nil {
+		t.Fatalf("fail to scan grouped : %s", err.Error())
+	}
+	if len(orders) != 3 || orders[0].Customer != "jin" || len(orders[0].Items) != 2 || len(orders[1].Items) != 0 || len(orders[2].Items) != 1 {
+		t.Fatalf("invalid groups : %v", orders)
+	}
+	if orders[0].Id != 1 || orders[0].Items[1].Id != 11 || orders[0].Items[1].Name != "pear" || orders[2].Items[0].OrderId != 3 {
+		t.Fatalf("invalid group values : %v", orders)
+	}
+
+	pointers := make([]*order, 0)
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanGrouped(&pointers, "Items", "id"); err != nil || len(pointers) != 3 || len(pointers[0].Items) != 2 {
+		t.Fatalf("invalid pointer groups : %v", err)
+	}
+
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanGrouped(&orders, "Customer", "id"); err == nil {
+		t.Fatalf("child field should be slice")
+	}
+	if err := result.ScanGrouped(&orders, "Items", "order_no"); err == nil {
+		t.Fatalf("unknown key column should be error")
+	}
+}
+
+func TestIdentifierBind(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectAnyTable" identifiers="city, country, stat.city_2023">
+		SELECT * FROM {{Table}} WHERE name = {Name} AND memo != '{{Table}}'
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+
+	stmt, _ := manager.find("selectAnyTable")
+	refined, err := refineConditional(queryNormalizer, stmt, map[string]interface{}{"Table": "city", "Name": "seoul"})
+	if err != nil {
+		t.Fatalf("fail to bind identifier : %s", err.Error())
+	}
+	if refined.Query != "SELECT * FROM `city` WHERE name = ? AND memo != '{{Table}}'" || len(refined.columnMention) != 1 {
+		t.Fatalf("invalid identifier bind : %s", refined.Query)
+	}
+
+	refined, err = refineConditional(newNormalizer("postgresql"), stmt, map[string]interface{}{"Table": "stat.city_2023", "Name": "seoul"})
+	if err != nil || !strings.HasPrefix(refined.Query, `SELECT * FROM "stat"."city_2023" WHERE name = $1`) {
+		t.Fatalf("invalid qualified identifier : %s, %v", refined.Query, err)
+	}
+
+	_, err = refineConditional(queryNormalizer, stmt, map[string]interface{}{"Table": "city; DROP TABLE city", "Name": "seoul"})
+	if !errors.Is(err, ErrIdentifierNotAllowed) {
+		t.Fatalf("identifier out of allowlist should be ErrIdentifierNotAllowed : %v", err)
+	}
+	if _, err = refineConditional(queryNormalizer, stmt, "city", "seoul"); err == nil {
+		t.Fatalf("identifier bind needs named parameter")
+	}
+
+	err = loadWithSax(manager, []byte(`<query><select id="selectBadIdentifier" identifiers="city, city name">SELECT * FROM {{Table}}</select></query>`))
+	if err == nil || !strings.Contains(err.Error(), "invalid identifier") {
+		t.Fatalf("invalid identifier should fail but %v", err)
+	}
+}
+
+func TestBindElapsed(t *testing.T) {
+	if bindElapsed(context.Background(), time.Now()) != 0 {
+		t.Fatalf("no bind timer should be zero")
+	}
+
+	ctx := withBindTimer(context.Background())
+	time.Sleep(time.Millisecond * 5)
+	first := bindElapsed(ctx, time.Now())
+	if first < time.Millisecond*5 {
+		t.Fatalf("bind elapsed should be measured from the timer : %s", first)
+	}
+	if next := bindElapsed(ctx, time.Now()); next >= first {
+		t.Fatalf("next call should be measured from the previous call : %s", next)
+	}
+
+	c := newStatsCollector()
+	c.recordElapsed("insertCity", time.Millisecond*3, time.Millisecond)
+	c.recordElapsed("insertCity", time.Millisecond*5, time.Millisecond*2)
+	s := c.snapshot()["insertCity"]
+	if s.Executions != 2 || s.TotalElapsed != time.Millisecond*8 || s.TotalBindElapsed != time.Millisecond*3 {
+		t.Fatalf("invalid stats : %+v", s)
+	}
+}
+
+func TestMaxPlaceholders(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	if manager.MaxPlaceholders() != 65535 {
+		t.Fatalf("invalid default of mysql : %d", manager.MaxPlaceholders())
+	}
+	if newNormalizer("postgresql").maxPlaceholders() != 65535 {
+		t.Fatalf("invalid default of postgresql")
+	}
+	if newNormalizer("sqlite3").maxPlaceholders() != 32766 || newNormalizer("sqlserver").maxPlaceholders() != 2098 {
+		t.Fatalf("invalid default of sqlite3 or sqlserver")
+	}
+
+	queryNormalizer = nil
+	manager.preference.DriverName = "sqlserver"
+	if manager.MaxPlaceholders() != 2098 {
+		t.Fatalf("default of driver should apply without normalizer : %d", manager.MaxPlaceholders())
+	}
+	if _, err := bindValues(manager, make([]interface{}, 3)); err != nil {
+		t.Fatalf("bind without normalizer : %s", err.Error())
+	}
+	queryNormalizer = newNormalizer("mysql")
+
+	manager.preference.MaxPlaceholders = 10
+	if _, err := bindValues(manager, make([]interface{}, 11)); !errors.Is(err, ErrTooManyPlaceholders) {
+		t.Fatalf("parameters over limit should be ErrTooManyPlaceholders : %v", err)
+	}
+	if _, err := bindValues(manager, make([]interface{}, 10)); err != nil {
+		t.Fatalf("parameters within limit : %s", err.Error())
+	}
+
+	stmt := QueryStatement{eleType: eleTypeInsert, Id: "insertCity"}
+	stmt.Query = "INSERT INTO city (name, age, grade) VALUES ({Name}, {Age}, {Grade})"
+	if err := queryNormalizer.normalize(&stmt); err != nil {
+		t.Fatalf("fail to normalize : %s", err.Error())
+	}
+
+	b := newQuerymanBulk(manager, stmt)
+	if b.rowsPerStatement() != 3 || b.chunkSize() != 3 {
+		t.Fatalf("chunk should be under placeholder limit : %d, %d", b.rowsPerStatement(), b.chunkSize())
+	}
+	b.BatchSize(2)
+	if b.chunkSize() != 2 {
+		t.Fatalf("smaller batch size should be kept : %d", b.chunkSize())
+	}
+}
+
+func TestScanNumericText(t *testing.T) {
+	type stat struct {
+		Count   int
+		Total   uint32
+		Average float64
+		Rank    *int64
+	}
+
+	cached := &cachedResult{}
+	cached.columns = []string{"count", "total", "average", "rank"}
+	cached.typeNames = []string{"DECIMAL", "CHAR", "VARCHAR", "TEXT"}
+	cached.values = [][]interface{}{{[]byte("42.00"), []byte(" 7 "), "3.5", []byte("-3")}}
+
+	stats := make([]stat, 0)
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanAll(&stats); err != nil {
+		t.Fatalf("fail to scan numeric text : %s", err.Error())
+	}
+	if len(stats) != 1 || stats[0].Count != 42 || stats[0].Total != 7 || stats[0].Average != 3.5 || stats[0].Rank == nil || *stats[0].Rank != -3 {
+		t.Fatalf("invalid numeric fields : %+v", stats)
+	}
+
+	for _, value := range []interface{}{[]byte("42.5"), []byte("many")} {
+		cached.values = [][]interface{}{{value, []byte("7"), "3.5", nil}}
+		result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+		err := result.ScanAll(&stats)
+		if err == nil || !strings.Contains(err.Error(), "column count") {
+			t.Fatalf("parse failure should name the column : %v", err)
+		}
+	}
+}
+
+type unsupportedAffectedResult struct{}
+
+func (unsupportedAffectedResult) LastInsertId() (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (unsupportedAffectedResult) RowsAffected() (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func TestAffectedUnknown(t *testing.T) {
+	result := ExecMultiResult{}
+	result.merge(driver.RowsAffected(2))
+	if affected, _ := result.RowsAffected(); affected != 2 || result.AffectedUnknown() {
+		t.Fatalf("invalid affected rows : %d", affected)
+	}
+
+	result.merge(unsupportedAffectedResult{})
+	result.merge(driver.RowsAffected(3))
+	if affected, err := result.RowsAffected(); affected != -1 || err != nil || !result.AffectedUnknown() {
+		t.Fatalf("unsupported affected rows should be -1 : %d, %v", affected, err)
+	}
+
+	total := ExecMultiResult{}
+	total.merge(result)
+	if !total.AffectedUnknown() {
+		t.Fatalf("unknown affected rows should be kept by merge")
+	}
+}
+
+func TestStripColumnQualifier(t *testing.T) {
+	type order struct {
+		Id     int64
+		UserId int64
+		Amount int64
+	}
+
+	cached := &cachedResult{}
+	cached.columns = []string{"orders.id", "orders.user_id", "users.id", "o.amount"}
+	cached.typeNames = []string{"BIGINT", "BIGINT", "BIGINT", "BIGINT"}
+	cached.values = [][]interface{}{{int64(10), int64(1), int64(1), int64(500)}}
+
+	orders := make([]order, 0)
+	result := &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}, stripQualifier: true}
+	if err := result.ScanAll(&orders); err != nil {
+		t.Fatalf("fail to scan qualified columns : %s", err.Error())
+	}
+	if len(orders) != 1 || orders[0].Id != 10 || orders[0].UserId != 1 || orders[0].Amount != 500 {
+		t.Fatalf("invalid qualified column scan : %v", orders)
+	}
+
+	orders = orders[:0]
+	result = &QueryResult{rows: &cachedRows{result: cached, index: -1}, fieldNameConverter: CamelConvertStrategy{}}
+	if err := result.ScanAll(&orders); err != nil {
+		t.Fatalf("fail to scan without stripping : %s", err.Error())
+	}
+	if len(orders) != 1 || orders[0].Id != 0 || orders[0].Amount != 0 {
+		t.Fatalf("qualified column should not be mapped without stripping : %v", orders)
+	}
+
+	if !NewQuerymanPreference("", "").StripColumnQualifier {
+		t.Fatalf("StripColumnQualifier should be default")
+	}
+}
+
+func TestEachResolver(t *testing.T) {
+	type event struct {
+		Kind string
+		Id   int
+	}
+
+	executed := make([]string, 0)
+	exec := func(ctx context.Context, stmtId string, v ...interface{}) (sql.Result, error) {
+		if stmtId == "failStmt" {
+			return nil, errors.New("failed")
+		}
+		executed = append(executed, fmt.Sprintf("%s%v", stmtId, v))
+		return driver.RowsAffected(1), nil
+	}
+	resolver := func(item interface{}) (string, interface{}) {
+		e := item.(event)
+		switch e.Kind {
+		case "create":
+			return "insertOrder", []interface{}{e.Id}
+		case "cancel":
+			return "cancelOrder", nil
+		case "fail":
+			return "failStmt", nil
+		}
+		return "", nil
+	}
+
+	items := []interface{}{event{"create", 1}, event{"cancel", 2}}
+	results, err := executeEach(context.Background(), exec, resolver, items)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("fail to execute each : %v", err)
+	}
+	if strings.Join(executed, ",") != "insertOrder[[1]],cancelOrder[]" {
+		t.Fatalf("invalid executions : %v", executed)
+	}
+
+	items = []interface{}{event{"create", 3}, event{"fail", 4}, event{"create", 5}}
+	results, err = executeEach(context.Background(), exec, resolver, items)
+	if err == nil || !strings.Contains(err.Error(), "item 1 [failStmt]") || len(results) != 1 {
+		t.Fatalf("failure should stop with the item : %v, %d", err, len(results))
+	}
+
+	_, err = executeEach(context.Background(), exec, resolver, []interface{}{event{"unknown", 6}})
+	if err == nil {
+		t.Fatalf("empty statement id should be error")
+	}
+}
+
+func TestResultCacheKeyOfPointer(t *testing.T) {
+	queryNormalizer = newNormalizer("mysql")
+
+	manager := &QueryMan{}
+	manager.preference = NewQuerymanPreference("", "")
+	manager.statementMap = make(map[string]QueryStatement)
+
+	err := loadWithSax(manager, []byte(`<query>
+	<select id="selectCodeById" cacheTTL="60s">
+		SELECT * FROM code WHERE id = {Id}
+	</select>
+</query>`))
+	if err != nil {
+		t.Fatalf("fail to load : %s", err.Error())
+	}
+	stmt, _ := manager.find("selectCodeById")
+
+	keyOf := func(v ...interface{}) string {
+		query, params, single, err := resolveBinds(manager, stmt, v)
+		if err != nil || !single {
+			t.Fatalf("fail to resolve binds of %v : %v", v, err)
+		}
+		return resultCacheKey(stmt.Id, query, params)
+	}
+
+	id := 5
+	byPtr := keyOf(&id)
+	byStruct := keyOf(struct{ Id *int }{&id})
+	id = 6
+	if keyOf(&id) == byPtr || keyOf(struct{ Id *int }{&id}) == byStruct {
+		t.Fatalf("key should follow the value behind reused pointer")
+	}
+	id = 5
+	if keyOf(&id) != byPtr || keyOf(5) != byPtr {
+		t.Fatalf("key of the same value should be equal")
+	}
+
+	if _, _, single, err := resolveBinds(manager, stmt, []interface{}{[]interface{}{1, 2}}); single || err != nil {
+		t.Fatalf("list parameter queried per element should not be resolved : %v", err)
+	}
+	if _, _, _, err := resolveBinds(manager, stmt, []interface{}{map[string]interface{}{"Name": "seoul"}}); err == nil {
+		t.Fatalf("missing bind should be error")
+	}
+	var nilId *int
+	if _, _, _, err := resolveBinds(manager, stmt, []interface{}{nilId}); err != ErrNilPtr {
+		t.Fatalf("expect ErrNilPtr but %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MultiStatementExec preference values for user query having several statements separated by ';'
+const (
+	MultiStatementAsIs        = ""            // send to the driver as it is (default)
+	MultiStatementTransaction = "transaction" // execute each statement in one transaction
+	MultiStatementReject      = "reject"      // return ErrMultiStatement
+)
+
+// splitStatements splits query by ';' outside of literal and comment. blank statements are dropped
+func splitStatements(query string, backslashEscape bool) []string {
+	statements := make([]string, 0)
+	start := 0
+	queryLen := len(query)
+	for i := 0; i < queryLen; i++ {
+		if skip := skipLiteralOrComment(query, i, backslashEscape); skip > i {
+			i = skip - 1
+			continue
+		}
+		if query[i] != ';' {
+			continue
+		}
+		if s := strings.Trim(query[start:i], cutset); len(s) > 0 {
+			statements = append(statements, s)
+		}
+		start = i + 1
+	}
+	if s := strings.Trim(query[start:], cutset); len(s) > 0 {
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// findMultiStatement returns statements of user query when it has several statements and the preference handles it
+func findMultiStatement(pref *QuerymanPreference, stmtIdOrUserQuery string) ([]string, bool) {
+	if pref == nil {
+		return nil, false
+	}
+	if pref.MultiStatementExec != MultiStatementTransaction && pref.MultiStatementExec != MultiStatementReject {
+		return nil, false
+	}
+	if !isUserQuery(stmtIdOrUserQuery) {
+		return nil, false
+	}
+
+	statements := splitStatements(stmtIdOrUserQuery, backslashEscapes(dialectOf(pref.DriverName)))
+	return statements, len(statements) > 1
+}
+
+func (man *QueryMan) executeMultiStatement(ctx context.Context, statements []string, v []interface{}) (sql.Result, error) {
+	if man.preference.MultiStatementExec == MultiStatementReject {
+		return nil, ErrMultiStatement
+	}
+
+	tx, err := man.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.executeStatements(ctx, statements, v)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// executeStatements executes statements in order. positional parameters are divided by bind count of each statement
+// and named parameter (map, struct) is passed to all statements
+func (t *DBTransaction) executeStatements(ctx context.Context, statements []string, v []interface{}) (sql.Result, error) {
+	args, err := distributeStatementArgs(t.queryFinder, statements, v)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ExecMultiResult{}
+	for i, s := range statements {
+		res, err := t.ExecuteWithStmtContext(ctx, s, args[i]...)
+		if err != nil {
+			return nil, fmt.Errorf("fail to execute statement %d of multi statement : %w", i+1, err)
+		}
+		(&result).addRowsAffected(res)
+		if id, err := res.LastInsertId(); err == nil && id > 0 {
+			(&result).addInsertId(id)
+		}
+		result.last = res
+	}
+	return result, nil
+}
+
+func distributeStatementArgs(finder QueryStatementFinder, statements []string, v []interface{}) ([][]interface{}, error) {
+	args := make([][]interface{}, len(statements))
+	if len(v) == 0 || isNamedParameter(v) {
+		for i := range statements {
+			args[i] = v
+		}
+		return args, nil
+	}
+
+	cursor := 0
+	for i, s := range statements {
+		stmt, err := finder.find(s)
+		if err != nil {
+			return nil, err
+		}
+		count := len(stmt.columnMention)
+		if cursor+count > len(v) {
+			return nil, fmt.Errorf("binding parameter count mismatch. defined=%d more, args=%d left", count, len(v)-cursor)
+		}
+		args[i] = v[cursor : cursor+count]
+		cursor += count
+	}
+
+	if cursor != len(v) {
+		return nil, fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", cursor, len(v))
+	}
+	return args, nil
+}
+
+// isNamedParameter reports whether parameters are bound by name (map, struct, BoundParams)
+func isNamedParameter(v []interface{}) bool {
+	if _, ok := findNamedParams(v); ok {
+		return true
+	}
+	if len(v) != 1 || v[0] == nil {
+		return false
+	}
+
+	val := reflect.ValueOf(v[0])
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return false
+		}
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return !isValueStruct(val.Interface())
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"reflect"
+)
+
+// BoundParams is a map or struct parameter flattened once.
+// pass it to Execute/Query of several statements sharing the same inputs to skip re-flattening
+type BoundParams struct {
+	m map[string]interface{}
+}
+
+func NewBoundParams(v interface{}) (*BoundParams, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, ErrNilPtr
+		}
+		val = val.Elem()
+	}
+
+	params := &BoundParams{}
+	switch val.Kind() {
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return nil, ErrInvalidMapKeyType
+		}
+		params.m = flattenToMap(val.Interface())
+	case reflect.Struct:
+		params.m = flattenStructToMap(val.Interface())
+	default:
+		return nil, ErrBoundParamsType
+	}
+	return params, nil
+}
+
+// findBoundParams returns flattened map when the first parameter is *BoundParams
+func findBoundParams(v []interface{}) (map[string]interface{}, bool) {
+	if len(v) == 0 {
+		return nil, false
+	}
+	if params, ok := v[0].(*BoundParams); ok && params != nil {
+		return params.m, true
+	}
+	return nil, false
+}
+
+// findNamedParams returns parameter map of *BoundParams or of several maps merged left to right.
+// e.g) Execute(pathVars, queryString, body). a later map overrides the same key of earlier maps
+func findNamedParams(v []interface{}) (map[string]interface{}, bool) {
+	if m, ok := findBoundParams(v); ok {
+		return m, true
+	}
+	if len(v) < 2 {
+		return nil, false
+	}
+
+	for _, p := range v {
+		t := reflect.TypeOf(p)
+		if t == nil || t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+			return nil, false
+		}
+	}
+
+	merged := make(map[string]interface{})
+	for _, p := range v {
+		for k, value := range flattenToMap(p) {
+			merged[k] = value
+		}
+	}
+	return merged, true
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	structColumnTag      = "db"
+	structColumnOptionPk = "pk"
+	structCombineTag     = "combine"
+)
+
+// structColumn returns column of struct field declared by db tag (e.g. db:"id,pk") or mapped by columnOf.
+// db:"-" skips the field and so does field combined from several columns (combine tag)
+func structColumn(f reflect.StructField, columnOf func(field string) string) (column string, pk bool, skip bool) {
+	column = columnOf(f.Name)
+	tag := strings.TrimSpace(f.Tag.Get(structColumnTag))
+	if tag == "-" || len(f.Tag.Get(structCombineTag)) > 0 {
+		return "", false, true
+	}
+
+	options := strings.Split(tag, ",")
+	if name := strings.TrimSpace(options[0]); len(name) > 0 {
+		column = name
+	}
+	for _, option := range options[1:] {
+		if strings.TrimSpace(option) == structColumnOptionPk {
+			pk = true
+		}
+	}
+	return column, pk, false
+}
+
+// buildPartialUpdate builds UPDATE user query whose SET clause contains only present fields of struct v.
+// nil pointer fields are always skipped and zero value fields are skipped when omitZero is set.
+// keys are struct field names used for WHERE clause and columnOf maps field name to column name
+func buildPartialUpdate(table string, v interface{}, omitZero bool, columnOf func(field string) string, keys ...string) (string, map[string]interface{}, error) {
+	if len(keys) == 0 {
+		return "", nil, ErrPartialUpdateNoKey
+	}
+
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", nil, ErrNilPtr
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return "", nil, ErrPartialUpdateNeedStruct
+	}
+
+	isKey := make(map[string]bool)
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	params := make(map[string]interface{})
+	var set bytes.Buffer
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := val.Field(i)
+		if !fv.CanInterface() || isKey[f.Name] {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+		} else if omitZero && fv.IsZero() {
+			continue
+		}
+
+		if set.Len() > 0 {
+			set.WriteString(", ")
+		}
+		set.WriteString(fmt.Sprintf("%s = {%s}", columnOf(f.Name), f.Name))
+		params[f.Name] = fieldBindValue(fv)
+	}
+
+	if set.Len() == 0 {
+		return "", nil, ErrPartialUpdateNoColumn
+	}
+
+	var where bytes.Buffer
+	for _, k := range keys {
+		fv := val.FieldByName(k)
+		if !fv.IsValid() || !fv.CanInterface() {
+			return "", nil, fmt.Errorf("key field %s is not exist", k)
+		}
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			return "", nil, fmt.Errorf("key field %s is nil", k)
+		}
+
+		if where.Len() > 0 {
+			where.WriteString(" AND ")
+		}
+		where.WriteString(fmt.Sprintf("%s = {%s}", columnOf(k), k))
+		params[k] = fieldBindValue(fv)
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, set.String(), where.String())
+	return query, params, nil
+}
+
+// buildStructInsert builds INSERT user query of the exported fields of struct v in declaration order.
+// column name is db tag (db:"-" skips the field) or mapped by columnOf. nil pointer fields are skipped
+// so that column default applies, and zero value fields are inserted as they are
+func buildStructInsert(table string, v interface{}, columnOf func(field string) string) (string, map[string]interface{}, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", nil, ErrNilPtr
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return "", nil, ErrInsertStructNeedStruct
+	}
+
+	params := make(map[string]interface{})
+	var columns, values bytes.Buffer
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := val.Field(i)
+		if !fv.CanInterface() {
+			continue
+		}
+
+		column, _, skip := structColumn(f, columnOf)
+		if skip {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+
+		if columns.Len() > 0 {
+			columns.WriteString(", ")
+			values.WriteString(", ")
+		}
+		columns.WriteString(column)
+		values.WriteString(fmt.Sprintf("{%s}", f.Name))
+		params[f.Name] = fieldBindValue(fv)
+	}
+
+	if columns.Len() == 0 {
+		return "", nil, ErrInsertStructNoColumn
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, columns.String(), values.String())
+	return query, params, nil
+}
+
+// buildStructUpdate builds UPDATE user query of struct v keyed by the fields tagged with pk option. e.g) db:"id,pk".
+// nil pointer and zero value fields are skipped from SET clause unless all is set. with all, nil pointer sets NULL
+func buildStructUpdate(table string, v interface{}, all bool, columnOf func(field string) string) (string, map[string]interface{}, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", nil, ErrNilPtr
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return "", nil, ErrUpdateStructNeedStruct
+	}
+
+	params := make(map[string]interface{})
+	var set, where bytes.Buffer
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := val.Field(i)
+		if !fv.CanInterface() {
+			continue
+		}
+
+		column, pk, skip := structColumn(f, columnOf)
+		if skip {
+			continue
+		}
+
+		if pk {
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				return "", nil, fmt.Errorf("key field %s is nil", f.Name)
+			}
+			if where.Len() > 0 {
+				where.WriteString(" AND ")
+			}
+			where.WriteString(fmt.Sprintf("%s = {%s}", column, f.Name))
+			params[f.Name] = fieldBindValue(fv)
+			continue
+		}
+
+		var value interface{}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() && !all {
+				continue
+			}
+			value = fieldBindValue(fv)
+		} else {
+			if !all && fv.IsZero() {
+				continue
+			}
+			value = fv.Interface()
+		}
+
+		if set.Len() > 0 {
+			set.WriteString(", ")
+		}
+		set.WriteString(fmt.Sprintf("%s = {%s}", column, f.Name))
+		params[f.Name] = value
+	}
+
+	if where.Len() == 0 {
+		return "", nil, ErrUpdateStructNoKey
+	}
+	if set.Len() == 0 {
+		return "", nil, ErrUpdateStructNoColumn
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, set.String(), where.String())
+	return query, params, nil
+}
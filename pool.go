@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+)
+
+// pool names of statement target attribute. e.g) <select id="x" target="primary">
+const (
+	poolPrimary = "primary"
+	poolReplica = "replica"
+)
+
+func isKnownPool(name string) bool {
+	return name == poolPrimary || name == poolReplica
+}
+
+// proxyFor returns SqlProxy of the pool where stmt runs.
+// target attribute of statement wins. otherwise select goes to replica and others to primary
+func (man *QueryMan) proxyFor(stmt QueryStatement) SqlProxy {
+	if man.replica == nil {
+		return man
+	}
+
+	target := stmt.target
+	if len(target) == 0 {
+		target = poolPrimary
+		if stmt.eleType == eleTypeSelect {
+			target = poolReplica
+		}
+	}
+
+	if target == poolReplica {
+		return replicaProxy{man}
+	}
+	return man
+}
+
+// replicaProxy runs statements on replica pool of QueryMan
+type replicaProxy struct {
+	*QueryMan
+}
+
+func (r replicaProxy) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.replica.ExecContext(ctx, query, args...)
+}
+
+func (r replicaProxy) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.replica.QueryContext(ctx, query, args...)
+}
+
+func (r replicaProxy) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.replica.QueryRowContext(ctx, query, args...)
+}
+
+func (r replicaProxy) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.replica.PrepareContext(ctx, query)
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// procedureOutMode is how the dialect delivers OUT parameters of stored procedure
+type procedureOutMode uint8
+
+const (
+	procedureOutUnsupported procedureOutMode = iota
+	procedureOutSessionVar                   // mysql : CALL p(?, @out) and SELECT @out on the same connection
+	procedureOutResultRow                    // postgresql : CALL p($1, NULL) returns a row of INOUT parameters
+)
+
+// CallProcedure executes stored procedure call statement with in parameters and scans OUT parameters into out pointers.
+// mysql declares OUT parameters as session variables : CALL proc({Name}, @total).
+// postgresql declares INOUT parameters as NULL : CALL proc({Name}, NULL)
+func (man *QueryMan) CallProcedure(stmtIdOrUserQuery string, in []interface{}, out []interface{}) error {
+	return man.CallProcedureContext(context.Background(), stmtIdOrUserQuery, in, out)
+}
+
+func (man *QueryMan) CallProcedureContext(ctx context.Context, stmtIdOrUserQuery string, in []interface{}, out []interface{}) error {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return err
+	}
+
+	if stmt.eleType != eleTypeUpdate {
+		return ErrExecutionInvalidSqlType
+	}
+
+	switch man.getNormalizer().procedureOut() {
+	case procedureOutSessionVar:
+		return callWithSessionVar(ctx, man, stmt, in, out)
+	case procedureOutResultRow:
+		if len(out) == 0 {
+			_, err = execute(ctx, man, stmt, in...)
+			return err
+		}
+		queryRowResult := querySingleRow(ctx, man, stmt, in...)
+		queryRowResult.fieldNameConverter = man.fieldNameConverter
+		return queryRowResult.Scan(out...)
+	}
+
+	return ErrProcedureNotSupported
+}
+
+// callWithSessionVar runs CALL and SELECT of session variables on one connection
+// because session variables are visible only to the connection
+func callWithSessionVar(ctx context.Context, man *QueryMan, stmt QueryStatement, in []interface{}, out []interface{}) error {
+	vars := findSessionVars(stmt.Query)
+	if len(vars) != len(out) {
+		return fmt.Errorf("OUT parameter count mismatch. declared=%d, out=%d", len(vars), len(out))
+	}
+
+	conn, err := man.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = execute(ctx, connProxy{QueryMan: man, conn: conn}, stmt, in...)
+	if err != nil {
+		return err
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+	return conn.QueryRowContext(ctx, "SELECT "+strings.Join(vars, ", ")).Scan(out...)
+}
+
+// findSessionVars returns user variables (@name) of query in order. system variables (@@name) are excluded
+func findSessionVars(query string) []string {
+	vars := make([]string, 0)
+	queryLen := len(query)
+	for i := 0; i < queryLen; i++ {
+		// session variables are of mysql
+		if skip := skipLiteralOrComment(query, i, true); skip > i {
+			i = skip - 1
+			continue
+		}
+
+		if query[i] != '@' {
+			continue
+		}
+		if i+1 < queryLen && query[i+1] == '@' {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < queryLen && isSessionVarChar(query[j]) {
+			j++
+		}
+		if j > i+1 {
+			vars = append(vars, query[i:j])
+		}
+		i = j - 1
+	}
+	return vars
+}
+
+func isSessionVarChar(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '$' ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// connProxy runs statements on a single connection of the pool
+type connProxy struct {
+	*QueryMan
+	conn *sql.Conn
+}
+
+func (c connProxy) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(ctx, query, args...)
+}
+
+func (c connProxy) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(ctx, query, args...)
+}
+
+func (c connProxy) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRowContext(ctx, query, args...)
+}
+
+func (c connProxy) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.conn.PrepareContext(ctx, query)
+}
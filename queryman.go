@@ -21,10 +21,15 @@
 package queryman
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,54 +38,103 @@ var queryNormalizer QueryNormalizer
 type QueryNormalizer interface {
 	normalize(stmt *QueryStatement) error
 	resolveHolding(query string) string
+	procedureOut() procedureOutMode
+	upsertClause(conflictColumns []string, updateColumns []string) (string, error)
+	explainPrefix(analyze bool) (string, error)
+	estimatePlan() estimatePlanMode
+	quoteIdentifier(name string) string
+	maxPlaceholders() int
+	orderByNulls(expr string, desc bool, nulls string) (string, error)
+	dialectName() string
 }
 
 type QueryMan struct {
 	db                 *sql.DB
+	replica            *sql.DB // nil without ReplicaDataSourceUrl
 	preference         QuerymanPreference
 	statementMap       map[string]QueryStatement
+	statementMutex     *sync.RWMutex // guards statementMap which may be shared between shards and grow by Register
 	fieldNameConverter FieldNameConvertStrategy
 	execRecordChan     chan queryExecution
+	stats              *statsCollector
+	userQueryCache     *statementCache
+	resultCache        *resultCache // rows of statements declared with cacheTTL
+	running            *runningRegistry
+	normalizer         QueryNormalizer
 }
 
+// defaultStatementMutex guards statementMap of QueryMan not opened with its own lock
+var defaultStatementMutex sync.RWMutex
+
+// normalizerMutex guards lazy initialization of queryNormalizer
+var normalizerMutex sync.Mutex
+
+// statementLock returns lock of statementMap
+func (man *QueryMan) statementLock() *sync.RWMutex {
+	if man.statementMutex != nil {
+		return man.statementMutex
+	}
+	return &defaultStatementMutex
+}
+
+// GetSqlCount returns count of statement ids. variants of dialects share the id
 func (man *QueryMan) GetSqlCount() int {
-	return len(man.statementMap)
+	lock := man.statementLock()
+	lock.RLock()
+	defer lock.RUnlock()
+
+	ids := make(map[string]bool)
+	for k := range man.statementMap {
+		ids[strings.SplitN(k, dialectKeyDelim, 2)[0]] = true
+	}
+	return len(ids)
 }
 
 func (man *QueryMan) GetMaxConnCount() int {
 	return man.preference.MaxOpenConns
 }
 
+// registStatement adds statement keyed by id and dialect. statement without dialect is the default of the id
 func (man *QueryMan) registStatement(queryStatement QueryStatement) error {
+	if err := applySoftDelete(&queryStatement, man.preference.SoftDeleteFilter); err != nil {
+		return err
+	}
+
 	queryStatement, err := man.buildStatement(queryStatement)
 	if err != nil {
 		return err
 	}
 
-	id := strings.ToUpper(queryStatement.Id)
-	if _, exists := man.statementMap[id]; exists {
+	id := man.statementKey(queryStatement.Id)
+	key := dialectStatementKey(id, queryStatement.dialect)
+	lock := man.statementLock()
+	lock.Lock()
+	if _, exists := man.statementMap[key]; exists {
+		lock.Unlock()
 		return fmt.Errorf("duplicated user statement id : %s", id)
 	}
-
-	man.statementMap[id] = queryStatement
+	man.statementMap[key] = queryStatement
+	lock.Unlock()
 
 	if man.preference.Debug {
-		man.preference.DebugLogger.Printf("stmt [%s] loaded", id)
+		man.preference.debugLogger().Printf("stmt [%s] loaded", id)
 	}
 
 	return nil
 }
 
 func (man *QueryMan) buildStatement(queryStatement QueryStatement) (QueryStatement, error) {
-	if queryNormalizer == nil {
-		queryNormalizer = newNormalizer(man.preference.DriverName)
-		if queryNormalizer == nil {
-			return queryStatement, fmt.Errorf("not found normalizer for %s", man.preference.DriverName)
-		}
+	normalizer := man.defaultNormalizer()
+	if len(queryStatement.dialect) > 0 && normalizer != nil && normalizer.dialectName() != queryStatement.dialect {
+		// variant of other dialect is normalized in its dialect and placeholders are resolved at execution
+		normalizer = newNormalizer(dialectDriverName(queryStatement.dialect))
+	}
+	if normalizer == nil {
+		return queryStatement, fmt.Errorf("not found normalizer for %s", man.preference.DriverName)
 	}
 
 	if !queryStatement.HasCondition() {
-		err := queryNormalizer.normalize(&queryStatement)
+		err := normalizer.normalize(&queryStatement)
 		if err != nil {
 			return queryStatement, err
 		}
@@ -93,53 +147,211 @@ func (man *QueryMan) Close() error {
 	if man.execRecordChan != nil {
 		man.execRecordChan <- queryExecution{close: true}
 		close(man.execRecordChan)
+		man.execRecordChan = nil
 	}
 
+	if man.replica != nil {
+		man.replica.Close()
+	}
 	return man.db.Close()
 }
 
-func (man *QueryMan) exec(query string, args ...interface{}) (sql.Result, error) {
-	return man.db.Exec(query, args...)
+func (man *QueryMan) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return man.db.ExecContext(ctx, query, args...)
 }
 
-func (man *QueryMan) query(query string, args ...interface{}) (*sql.Rows, error) {
-	return man.db.Query(query, args...)
+func (man *QueryMan) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return man.db.QueryContext(ctx, query, args...)
 }
 
-func (man *QueryMan) queryRow(query string, args ...interface{}) *sql.Row {
-	return man.db.QueryRow(query, args...)
+func (man *QueryMan) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return man.db.QueryRowContext(ctx, query, args...)
 }
 
-func (man *QueryMan) prepare(query string) (*sql.Stmt, error) {
-	return man.db.Prepare(query)
+func (man *QueryMan) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return man.db.PrepareContext(ctx, query)
+}
+
+// defaultNormalizer returns queryNormalizer. it is created with DriverName of the first QueryMan building statement
+func (man *QueryMan) defaultNormalizer() QueryNormalizer {
+	normalizerMutex.Lock()
+	defer normalizerMutex.Unlock()
+
+	if queryNormalizer == nil {
+		queryNormalizer = newNormalizer(man.preference.DriverName)
+	}
+	return queryNormalizer
 }
 
 func (man *QueryMan) isTransaction() bool {
 	return false
 }
 
+func (man *QueryMan) getPreference() *QuerymanPreference {
+	return &man.preference
+}
+
+func (man *QueryMan) getNormalizer() QueryNormalizer {
+	if man.normalizer != nil {
+		return man.normalizer
+	}
+	return queryNormalizer
+}
+
+// WithNormalizer returns a view which resolves placeholders and IN expansion with the dialect of driverName.
+// the view shares db connections and statements with man, so do not Close the view
+func (man *QueryMan) WithNormalizer(driverName string) *QueryMan {
+	view := *man
+	view.normalizer = newNormalizer(driverName)
+	return &view
+}
+
+// WithPrepare returns a view which executes single Execute/Query through prepared statement or not.
+// the view shares db connections and statements with man, so do not Close the view
+func (man *QueryMan) WithPrepare(alwaysPrepare bool) *QueryMan {
+	view := *man
+	view.preference.AlwaysPrepare = alwaysPrepare
+	return &view
+}
+
+// debugEnabled reports free-form text debug output. with DebugFormat json, each execution is logged as DebugEvent instead
 func (man *QueryMan) debugEnabled() bool {
-	return man.preference.Debug
+	return man.preference.Debug && man.preference.DebugFormat != DebugFormatJson
 }
 
 func (man *QueryMan) debugPrint(format string, params ...interface{}) {
-	if man.preference.Debug {
-		man.preference.DebugLogger.Printf(format, params...)
+	if man.debugEnabled() {
+		if man.preference.DebugCallerLocation {
+			format = "(" + findCallerLocation() + ") " + format
+		}
+		man.preference.debugLogger().Printf(format, params...)
 	}
 }
 
-func (man *QueryMan) recordExcution(stmtId string, start time.Time) {
+// recordExcution records elapsed time of driver call from start and bind resolution time before it
+func (man *QueryMan) recordExcution(stmtId string, bind time.Duration, start time.Time) {
+	if man.stats != nil {
+		man.stats.recordElapsed(stmtId, time.Since(start), bind)
+	}
+
 	if man.execRecordChan != nil {
-		man.execRecordChan <- newQueryExecution(stmtId, start)
+		man.execRecordChan <- newQueryExecution(stmtId, bind, start)
 	}
 
 }
 
+func (man *QueryMan) recordAffected(stmtId string, affected int64) {
+	if man.stats != nil {
+		man.stats.recordAffected(stmtId, affected)
+	}
+}
+
+// MaxPlaceholders returns placeholder count of a statement accepted by the database.
+// MaxPlaceholders preference or the default of the dialect
+func (man *QueryMan) MaxPlaceholders() int {
+	return maxPlaceholders(man)
+}
+
+// Stats returns cumulative statistics by statement id. only collected with CollectStats preference
+func (man *QueryMan) Stats() map[string]StatementStats {
+	if man.stats == nil {
+		return make(map[string]StatementStats)
+	}
+	return man.stats.snapshot()
+}
+
+// PoolStats returns connection pool statistics of db. distinct from execution Stats()
+func (man *QueryMan) PoolStats() sql.DBStats {
+	return man.db.Stats()
+}
+
+// ReplicaPoolStats returns connection pool statistics of replica. zero value without ReplicaDataSourceUrl
+func (man *QueryMan) ReplicaPoolStats() sql.DBStats {
+	if man.replica == nil {
+		return sql.DBStats{}
+	}
+	return man.replica.Stats()
+}
+
+// CacheStats returns counters of user query statement cache. only available with UserQueryCacheSize preference
+func (man *QueryMan) CacheStats() CacheStats {
+	if man.userQueryCache == nil {
+		return CacheStats{}
+	}
+	return man.userQueryCache.stats()
+}
+
+func (man *QueryMan) ResetStats() {
+	if man.stats != nil {
+		man.stats.reset()
+	}
+}
+
+// Register adds statement to the loaded statements after construction.
+// element type is resolved from the query when it is not declared by xml
+func (man *QueryMan) Register(stmt QueryStatement) error {
+	if len(stmt.Id) == 0 {
+		return fmt.Errorf("empty statement id")
+	}
+	stmt.Query = strings.Trim(stmt.Query, cutset)
+	if len(stmt.Query) == 0 {
+		return fmt.Errorf("empty query of statement %s", stmt.Id)
+	}
+	if !stmt.eleType.IsSql() {
+		stmt.eleType = getDeclareSqlType(stmt.Query, backslashEscapes(man.dialect()))
+	}
+	return man.registStatement(stmt)
+}
+
+// RegisterXML loads statements of xml from reader after construction
+func (man *QueryMan) RegisterXML(reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("fail to read xml : %s", err.Error())
+	}
+	return loadWithSax(man, data)
+}
+
+// statementKey returns key of statementMap for id. ids are folded to upper case unless PreserveIdCase
+func (man *QueryMan) statementKey(id string) string {
+	if man.preference.PreserveIdCase {
+		return id
+	}
+	return strings.ToUpper(id)
+}
+
+const dialectKeyDelim = "\x00"
+
+// dialectStatementKey returns key of statementMap for statement id and dialect
+func dialectStatementKey(id string, dialect string) string {
+	if len(dialect) == 0 {
+		return id
+	}
+	return id + dialectKeyDelim + dialect
+}
+
+// dialect returns dialect picking statement variants. dialect of WithNormalizer view or DriverName
+func (man *QueryMan) dialect() string {
+	if man.normalizer != nil {
+		return man.normalizer.dialectName()
+	}
+	return dialectOf(man.preference.DriverName)
+}
+
+// find returns statement of id. the variant of the dialect is picked ahead of the default
 func (man *QueryMan) find(id string) (QueryStatement, error) {
-	stmt, ok := man.statementMap[strings.ToUpper(id)]
+	key := man.statementKey(id)
+	dialect := man.dialect()
+	lock := man.statementLock()
+	lock.RLock()
+	stmt, ok := man.statementMap[dialectStatementKey(key, dialect)]
+	if !ok && len(dialect) > 0 {
+		stmt, ok = man.statementMap[key]
+	}
+	lock.RUnlock()
 	if !ok {
 		if isUserQuery(id) {
-			return buildUserQueryStatement(man, id)
+			return man.findUserQuery(id)
 		}
 		return stmt, fmt.Errorf("not found query statement for id : %s", id)
 	}
@@ -147,6 +359,85 @@ func (man *QueryMan) find(id string) (QueryStatement, error) {
 	return stmt, nil
 }
 
+func (man *QueryMan) findUserQuery(query string) (QueryStatement, error) {
+	if man.userQueryCache == nil {
+		return buildUserQueryStatement(man, query)
+	}
+
+	if stmt, ok := man.userQueryCache.get(query); ok {
+		return stmt, nil
+	}
+
+	stmt, err := buildUserQueryStatement(man, query)
+	if err != nil {
+		return stmt, err
+	}
+	man.userQueryCache.put(query, stmt)
+	return stmt, nil
+}
+
+// StatementType returns sql type (SELECT, INSERT, UPDATE) of statement id or user query
+func (man *QueryMan) StatementType(stmtIdOrUserQuery string) (string, error) {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return "", err
+	}
+	return stmt.eleType.String(), nil
+}
+
+// RefinePreview returns query of statement id or user query after if clause resolution with params.
+// parameter values are not bound. useful to test which conditional fragments are included
+func (man *QueryMan) RefinePreview(stmtIdOrUserQuery string, params map[string]interface{}) (string, error) {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return "", err
+	}
+
+	if !stmt.HasCondition() {
+		return man.getNormalizer().resolveHolding(stmt.HoldedQuery), nil
+	}
+
+	refined, err := stmt.refine(man.getNormalizer(), params)
+	if err != nil {
+		return "", err
+	}
+	return refined.Query, nil
+}
+
+// CheckParams returns bind names of statement id or user query missing from params without db access.
+// if clauses are resolved with params first, so binds of rejected clauses are not required
+func (man *QueryMan) CheckParams(stmtIdOrUserQuery string, params map[string]interface{}) ([]string, error) {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.HasCondition() {
+		stmt, err = stmt.refine(man.getNormalizer(), params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	missing := make([]string, 0)
+	checked := make(map[string]bool)
+	for _, v := range stmt.columnMention {
+		if checked[v.Name()] {
+			continue
+		}
+		checked[v.Name()] = true
+
+		_, ok, err := findBindValue(&man.preference, params, v.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			missing = append(missing, v.Name())
+		}
+	}
+	return missing, nil
+}
+
 func isUserQuery(query string) bool {
 	if strings.Index(query, " ") > 0 {
 		return true
@@ -165,24 +456,92 @@ func isUserQuery(query string) bool {
 
 func buildUserQueryStatement(manager *QueryMan, query string) (QueryStatement, error) {
 	stmt := QueryStatement{}
-	stmt.eleType = getDeclareSqlType(query)
+	stmt.eleType = getDeclareSqlType(query, backslashEscapes(manager.dialect()))
 	stmt.Id = query
 	stmt.Query = query
+	stmt.userQuery = true
 
 	return manager.buildStatement(stmt)
 }
 
-func getDeclareSqlType(query string) declareElementType {
-	prefix := strings.Trim(query, " \r\n\t")[:10]
-	prefix = strings.ToUpper(prefix)
-	if strings.HasPrefix(prefix, "SELECT") {
+// getDeclareSqlType classifies user query by its first keyword after comments and opening parentheses.
+// WITH (CTE) is classified by the statement following its definitions and EXPLAIN, SHOW, DESCRIBE are select
+func getDeclareSqlType(query string, backslashEscape bool) declareElementType {
+	keyword, next := nextSqlKeyword(query, 0, backslashEscape)
+	if keyword == "WITH" {
+		keyword = cteStatementKeyword(query, next, backslashEscape)
+	}
+
+	switch keyword {
+	case "SELECT", "EXPLAIN", "SHOW", "DESCRIBE", "DESC":
 		return eleTypeSelect
-	} else if strings.HasPrefix(prefix, "INSERT") {
+	case "INSERT":
 		return eleTypeInsert
 	}
 	return eleTypeUpdate
 }
 
+// nextSqlKeyword returns upper cased word at i skipping spaces, comments and opening parentheses
+// with the index following the word
+func nextSqlKeyword(query string, i int, backslashEscape bool) (string, int) {
+	for i < len(query) {
+		if end := skipLiteralOrComment(query, i, backslashEscape); end > i {
+			i = end
+			continue
+		}
+		ch := query[i]
+		if ch != '(' && ch != ' ' && ch != '\t' && ch != '\r' && ch != '\n' {
+			break
+		}
+		i++
+	}
+
+	start := i
+	for i < len(query) && isKeywordChar(query[i]) {
+		i++
+	}
+	return strings.ToUpper(query[start:i]), i
+}
+
+// cteStatementKeyword returns keyword of the statement following common table expressions of WITH clause
+func cteStatementKeyword(query string, i int, backslashEscape bool) string {
+	depth := 0
+	for i < len(query) {
+		if end := skipLiteralOrComment(query, i, backslashEscape); end > i {
+			i = end
+			continue
+		}
+
+		ch := query[i]
+		if !isKeywordChar(ch) {
+			if ch == '(' {
+				depth++
+			} else if ch == ')' {
+				depth--
+			}
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(query) && isKeywordChar(query[i]) {
+			i++
+		}
+		if depth > 0 {
+			continue
+		}
+		switch word := strings.ToUpper(query[start:i]); word {
+		case "SELECT", "INSERT", "UPDATE", "DELETE":
+			return word
+		}
+	}
+	return ""
+}
+
+func isKeywordChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
 func (man *QueryMan) CreateBulk() (Bulk, error) {
 	pc, _, _, _ := runtime.Caller(1)
 	funcName := findFunctionName(pc)
@@ -199,7 +558,7 @@ func (man *QueryMan) CreateBulkWithStmt(stmtIdOrUserQuery string) (Bulk, error)
 		return nil, ErrExecutionInvalidSqlType
 	}
 
-	bulk := newQuerymanBulk(man, stmt)
+	bulk := newQuerymanBulk(man.proxyFor(stmt), stmt)
 	return bulk, nil
 }
 
@@ -210,6 +569,14 @@ func (man *QueryMan) Execute(v ...interface{}) (sql.Result, error) {
 }
 
 func (man *QueryMan) ExecuteWithStmt(stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	return man.ExecuteWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) ExecuteWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	if statements, ok := findMultiStatement(&man.preference, stmtIdOrUserQuery); ok {
+		return man.executeMultiStatement(ctx, statements, v)
+	}
+
 	stmt, err := man.find(stmtIdOrUserQuery)
 	if err != nil {
 		return nil, err
@@ -219,7 +586,53 @@ func (man *QueryMan) ExecuteWithStmt(stmtIdOrUserQuery string, v ...interface{})
 		return nil, ErrExecutionInvalidSqlType
 	}
 
-	return execute(man, stmt, v...)
+	return execute(ctx, man.proxyFor(stmt), stmt, v...)
+}
+
+// ExecuteAffected executes statement and returns affected row count
+func (man *QueryMan) ExecuteAffected(stmtIdOrUserQuery string, v ...interface{}) (int64, error) {
+	result, err := man.ExecuteWithStmt(stmtIdOrUserQuery, v...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ExecuteVerbose executes statement and returns the query and parameters actually passed to the driver with result
+func (man *QueryMan) ExecuteVerbose(stmtIdOrUserQuery string, v ...interface{}) (sql.Result, string, []interface{}, error) {
+	ctx, capture := withExecCapture(context.Background())
+	result, err := man.ExecuteWithStmtContext(ctx, stmtIdOrUserQuery, v...)
+	return result, capture.query, capture.params, err
+}
+
+// UpdatePartial updates table with only the present (non nil pointer) fields of v.
+// keys are struct field names for WHERE clause. column name is mapped by FieldNameConverter
+func (man *QueryMan) UpdatePartial(table string, v interface{}, keys ...string) (sql.Result, error) {
+	query, params, err := buildPartialUpdate(table, v, man.preference.PartialOmitZero, man.fieldNameConverter.convertColumnName, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return man.ExecuteWithStmt(query, params)
+}
+
+// InsertStruct inserts the exported fields of struct v into table without declared statement.
+// column name is db tag or mapped by FieldNameConverter. nil pointer fields are skipped
+func (man *QueryMan) InsertStruct(table string, v interface{}) (sql.Result, error) {
+	query, params, err := buildStructInsert(table, v, man.fieldNameConverter.convertColumnName)
+	if err != nil {
+		return nil, err
+	}
+	return man.ExecuteWithStmt(query, params)
+}
+
+// UpdateStruct updates table with struct v keyed by the fields tagged with pk option (e.g. db:"id,pk")
+// and returns affected row count. nil pointer and zero value fields are not set unless UpdateStructAll
+func (man *QueryMan) UpdateStruct(table string, v interface{}) (int64, error) {
+	query, params, err := buildStructUpdate(table, v, man.preference.UpdateStructAll, man.fieldNameConverter.convertColumnName)
+	if err != nil {
+		return 0, err
+	}
+	return man.ExecuteAffected(query, params)
 }
 
 func (man *QueryMan) Query(v ...interface{}) *QueryResult {
@@ -229,6 +642,10 @@ func (man *QueryMan) Query(v ...interface{}) *QueryResult {
 }
 
 func (man *QueryMan) QueryWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	return man.QueryWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) QueryWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
 	stmt, err := man.find(stmtIdOrUserQuery)
 	if err != nil {
 		return newQueryResultError(err)
@@ -238,7 +655,12 @@ func (man *QueryMan) QueryWithStmt(stmtIdOrUserQuery string, v ...interface{}) *
 		return newQueryResultError(ErrQueryInvalidSqlType)
 	}
 
-	queryedRow := queryMultiRow(man, stmt, v...)
+	var queryedRow *QueryResult
+	if stmt.cacheTTL > 0 && man.resultCache != nil {
+		queryedRow = man.queryCached(ctx, stmt, v...)
+	} else {
+		queryedRow = queryMultiRow(ctx, man.proxyFor(stmt), stmt, v...)
+	}
 	queryedRow.fieldNameConverter = man.fieldNameConverter
 	return queryedRow
 }
@@ -250,6 +672,10 @@ func (man *QueryMan) QueryRow(v ...interface{}) *QueryRowResult {
 }
 
 func (man *QueryMan) QueryRowWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
+	return man.QueryRowWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) QueryRowWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
 	stmt, err := man.find(stmtIdOrUserQuery)
 	if err != nil {
 		return newQueryRowResultError(err)
@@ -259,19 +685,60 @@ func (man *QueryMan) QueryRowWithStmt(stmtIdOrUserQuery string, v ...interface{}
 		return newQueryRowResultError(ErrQueryInvalidSqlType)
 	}
 
-	var queryRowResult *QueryRowResult
-	queryResult := queryMultiRow(man, stmt, v...)
-	if queryResult.err != nil {
-		queryResult.Close()
-		queryRowResult = newQueryRowResultError(queryResult.err)
-	} else {
-		queryRowResult = newQueryRowResult(queryResult.pstmt, queryResult.rows)
+	queryRowResult := querySingleRow(ctx, man.proxyFor(stmt), stmt, v...)
+	queryRowResult.fieldNameConverter = man.fieldNameConverter
+	return queryRowResult
+}
+
+// QueryFirst scans the first row of select statement into dest and ignores the rest.
+// no row is not an error but found=false with dest untouched
+func (man *QueryMan) QueryFirst(stmtIdOrUserQuery string, dest interface{}, v ...interface{}) (bool, error) {
+	return man.QueryFirstContext(context.Background(), stmtIdOrUserQuery, dest, v...)
+}
+
+func (man *QueryMan) QueryFirstContext(ctx context.Context, stmtIdOrUserQuery string, dest interface{}, v ...interface{}) (bool, error) {
+	return man.QueryWithStmtContext(ctx, stmtIdOrUserQuery, v...).scanFirst(dest)
+}
+
+// InsertReturning executes insert statement having RETURNING clause and scans the returned row into dest.
+// no returned row is ErrNoRows
+func (man *QueryMan) InsertReturning(stmtIdOrUserQuery string, dest interface{}, v ...interface{}) error {
+	return man.InsertReturningContext(context.Background(), stmtIdOrUserQuery, dest, v...)
+}
+
+func (man *QueryMan) InsertReturningContext(ctx context.Context, stmtIdOrUserQuery string, dest interface{}, v ...interface{}) error {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return err
+	}
+
+	if stmt.eleType != eleTypeInsert {
+		return ErrInsertReturningInvalidSqlType
 	}
 
-	queryResult.pstmt = nil
-	queryResult.rows = nil
+	queryRowResult := querySingleRow(ctx, man.proxyFor(stmt), stmt, v...)
 	queryRowResult.fieldNameConverter = man.fieldNameConverter
-	return queryRowResult
+	return queryRowResult.Scan(dest)
+}
+
+// ExecuteQuery executes insert, update or delete statement having RETURNING clause and returns the returned rows
+func (man *QueryMan) ExecuteQuery(stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	return man.ExecuteQueryContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) ExecuteQueryContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	stmt, err := man.find(stmtIdOrUserQuery)
+	if err != nil {
+		return newQueryResultError(err)
+	}
+
+	if stmt.eleType != eleTypeInsert && stmt.eleType != eleTypeUpdate {
+		return newQueryResultError(ErrExecutionInvalidSqlType)
+	}
+
+	queryedRow := queryMultiRow(ctx, man.proxyFor(stmt), stmt, v...)
+	queryedRow.fieldNameConverter = man.fieldNameConverter
+	return queryedRow
 }
 
 func (man *QueryMan) Begin() (*DBTransaction, error) {
@@ -281,7 +748,39 @@ func (man *QueryMan) Begin() (*DBTransaction, error) {
 	}
 
 	runtime.SetFinalizer(tx, closeTransaction)
-	return newTransaction(man, tx, man, man.fieldNameConverter), nil
+	return man.WrapTx(tx), nil
+}
+
+// WrapTx returns DBTransaction running statements on tx begun outside of queryman (e.g. other data access library).
+// commit and rollback stay with the owner of tx
+func (man *QueryMan) WrapTx(tx *sql.Tx) *DBTransaction {
+	dbTransaction := newTransaction(man, tx, man, man.fieldNameConverter, &man.preference)
+	dbTransaction.normalizer = man.normalizer
+	return dbTransaction
+}
+
+// ExecuteWithTx executes statement on tx begun outside of queryman
+func (man *QueryMan) ExecuteWithTx(tx *sql.Tx, stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	return man.ExecuteWithTxContext(context.Background(), tx, stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) ExecuteWithTxContext(ctx context.Context, tx *sql.Tx, stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	if tx == nil {
+		return nil, ErrNilTransaction
+	}
+	return man.WrapTx(tx).ExecuteWithStmtContext(ctx, stmtIdOrUserQuery, v...)
+}
+
+// QueryWithTx queries statement on tx begun outside of queryman
+func (man *QueryMan) QueryWithTx(tx *sql.Tx, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	return man.QueryWithTxContext(context.Background(), tx, stmtIdOrUserQuery, v...)
+}
+
+func (man *QueryMan) QueryWithTxContext(ctx context.Context, tx *sql.Tx, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	if tx == nil {
+		return newQueryResultError(ErrNilTransaction)
+	}
+	return man.WrapTx(tx).QueryWithStmtContext(ctx, stmtIdOrUserQuery, v...)
 }
 
 // you have to commit before closing transaction
@@ -289,6 +788,28 @@ func closeTransaction(tx *sql.Tx) {
 	tx.Rollback()
 }
 
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// findCallerLocation returns file:line of the first caller outside of queryman package
+func findCallerLocation() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return "unknown"
+}
+
 func findFunctionName(pc uintptr) string {
 	var funcName = runtime.FuncForPC(pc).Name()
 	var found = strings.LastIndexByte(funcName, '.')
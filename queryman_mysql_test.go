@@ -22,15 +22,21 @@ package queryman
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -49,6 +55,7 @@ const (
 	sqlSelectCityWithName     = "SelectCityWithName"
 	sqlSelectCityWithInClause = "SelectCityWithInClause"
 	sqlCountCity              = "CountCity"
+	sqlCountCityCreatedBefore = "CountCityCreatedBefore"
 	sqlSelectCityWithIf       = "SelectCityWithIf"
 )
 
@@ -169,6 +176,12 @@ create table city (
     </select>
     <select id="CountCity">
         SELECT Count(*) FROM CITY
+    </select>
+    <select id="SelectSleepWithTimeout" timeout="100ms">
+        SELECT SLEEP(1)
+    </select>
+    <select id="CountCityCreatedBefore">
+        SELECT Count(*) FROM CITY WHERE {CreateTime} > create_time
     </select>
 	<select id="SelectCityWithIf">
         SELECT id, name, age
@@ -1312,6 +1325,41 @@ func TestBatchInsert(t *testing.T) {
 	}
 }
 
+func TestBatchUpsert(t *testing.T) {
+	setup()
+
+	bulk, err := queryManager.CreateBulkWithStmt("insertAlbum")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	bulk.AddBatch(&AlbumData{Id: 2100, Score: 10})
+	bulk.AddBatch(&AlbumData{Id: 2200, Score: 20})
+	if _, err = bulk.Execute(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	bulk, _ = queryManager.CreateBulkWithStmt("insertAlbum")
+	bulk.Upsert([]string{"id"}, "score")
+	bulk.AddBatch(&AlbumData{Id: 2100, Score: 11})
+	bulk.AddBatch(&AlbumData{Id: 2300, Score: 30})
+	if _, err = bulk.Execute(); err != nil {
+		t.Fatalf("fail to upsert : %s", err.Error())
+	}
+
+	if count := selectAlbumCount(); count != 3 {
+		t.Fatalf("with %d, but %d", 3, count)
+	}
+
+	score := 0
+	err = queryManager.QueryRowWithStmt("SELECT score FROM album WHERE id = {Id}", 2100).Scan(&score)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if score != 11 {
+		t.Fatalf("with %d, but %d", 11, score)
+	}
+}
+
 func TestBatchInsertWithMap(t *testing.T) {
 	setup()
 
@@ -1401,3 +1449,1822 @@ func selectAlbumCount() int {
 	}
 	return count
 }
+
+func TestQueryWriteCSV(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "csv_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "csv_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	defer result.Close()
+
+	var buffer bytes.Buffer
+	err = result.WriteCSV(&buffer, CSVOptions{NullValue: "NULL"})
+	if err != nil {
+		t.Fatalf("fail to write csv : %s", err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect header and 1 row but %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[1], "1,csv_city,42,") || !strings.HasSuffix(lines[1], ",NULL") {
+		t.Fatalf("invalid csv row : %s", lines[1])
+	}
+}
+
+func TestTimeParameter(t *testing.T) {
+	setup()
+
+	past := time.Now().Add(-time.Hour)
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "time_city", 42, true, 40.0, past, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// with bare param
+	before := time.Now().Add(time.Minute)
+	count := 0
+	err = queryManager.QueryRowWithStmt(sqlCountCityCreatedBefore, before).Scan(&count)
+	if err != nil {
+		t.Fatalf("fail to query with bare time : %s", err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+
+	// with ptr param
+	err = queryManager.QueryRowWithStmt(sqlCountCityCreatedBefore, &before).Scan(&count)
+	if err != nil {
+		t.Fatalf("fail to query with time ptr : %s", err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+
+	// in slice
+	err = queryManager.QueryRowWithStmt(sqlCountCityCreatedBefore, []time.Time{before}).Scan(&count)
+	if err != nil {
+		t.Fatalf("fail to query with time slice : %s", err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+
+	// as map value
+	m := make(map[string]interface{})
+	m["CreateTime"] = past.Add(-time.Minute)
+	err = queryManager.QueryRowWithStmt(sqlCountCityCreatedBefore, m).Scan(&count)
+	if err != nil {
+		t.Fatalf("fail to query with time in map : %s", err.Error())
+	}
+	if count != 0 {
+		t.Fatalf("with %d, but %d", 0, count)
+	}
+
+	// execute with time list
+	_, err = queryManager.ExecuteWithStmt(sqlInsertCity, []interface{}{"time_city2", 43, true, 41.0, time.Now(), time.Now()})
+	if err != nil {
+		t.Fatalf("fail to execute with time in list : %s", err.Error())
+	}
+
+	// scan into time
+	var createTime time.Time
+	err = queryManager.QueryRowWithStmt("SELECT create_time FROM city WHERE name='time_city'").Scan(&createTime)
+	if err != nil {
+		t.Fatalf("fail to scan time : %s", err.Error())
+	}
+}
+
+func TestBatchInsertCommitEvery(t *testing.T) {
+	setup()
+
+	bulk, err := queryManager.CreateBulkWithStmt("insertAlbum")
+	if err != nil {
+		t.Fatalf("fail to create bulk : %s", err.Error())
+	}
+	for i := 0; i < 11; i++ {
+		bulk.AddBatch(AlbumData{Id: i + 100, Score: i})
+	}
+
+	result, err := bulk.CommitEvery(3).Execute()
+	if err != nil {
+		t.Fatalf("fail to execute bulk : %s", err.Error())
+	}
+	affected, _ := result.RowsAffected()
+	if affected != 11 {
+		t.Fatalf("with %d, but %d", 11, affected)
+	}
+
+	setup()
+
+	// 5th row is duplicated. first window should be committed
+	bulk, _ = queryManager.CreateBulkWithStmt("insertAlbum")
+	for _, id := range []int{1, 2, 3, 4, 1, 6} {
+		bulk.AddBatch(AlbumData{Id: id, Score: id})
+	}
+	_, err = bulk.CommitEvery(3).Execute()
+	if err == nil {
+		t.Fatalf("duplicated key should fail")
+	}
+	var commitErr *BulkCommitError
+	if !errors.As(err, &commitErr) {
+		t.Fatalf("expect BulkCommitError but %v", err)
+	}
+	if commitErr.Committed != 3 {
+		t.Fatalf("with %d, but %d", 3, commitErr.Committed)
+	}
+
+	count := selectAlbumCount()
+	if count != 3 {
+		t.Fatalf("with %d, but %d", 3, count)
+	}
+}
+
+func TestQueryWriteJSON(t *testing.T) {
+	setup()
+
+	for _, name := range []string{"json_city", "json_city"} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, name, 42, true, 40.5, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "json_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	defer result.Close()
+
+	var buffer bytes.Buffer
+	err := result.WriteJSON(&buffer)
+	if err != nil {
+		t.Fatalf("fail to write json : %s", err.Error())
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	err = json.Unmarshal(buffer.Bytes(), &rows)
+	if err != nil {
+		t.Fatalf("invalid json : %s [%s]", err.Error(), buffer.String())
+	}
+	if len(rows) != 2 {
+		t.Fatalf("with %d, but %d", 2, len(rows))
+	}
+	if rows[0]["name"] != "json_city" || rows[0]["age"] != float64(42) || rows[0]["update_time"] != nil {
+		t.Fatalf("invalid json row : %v", rows[0])
+	}
+}
+
+func TestQueryScanValues(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "values_city", 42, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "values_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	defer result.Close()
+
+	columns, err := result.Columns()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	count := 0
+	for result.Next() {
+		values, err := result.ScanValues()
+		if err != nil {
+			t.Fatalf("fail to scan values : %s", err.Error())
+		}
+		if len(values) != len(columns) {
+			t.Fatalf("expect %d values but %d", len(columns), len(values))
+		}
+		row := make(map[string]interface{})
+		for i, c := range columns {
+			row[c] = values[i]
+		}
+		if row["name"] != "values_city" || row["age"] != int64(42) || row["update_time"] != nil {
+			t.Fatalf("invalid row : %v", row)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+}
+
+func TestQueryScanAll(t *testing.T) {
+	setup()
+
+	for _, name := range []string{"scan_all_city", "scan_all_city", "scan_all_city"} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, name, 42, true, 40.5, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "scan_all_city")
+	cities := make([]City, 0)
+	err := result.ScanAll(&cities)
+	result.Close()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(cities) != 3 || cities[2].Name != "scan_all_city" {
+		t.Fatalf("invalid cities : %v", cities)
+	}
+
+	queryManager.preference.MaxScanRows = 2
+	defer func() {
+		queryManager.preference.MaxScanRows = 0
+	}()
+
+	result = queryManager.QueryWithStmt(sqlSelectCityWithName, "scan_all_city")
+	pointers := make([]*City, 0)
+	err = result.ScanAll(&pointers)
+	result.Close()
+	if !errors.Is(err, ErrMaxScanRowsExceeded) {
+		t.Fatalf("expect ErrMaxScanRowsExceeded but %v", err)
+	}
+
+	result = queryManager.QueryWithStmt(sqlSelectCityWithName, "scan_all_city")
+	_, err = result.ScanMapSlice()
+	result.Close()
+	if !errors.Is(err, ErrMaxScanRowsExceeded) {
+		t.Fatalf("expect ErrMaxScanRowsExceeded but %v", err)
+	}
+}
+
+func TestInterfaceListPtrParameter(t *testing.T) {
+	setup()
+
+	for _, name := range []string{"list_ptr_city", "list_ptr_city"} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, name, 42, true, 40.5, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	query := "SELECT * FROM city WHERE name = {Name} AND age IN ({Ages})"
+	args := []interface{}{"list_ptr_city", []int{41, 42}}
+	for _, v := range []interface{}{args, &args} {
+		result := queryManager.QueryWithStmt(query, v)
+		count := 0
+		for result.Next() {
+			count++
+		}
+		result.Close()
+		if result.GetError() != nil || count != 2 {
+			t.Fatalf("%T : expect 2 rows but %d, %v", v, count, result.GetError())
+		}
+	}
+
+	update := "UPDATE city SET age = {Age} WHERE name = {Name} AND age IN ({Ages})"
+	updateArgs := []interface{}{43, "list_ptr_city", []int{42}}
+	for i, v := range []interface{}{updateArgs, &updateArgs} {
+		affected, err := queryManager.ExecuteAffected(update, v)
+		if err != nil {
+			t.Fatalf("%T : %s", v, err.Error())
+		}
+		if expect := int64(2 - i*2); affected != expect {
+			t.Fatalf("%T : expect %d but %d", v, expect, affected)
+		}
+	}
+}
+
+func TestNullTypeParameter(t *testing.T) {
+	setup()
+
+	now := time.Now()
+
+	// as map value
+	m := make(map[string]interface{})
+	m["Name"] = sql.NullString{String: "null_city", Valid: true}
+	m["Age"] = sql.NullInt64{}
+	m["IsMan"] = sql.NullBool{Bool: true, Valid: true}
+	m["Percentage"] = sql.NullFloat64{Float64: 12.5, Valid: true}
+	m["CreateTime"] = sql.NullTime{Time: now, Valid: true}
+	m["UpdateTime"] = sql.NullTime{}
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, m)
+	if err != nil {
+		t.Fatalf("fail to execute with null types in map : %s", err.Error())
+	}
+
+	// as slice element
+	args := []interface{}{
+		sql.NullString{String: "null_city", Valid: true},
+		sql.NullInt32{Int32: 42, Valid: true},
+		sql.NullBool{},
+		sql.NullFloat64{},
+		sql.NullTime{Time: now, Valid: true},
+		sql.NullTime{},
+	}
+	_, err = queryManager.ExecuteWithStmt(sqlInsertCity, args)
+	if err != nil {
+		t.Fatalf("fail to execute with null types in slice : %s", err.Error())
+	}
+
+	// as bare params
+	_, err = queryManager.ExecuteWithStmt(sqlInsertCity, args...)
+	if err != nil {
+		t.Fatalf("fail to execute with null types as params : %s", err.Error())
+	}
+
+	// in bulk
+	bulk, err := queryManager.CreateBulkWithStmt(sqlInsertCity)
+	if err != nil {
+		t.Fatalf("fail to create bulk : %s", err.Error())
+	}
+	bulk.AddBatch(m)
+	bulk.AddBatch(args...)
+	_, err = bulk.Execute()
+	if err != nil {
+		t.Fatalf("fail to execute bulk with null types : %s", err.Error())
+	}
+
+	count := 0
+	err = queryManager.QueryRowWithStmt(sqlCountCity).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 5 {
+		t.Fatalf("with %d, but %d", 5, count)
+	}
+
+	// as query param
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, sql.NullString{String: "null_city", Valid: true})
+	if result.GetError() != nil {
+		t.Fatalf("fail to query with null type : %s", result.GetError())
+	}
+	result.Close()
+}
+
+func TestExecuteAffected(t *testing.T) {
+	setup()
+
+	for i := 0; i < 3; i++ {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "affected_city", 42, true, 40.0, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	affected, err := queryManager.ExecuteAffected(sqlUpdateCityWithName, 50, "affected_city")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected != 3 {
+		t.Fatalf("with %d, but %d", 3, affected)
+	}
+
+	// multi result
+	params := [][]interface{}{{51, "affected_city"}, {52, "affected_city"}}
+	affected, err = queryManager.ExecuteAffected(sqlUpdateCityWithName, params)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected != 6 {
+		t.Fatalf("with %d, but %d", 6, affected)
+	}
+}
+
+func TestExecuteNestedStructList(t *testing.T) {
+	setup()
+
+	type cityInfo struct {
+		Name string
+		Age  int
+	}
+	type cityRow struct {
+		Info cityInfo
+	}
+
+	rows := []cityRow{{Info: cityInfo{Name: "nested_city", Age: 10}}, {Info: cityInfo{Name: "nested_city", Age: 11}}}
+	affected, err := queryManager.ExecuteAffected("INSERT INTO city (name, age) VALUES ({info.name}, {Info.Age})", rows)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected != 2 {
+		t.Fatalf("with %d, but %d", 2, affected)
+	}
+}
+
+type CityPatch struct {
+	Id         int
+	Name       *string
+	Age        *int
+	Percentage *float32
+}
+
+func TestUpdatePartial(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "partial_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	age := 50
+	affected, err := queryManager.UpdatePartial("city", CityPatch{Id: 1, Age: &age}, "Id")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	n, _ := affected.RowsAffected()
+	if n != 1 {
+		t.Fatalf("with %d, but %d", 1, n)
+	}
+
+	city := City{}
+	err = queryManager.QueryRowWithStmt(sqlSelectCityWithName, "partial_city").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.Age != 50 || city.Percentage != 40.0 {
+		t.Fatalf("unexpected city : %v", city)
+	}
+
+	_, err = queryManager.UpdatePartial("city", CityPatch{Id: 1}, "Id")
+	if err != ErrPartialUpdateNoColumn {
+		t.Fatalf("expect ErrPartialUpdateNoColumn but %v", err)
+	}
+}
+
+type CityCreate struct {
+	Name       string
+	Age        int
+	IsMan      bool      `db:"is_man"`
+	CreateTime time.Time `db:"create_time"`
+	UpdateTime *time.Time
+	Memo       string `db:"-"`
+}
+
+func TestInsertStruct(t *testing.T) {
+	setup()
+
+	result, err := queryManager.InsertStruct("city", CityCreate{Name: "struct_city", Age: 42, CreateTime: time.Now(), Memo: "skip"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	n, _ := result.RowsAffected()
+	if n != 1 {
+		t.Fatalf("with %d, but %d", 1, n)
+	}
+
+	city := City{}
+	err = queryManager.QueryRowWithStmt(sqlSelectCityWithName, "struct_city").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.Age != 42 || city.IsMan {
+		t.Fatalf("unexpected city : %v", city)
+	}
+}
+
+type CityUpdate struct {
+	Id         int `db:"id,pk"`
+	Age        int
+	Percentage *float32
+}
+
+func TestUpdateStruct(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "update_struct_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	affected, err := queryManager.UpdateStruct("city", CityUpdate{Id: 1, Age: 51})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected != 1 {
+		t.Fatalf("with %d, but %d", 1, affected)
+	}
+
+	city := City{}
+	err = queryManager.QueryRowWithStmt(sqlSelectCityWithName, "update_struct_city").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.Age != 51 || city.Percentage != 40.0 {
+		t.Fatalf("unexpected city : %v", city)
+	}
+}
+
+func TestQueryRewriter(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "rewrite_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	rewritten := ""
+	queryManager.preference.QueryRewriter = func(ctx context.Context, stmtId string, query string) (string, error) {
+		if stmtId != sqlSelectCityWithName {
+			return query, nil
+		}
+		if strings.Contains(query, "{") {
+			return "", fmt.Errorf("unresolved query : %s", query)
+		}
+		rewritten = query + " AND age > 100"
+		return rewritten, nil
+	}
+	defer func() {
+		queryManager.preference.QueryRewriter = nil
+	}()
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "rewrite_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	defer result.Close()
+
+	if !strings.HasSuffix(rewritten, "AND age > 100") {
+		t.Fatalf("rewriter is not invoked")
+	}
+	if result.Next() {
+		t.Fatalf("rewritten query should return no row")
+	}
+
+	errAbort := fmt.Errorf("abort")
+	queryManager.preference.QueryRewriter = func(ctx context.Context, stmtId string, query string) (string, error) {
+		return "", errAbort
+	}
+	_, err = queryManager.ExecuteWithStmt(sqlUpdateCityWithName, 50, "rewrite_city")
+	if err != errAbort {
+		t.Fatalf("expect abort error but %v", err)
+	}
+}
+
+type CityGrade int
+
+const (
+	CityGradeUnknown CityGrade = iota
+	CityGradeMetro
+)
+
+type CityWithGrade struct {
+	Id   int
+	Name CityGrade
+}
+
+func TestScanConverter(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "METRO", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	RegisterScanConverter(reflect.TypeOf(CityGradeUnknown), func(src interface{}) (interface{}, error) {
+		var s string
+		switch v := src.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return nil, fmt.Errorf("unexpected source type %T", src)
+		}
+		if s == "METRO" {
+			return CityGradeMetro, nil
+		}
+		return CityGradeUnknown, nil
+	})
+	defer RegisterScanConverter(reflect.TypeOf(CityGradeUnknown), nil)
+
+	city := CityWithGrade{}
+	err = queryManager.QueryRowWithStmt("SELECT id, name FROM city WHERE name = {Name}", "METRO").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.Name != CityGradeMetro {
+		t.Fatalf("with %d, but %d", CityGradeMetro, city.Name)
+	}
+}
+
+type captureLogger struct {
+	lines []string
+}
+
+func (c *captureLogger) Printf(format string, a ...interface{}) {
+	c.lines = append(c.lines, fmt.Sprintf(format, a...))
+}
+
+func TestDebugCallerLocation(t *testing.T) {
+	setup()
+
+	logger := &captureLogger{}
+	queryManager.preference.Debug = true
+	queryManager.preference.DebugCallerLocation = true
+	queryManager.preference.DebugLogger = logger
+	defer func() {
+		queryManager.preference.Debug = false
+		queryManager.preference.DebugCallerLocation = false
+		queryManager.preference.DebugLogger = defaultLogger{}
+	}()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "caller_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(logger.lines) == 0 {
+		t.Fatalf("no debug output")
+	}
+	if !strings.HasPrefix(logger.lines[0], "(queryman_mysql_test.go:") {
+		t.Fatalf("caller location is not printed : %s", logger.lines[0])
+	}
+}
+
+func TestDebugFormatJson(t *testing.T) {
+	setup()
+
+	logger := &captureLogger{}
+	queryManager.preference.Debug = true
+	queryManager.preference.DebugFormat = DebugFormatJson
+	queryManager.preference.DebugLogger = logger
+	queryManager.preference.ParamMasker = func(stmtId string, index int, v interface{}) interface{} {
+		if index == 0 {
+			return "***"
+		}
+		return v
+	}
+	defer func() {
+		queryManager.preference.Debug = false
+		queryManager.preference.DebugFormat = DebugFormatText
+		queryManager.preference.DebugLogger = defaultLogger{}
+		queryManager.preference.ParamMasker = nil
+	}()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "json_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("expect single json event : %v", logger.lines)
+	}
+
+	event := DebugEvent{}
+	if err = json.Unmarshal([]byte(logger.lines[0]), &event); err != nil {
+		t.Fatalf("invalid json event : %s", err.Error())
+	}
+	if event.StmtId != sqlInsertCity {
+		t.Fatalf("unexpected stmtId : %s", event.StmtId)
+	}
+	if event.RowsAffected == nil || *event.RowsAffected != 1 {
+		t.Fatalf("unexpected rowsAffected : %v", event.RowsAffected)
+	}
+	if len(event.Params) == 0 || event.Params[0] != "***" {
+		t.Fatalf("param is not masked : %v", event.Params)
+	}
+}
+
+func TestScanOrDefault(t *testing.T) {
+	setup()
+
+	count := -1
+	found, err := queryManager.QueryRowWithStmt(sqlSelectCityWithName, "not_exist_city").ScanOrDefault(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if found || count != -1 {
+		t.Fatalf("expect not found with untouched dest but found=%v, count=%d", found, count)
+	}
+
+	found, err = queryManager.QueryRowWithStmt(sqlCountCity).ScanOrDefault(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !found {
+		t.Fatalf("expect found")
+	}
+
+	_, err = queryManager.QueryRowWithStmt("notExistStmt").ScanOrDefault(&count)
+	if err == nil {
+		t.Fatalf("expect error of unknown statement")
+	}
+}
+
+func TestCancelRunning(t *testing.T) {
+	setup()
+
+	queryManager.running = newRunningRegistry()
+	defer func() {
+		queryManager.running = nil
+	}()
+
+	errCh := make(chan error)
+	go func() {
+		sleep := 0
+		errCh <- queryManager.QueryRowWithStmt("SELECT SLEEP({Seconds})", 10).Scan(&sleep)
+	}()
+
+	var running []RunningQuery
+	for i := 0; i < 100 && len(running) == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+		running = queryManager.RunningQueries()
+	}
+	if len(running) != 1 {
+		t.Fatalf("expect 1 running query but %v", running)
+	}
+	if !queryManager.CancelRunning(running[0].Id) {
+		t.Fatalf("fail to cancel running query")
+	}
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("canceled query should fail")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("query is not canceled")
+	}
+
+	if len(queryManager.RunningQueries()) != 0 {
+		t.Fatalf("canceled query should be deregistered")
+	}
+}
+
+func TestScanPositional(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, createCity())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	type ageSummary struct {
+		Doubled int
+		Count   int
+	}
+
+	summary := ageSummary{}
+	err = queryManager.QueryRowWithStmt("SELECT MAX(age) * 2, COUNT(*) FROM city").ScanPositional(&summary)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if summary.Count != 1 || summary.Doubled != 284 {
+		t.Fatalf("invalid positional scan : %+v", summary)
+	}
+
+	result := queryManager.QueryWithStmt("SELECT age + 1, name FROM city")
+	defer result.Close()
+	type agePlus struct {
+		Age  int
+		Name string
+	}
+	for result.Next() {
+		row := agePlus{}
+		if err = result.ScanPositional(&row); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if row.Age != 143 {
+			t.Fatalf("with %d, but %d", 143, row.Age)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	setup()
+
+	plan, err := queryManager.Explain(sqlSelectCityWithName, "seoul")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	lines := strings.Split(plan, "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "table") || !strings.Contains(plan, "city") {
+		t.Fatalf("invalid plan : %s", plan)
+	}
+}
+
+func TestEstimateCount(t *testing.T) {
+	setup()
+
+	estimate, err := queryManager.EstimateCount(sqlSelectCityWithName, "seoul")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if estimate < 0 {
+		t.Fatalf("invalid estimate : %d", estimate)
+	}
+
+	_, err = queryManager.EstimateCount(sqlUpdateCityWithName, "seoul", 1)
+	if err != ErrQueryInvalidSqlType {
+		t.Fatalf("estimate of update should be rejected but %v", err)
+	}
+}
+
+func TestMultiStatementTransaction(t *testing.T) {
+	setup()
+
+	queryManager.preference.MultiStatementExec = MultiStatementTransaction
+	defer func() {
+		queryManager.preference.MultiStatementExec = MultiStatementAsIs
+	}()
+
+	_, err := queryManager.ExecuteWithStmt("INSERT INTO city (name, age) VALUES ({Name}, {Age}); INSERT INTO no_such_table (name) VALUES ({Name})", "multi", 42, "multi")
+	if err == nil {
+		t.Fatalf("second statement should fail")
+	}
+
+	count := 0
+	if err = queryManager.QueryRowWithStmt(sqlCountCity).Scan(&count); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 0 {
+		t.Fatalf("first statement should be rolled back but count=%d", count)
+	}
+
+	result, err := queryManager.ExecuteWithStmt("INSERT INTO city (name, age) VALUES ({Name}, {Age}); UPDATE city SET age = {Age}", "multi", 42, 43)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected, _ := result.RowsAffected(); affected != 2 {
+		t.Fatalf("with %d, but %d", 2, affected)
+	}
+}
+
+func TestTransactionQueryRow(t *testing.T) {
+	setup()
+
+	tx, err := queryManager.Begin()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecuteWithStmt(sqlInsertCity, createCity())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	count := 0
+	err = tx.QueryRowWithStmt(sqlCountCity).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+
+	// tx should be still usable after QueryRow
+	_, err = tx.ExecuteWithStmt(sqlInsertCity, createCity())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = tx.QueryRowWithStmt(sqlCountCity).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 2 {
+		t.Fatalf("with %d, but %d", 2, count)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestBatchInsertSeq(t *testing.T) {
+	setup()
+
+	bulk, err := queryManager.CreateBulkWithStmt("insertAlbum")
+	if err != nil {
+		t.Fatalf("fail to create bulk : %s", err.Error())
+	}
+
+	pulled := 0
+	seq := func(yield func(interface{}) bool) {
+		for i := 0; i < 25; i++ {
+			pulled++
+			if !yield(AlbumData{Id: i + 100, Score: i}) {
+				return
+			}
+		}
+	}
+
+	result, err := bulk.AddSeq(seq).BatchSize(10).Execute()
+	if err != nil {
+		t.Fatalf("fail to execute bulk : %s", err.Error())
+	}
+	affected, _ := result.RowsAffected()
+	if affected != 25 || pulled != 25 {
+		t.Fatalf("with %d, but affected=%d, pulled=%d", 25, affected, pulled)
+	}
+
+	if multiResult, ok := result.(ExecMultiResult); ok {
+		if len(multiResult.GetInsertIdList()) != 3 {
+			t.Fatalf("expect 3 chunks but %d", len(multiResult.GetInsertIdList()))
+		}
+	}
+
+	count := selectAlbumCount()
+	if count != 25 {
+		t.Fatalf("with %d, but %d", 25, count)
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	setup()
+
+	tests := map[string]string{
+		sqlInsertCity:                   "INSERT",
+		sqlUpdateCityWithName:           "UPDATE",
+		sqlSelectCityWithName:           "SELECT",
+		"SELECT * FROM city":            "SELECT",
+		"DELETE FROM city WHERE id = 1": "UPDATE",
+	}
+	for id, expect := range tests {
+		sqlType, err := queryManager.StatementType(id)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if sqlType != expect {
+			t.Fatalf("[%s] with %s, but %s", id, expect, sqlType)
+		}
+	}
+
+	if _, err := queryManager.StatementType("UnknownSomethingStatement"); err == nil {
+		t.Fatalf("unknown statement should fail")
+	}
+}
+
+func TestMapKeyCaseInsensitive(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "case_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	m := map[string]interface{}{"age": 50, "NAME": "case_city"}
+	_, err = queryManager.ExecuteWithStmt(sqlUpdateCityWithName, m)
+	if err == nil {
+		t.Fatalf("exact key lookup should fail")
+	}
+
+	queryManager.preference.MapKeyCaseInsensitive = true
+	defer func() {
+		queryManager.preference.MapKeyCaseInsensitive = false
+	}()
+
+	affected, err := queryManager.ExecuteAffected(sqlUpdateCityWithName, m)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected != 1 {
+		t.Fatalf("with %d, but %d", 1, affected)
+	}
+
+	m = map[string]interface{}{"age": 50, "NAME": "case_city", "name": "case_city"}
+	_, err = queryManager.ExecuteWithStmt(sqlUpdateCityWithName, m)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expect ambiguous error but %v", err)
+	}
+}
+
+func TestExecuteVerbose(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "verbose_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	result, query, params, err := queryManager.ExecuteVerbose(sqlUpdateCityWithName, 50, "verbose_city")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	affected, _ := result.RowsAffected()
+	if affected != 1 {
+		t.Fatalf("with %d, but %d", 1, affected)
+	}
+	if query != "UPDATE CITY SET AGE=? WHERE NAME=?" {
+		t.Fatalf("unexpected query : %s", query)
+	}
+	if len(params) != 2 || params[0] != 50 || params[1] != "verbose_city" {
+		t.Fatalf("unexpected params : %v", params)
+	}
+}
+
+func TestAlwaysPrepare(t *testing.T) {
+	setup()
+
+	prepared := queryManager.WithPrepare(true)
+	_, err := prepared.ExecuteWithStmt(sqlInsertCity, "prepare_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	result := prepared.QueryWithStmt(sqlSelectCityWithName, "prepare_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	if result.pstmt == nil {
+		t.Fatalf("query should be prepared")
+	}
+
+	city := City{}
+	if !result.Next() {
+		t.Fatalf("no row")
+	}
+	err = result.Scan(&city)
+	result.Close()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.Name != "prepare_city" {
+		t.Fatalf("with %s, but %s", "prepare_city", city.Name)
+	}
+
+	count := 0
+	err = prepared.QueryRowWithStmt(sqlSelectCityWithName, "prepare_city").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = queryManager.QueryRowWithStmt(sqlCountCity).Scan(&count)
+	if err != nil || count != 1 {
+		t.Fatalf("with %d, but %d (%v)", 1, count, err)
+	}
+}
+
+func TestQueryScanReusePlan(t *testing.T) {
+	setup()
+
+	for i := 0; i < 5; i++ {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "plan_city", i, true, 40.0, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	result := queryManager.QueryWithStmt(sqlSelectCityWithName, "plan_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	defer result.Close()
+
+	var scanner *StructureScanner
+	ages := make([]int, 0)
+	for result.Next() {
+		city := City{}
+		err := result.Scan(&city)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if scanner != nil && scanner != result.structScanner {
+			t.Fatalf("scan plan should be reused across rows")
+		}
+		scanner = result.structScanner
+		ages = append(ages, city.Age)
+	}
+
+	if len(ages) != 5 || ages[4] != 4 {
+		t.Fatalf("unexpected scanned rows : %v", ages)
+	}
+}
+
+func TestShardedQueryman(t *testing.T) {
+	setup()
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, "")
+	pref.Fileset = xmlFilePrefix + "*.xml"
+
+	resolved := make([]string, 0)
+	urls := map[string]string{"a": sourceName, "b": sourceName}
+	sharded, err := NewShardedQueryman(pref, urls, func(ctx context.Context, stmtId string, params []interface{}) (string, error) {
+		shardKey, _ := ctx.Value("shard").(string)
+		resolved = append(resolved, shardKey)
+		return shardKey, nil
+	})
+	if err != nil {
+		t.Fatalf("fail to create sharded queryman : %s", err.Error())
+	}
+	defer sharded.Close()
+
+	ctx := context.WithValue(context.Background(), "shard", "b")
+	_, err = sharded.ExecuteWithStmtContext(ctx, sqlInsertCity, "shard_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	count := 0
+	ctx = context.WithValue(context.Background(), "shard", "a")
+	err = sharded.QueryRowWithStmtContext(ctx, sqlCountCity).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 1 || len(resolved) != 2 || resolved[0] != "b" || resolved[1] != "a" {
+		t.Fatalf("unexpected routing : count=%d, resolved=%v", count, resolved)
+	}
+
+	ctx = context.WithValue(context.Background(), "shard", "c")
+	err = sharded.QueryRowWithStmtContext(ctx, sqlCountCity).Scan(&count)
+	if err == nil {
+		t.Fatalf("unknown shard should fail")
+	}
+
+	tx, err := sharded.Begin("a")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer tx.Rollback()
+	err = tx.QueryRowWithStmt(sqlCountCity).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	setup()
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.CollectStats = true
+
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+	defer man.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = man.ExecuteWithStmt(sqlInsertCity, "stats_city", 42, true, 40.0, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	_, err = man.ExecuteWithStmt(sqlUpdateCityWithName, 50, "stats_city")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	stats := man.Stats()
+	if stats[sqlInsertCity].Executions != 3 || stats[sqlInsertCity].RowsAffected != 3 {
+		t.Fatalf("unexpected insert stats : %+v", stats[sqlInsertCity])
+	}
+	if stats[sqlUpdateCityWithName].RowsAffected != 3 {
+		t.Fatalf("unexpected update stats : %+v", stats[sqlUpdateCityWithName])
+	}
+	if stats[sqlInsertCity].TotalBindElapsed <= 0 {
+		t.Fatalf("bind resolution time should be recorded : %+v", stats[sqlInsertCity])
+	}
+
+	man.ResetStats()
+	if len(man.Stats()) != 0 {
+		t.Fatalf("stats should be reset")
+	}
+
+	if addAffected(math.MaxInt64-1, 10) != math.MaxInt64 {
+		t.Fatalf("affected rows should saturate")
+	}
+}
+
+type CityOptional struct {
+	Name       *string
+	Age        int
+	IsMan      bool
+	Percentage float32
+	CreateTime time.Time
+	UpdateTime *time.Time
+}
+
+func TestNilPointerFieldParameter(t *testing.T) {
+	setup()
+
+	name := "optional_city"
+	now := time.Now()
+	cities := []CityOptional{
+		{Name: &name, Age: 1, CreateTime: now, UpdateTime: &now},
+		{Name: nil, Age: 2, CreateTime: now, UpdateTime: nil},
+	}
+
+	for _, city := range cities {
+		m := flattenStructToMap(city)
+		if city.Name == nil && m["Name"] != nil {
+			t.Fatalf("nil pointer field should be plain nil : %#v", m["Name"])
+		}
+		if city.Name != nil && m["Name"] != name {
+			t.Fatalf("non nil pointer field should be dereferenced : %#v", m["Name"])
+		}
+
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, city)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	count := 0
+	err := queryManager.QueryRowWithStmt("SELECT COUNT(*) FROM city WHERE name IS NULL AND update_time IS NULL").Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+
+	err = queryManager.QueryRowWithStmt("SELECT COUNT(*) FROM city WHERE name = {Name}", name).Scan(&count)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 1 {
+		t.Fatalf("with %d, but %d", 1, count)
+	}
+}
+
+type CityAcronym struct {
+	ID         int
+	Name       string
+	CreateTime time.Time
+}
+
+type upperConverter struct{}
+
+func (upperConverter) ColumnToField(column string) string {
+	return "F" + strings.ToUpper(column)
+}
+
+func (upperConverter) FieldToColumn(field string) string {
+	return strings.ToLower(strings.TrimPrefix(field, "F"))
+}
+
+func TestFieldNameConverter(t *testing.T) {
+	setup()
+
+	converter := SnakeCaseConverter{}
+	for column, field := range map[string]string{"user_name": "UserName", "user_id": "UserId", "create_time": "CreateTime"} {
+		if converter.ColumnToField(column) != field {
+			t.Fatalf("with %s, but %s", field, converter.ColumnToField(column))
+		}
+	}
+	for field, column := range map[string]string{"UserName": "user_name", "UserID": "user_id", "ID": "id", "HTTPCode": "http_code"} {
+		if converter.FieldToColumn(field) != column {
+			t.Fatalf("with %s, but %s", column, converter.FieldToColumn(field))
+		}
+	}
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "acronym_city", 42, true, 40.0, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	city := CityAcronym{}
+	err = queryManager.QueryRowWithStmt("SELECT id, name, create_time FROM city WHERE name = {Name}", "acronym_city").Scan(&city)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if city.ID != 1 || city.Name != "acronym_city" {
+		t.Fatalf("unexpected city : %+v", city)
+	}
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.FieldNameConverter = upperConverter{}
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+	defer man.Close()
+
+	upper := struct {
+		FID   int
+		FNAME string
+	}{}
+	err = man.QueryRowWithStmt("SELECT id, name FROM city WHERE name = {Name}", "acronym_city").Scan(&upper)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if upper.FID != 1 || upper.FNAME != "acronym_city" {
+		t.Fatalf("unexpected scan with custom converter : %+v", upper)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	setup()
+
+	slept := 0
+	start := time.Now()
+	err := queryManager.QueryRowWithStmt("SelectSleepWithTimeout").Scan(&slept)
+	if err == nil {
+		t.Fatalf("statement should be timed out")
+	}
+	if time.Since(start) > time.Millisecond*900 {
+		t.Fatalf("timeout is not applied. elapsed %s", time.Since(start))
+	}
+}
+
+func TestUserQueryCache(t *testing.T) {
+	setup()
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.UserQueryCacheSize = 2
+
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+	defer man.Close()
+
+	queries := []string{"SELECT 1 FROM DUAL", "SELECT 1 FROM DUAL", "SELECT 2 FROM DUAL", "SELECT 3 FROM DUAL", "SELECT 1 FROM DUAL"}
+	for _, q := range queries {
+		var n int
+		err = man.QueryRowWithStmt(q).Scan(&n)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	stats := man.CacheStats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Evictions != 2 || stats.Size != 2 {
+		t.Fatalf("unexpected cache stats : %+v", stats)
+	}
+
+	// statement id is not cached
+	_, _ = man.StatementType(sqlInsertCity)
+	if man.CacheStats().Misses != 4 {
+		t.Fatalf("statement id should not touch cache : %+v", man.CacheStats())
+	}
+}
+
+func TestStrictArity(t *testing.T) {
+	setup()
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.StrictArity = true
+
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+	defer man.Close()
+
+	_, err = man.ExecuteWithStmt(sqlUpdateCityWithName, 50, "arity_city", "extra")
+	if err == nil {
+		t.Fatalf("extra exec parameter should be error")
+	}
+
+	result := man.QueryWithStmt(sqlSelectCityWithName, "arity_city", "extra")
+	if result.GetError() == nil {
+		result.Close()
+		t.Fatalf("extra query parameter should be error")
+	}
+
+	result = man.QueryWithStmt(sqlSelectCityWithName, "arity_city")
+	if result.GetError() != nil {
+		t.Fatalf(result.GetError().Error())
+	}
+	result.Close()
+
+	// lenient by default
+	_, err = queryManager.ExecuteWithStmt(sqlUpdateCityWithName, 50, "arity_city", "extra")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestInsertReturningInvalidSqlType(t *testing.T) {
+	setup()
+
+	var city City
+	err := queryManager.InsertReturning(sqlSelectCityWithName, &city, "seoul")
+	if err != ErrInsertReturningInvalidSqlType {
+		t.Fatalf("select statement should be rejected : %v", err)
+	}
+}
+
+func TestExecuteQueryInvalidSqlType(t *testing.T) {
+	setup()
+
+	result := queryManager.ExecuteQuery(sqlSelectCityWithName, "seoul")
+	defer result.Close()
+	if result.GetError() != ErrExecutionInvalidSqlType {
+		t.Fatalf("select statement should be rejected : %v", result.GetError())
+	}
+}
+
+func TestUnwrapSingleBatch(t *testing.T) {
+	setup()
+
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.UnwrapSingleBatch = true
+
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+	defer man.Close()
+
+	params := []map[string]interface{}{
+		{"Name": "single batch", "Age": 1, "IsMan": true, "Percentage": 1.0, "CreateTime": time.Now(), "UpdateTime": nil},
+	}
+	result, err := man.ExecuteWithStmt(sqlInsertCity, params)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, ok := result.(ExecMultiResult); ok {
+		t.Fatalf("single element batch should be unwrapped")
+	}
+	if id, err := result.LastInsertId(); err != nil || id <= 0 {
+		t.Fatalf("invalid last insert id : %d, %v", id, err)
+	}
+
+	params = append(params, params[0])
+	result, err = man.ExecuteWithStmt(sqlInsertCity, params)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, ok := result.(ExecMultiResult); !ok {
+		t.Fatalf("multi element batch should be ExecMultiResult")
+	}
+}
+
+func TestConnLifecycle(t *testing.T) {
+	setup()
+
+	var connected, closed int32
+	path := filepath.Dir(xmlFile)
+	pref := NewQuerymanPreference(path, sourceName)
+	pref.Fileset = xmlFilePrefix + "*.xml"
+	pref.OnConnect = func(err error) {
+		if err == nil {
+			atomic.AddInt32(&connected, 1)
+		}
+	}
+	pref.OnConnClose = func(err error) {
+		atomic.AddInt32(&closed, 1)
+	}
+
+	man, err := NewQueryman(pref)
+	if err != nil {
+		t.Fatalf("fail to create queryman : %s", err.Error())
+	}
+
+	var n int
+	err = man.QueryRowWithStmt("SELECT 1 FROM DUAL").Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("fail to query through wrapped connection : %v", err)
+	}
+	if atomic.LoadInt32(&connected) == 0 {
+		t.Fatalf("OnConnect should be called")
+	}
+
+	man.Close()
+	if atomic.LoadInt32(&closed) != atomic.LoadInt32(&connected) {
+		t.Fatalf("every connection should be closed. connected=%d, closed=%d", connected, closed)
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	setup()
+
+	var n int
+	err := queryManager.QueryRowWithStmt("SELECT 1 FROM DUAL").Scan(&n)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	stats := queryManager.PoolStats()
+	if stats.MaxOpenConnections != queryManager.GetMaxConnCount() || stats.OpenConnections == 0 {
+		t.Fatalf("unexpected pool stats : %+v", stats)
+	}
+}
+
+func TestQueryAnonymousStruct(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "anonymous_struct", 42, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var row struct {
+		ID       int
+		CityName string `db:"name"`
+		Age      int
+	}
+	err = queryManager.QueryRowWithStmt(sqlSelectCityWithName, "anonymous_struct").Scan(&row)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if row.ID == 0 || row.CityName != "anonymous_struct" || row.Age != 42 {
+		t.Fatalf("invalid row : %+v", row)
+	}
+}
+
+func TestQueryFirst(t *testing.T) {
+	setup()
+
+	for _, age := range []int{41, 42} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "query_first_city", age, true, 40.5, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	city := City{}
+	found, err := queryManager.QueryFirst(sqlSelectCityWithName, &city, "query_first_city")
+	if err != nil || !found {
+		t.Fatalf("expect first row but %v, %v", found, err)
+	}
+	if city.Name != "query_first_city" {
+		t.Fatalf("invalid city : %v", city)
+	}
+
+	city = City{}
+	found, err = queryManager.QueryFirst(sqlSelectCityWithName, &city, "not_exist_city")
+	if err != nil || found {
+		t.Fatalf("expect not found but %v, %v", found, err)
+	}
+	if city.Id != 0 {
+		t.Fatalf("dest should be untouched : %v", city)
+	}
+}
+
+func TestDeleteByKeyListInArray(t *testing.T) {
+	setup()
+
+	for _, name := range []string{"delete_in_1", "delete_in_2", "delete_in_3"} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, name, 42, true, 40.0, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	sqlStr := "DELETE FROM CITY WHERE NAME IN ({Names})"
+	result, err := queryManager.ExecuteWithStmt(sqlStr, []interface{}{"delete_in_1", "delete_in_2"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, ok := result.(ExecMultiResult); ok {
+		t.Fatalf("IN array delete should be executed once")
+	}
+	if affected, _ := result.RowsAffected(); affected != 2 {
+		t.Fatalf("row affected = %d", affected)
+	}
+
+	result, err = queryManager.ExecuteWithStmt(sqlStr, map[string]interface{}{"Names": []string{"delete_in_3"}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if affected, _ := result.RowsAffected(); affected != 1 {
+		t.Fatalf("row affected = %d", affected)
+	}
+}
+
+func TestQueryStream(t *testing.T) {
+	setup()
+
+	for _, age := range []int{41, 42} {
+		_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "stream_city", age, true, 40.5, time.Now(), nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	ages := make([]int, 0)
+	err := queryManager.QueryWithStmt(sqlSelectCityWithName, "stream_city").Stream(func(columns []string, scan func(dest ...interface{}) error) error {
+		city := City{}
+		if len(columns) == 0 {
+			return fmt.Errorf("no column")
+		}
+		if err := scan(&city); err != nil {
+			return err
+		}
+		ages = append(ages, city.Age)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(ages) != 2 {
+		t.Fatalf("invalid stream : %v", ages)
+	}
+
+	stop := errors.New("stop")
+	err = queryManager.QueryWithStmt(sqlSelectCityWithName, "stream_city").Stream(func(columns []string, scan func(dest ...interface{}) error) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("expect error of fn but %v", err)
+	}
+}
+
+func TestForeignTransaction(t *testing.T) {
+	setup()
+
+	tx, err := queryManager.db.Begin()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	_, err = queryManager.ExecuteWithTx(tx, sqlInsertCity, "foreign_tx_city", 42, true, 40.5, time.Now(), nil)
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf(err.Error())
+	}
+
+	result := queryManager.QueryWithTx(tx, sqlSelectCityWithName, "foreign_tx_city")
+	cities := make([]City, 0)
+	err = result.ScanAll(&cities)
+	result.Close()
+	if err != nil || len(cities) != 1 {
+		tx.Rollback()
+		t.Fatalf("insert should be visible in tx : %v, %v", cities, err)
+	}
+
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	city := City{}
+	found, err := queryManager.QueryFirst(sqlSelectCityWithName, &city, "foreign_tx_city")
+	if err != nil || found {
+		t.Fatalf("rolled back insert should not be found : %v, %v", found, err)
+	}
+}
+
+func TestQueryCacheTTL(t *testing.T) {
+	setup()
+
+	err := queryManager.RegisterXML(strings.NewReader(`<query>
+	<select id="selectCachedCity" cacheTTL="1m">
+		SELECT * FROM city WHERE name = {Name}
+	</select>
+</query>`))
+	if err != nil && !strings.Contains(err.Error(), "duplicated") {
+		t.Fatalf(err.Error())
+	}
+
+	_, err = queryManager.ExecuteWithStmt(sqlInsertCity, "cached_city", 42, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	cities := make([]City, 0)
+	result := queryManager.QueryWithStmt("selectCachedCity", "cached_city")
+	err = result.ScanAll(&cities)
+	result.Close()
+	if err != nil || len(cities) != 1 {
+		t.Fatalf("invalid cities : %v, %v", cities, err)
+	}
+
+	_, err = queryManager.ExecuteWithStmt(sqlInsertCity, "cached_city", 43, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	cities = make([]City, 0)
+	result = queryManager.QueryWithStmt("selectCachedCity", "cached_city")
+	err = result.ScanAll(&cities)
+	result.Close()
+	if err != nil || len(cities) != 1 || cities[0].Age != 42 {
+		t.Fatalf("rows should be served from cache until ttl : %v, %v", cities, err)
+	}
+}
+
+func TestQueryBatch(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "batch_city", 51, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	requests := []BatchRequest{
+		{StmtId: sqlSelectCityWithName, Params: []interface{}{"batch_city"}},
+		{StmtId: "notExistBatchStmt"},
+		{StmtId: sqlSelectCityWithName, Params: []interface{}{"batch_city"}},
+	}
+	results, err := queryManager.QueryBatch(context.Background(), requests)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(results) != len(requests) {
+		t.Fatalf("invalid result count : %d", len(results))
+	}
+
+	if results[1].GetError() == nil {
+		t.Fatalf("failing statement should be reported in its result")
+	}
+
+	for _, i := range []int{0, 2} {
+		cities := make([]City, 0)
+		err = results[i].ScanAll(&cities)
+		results[i].Close()
+		if err != nil || len(cities) == 0 || cities[0].Name != "batch_city" {
+			t.Fatalf("invalid cities of result %d : %v, %v", i, cities, err)
+		}
+	}
+}
+
+func TestQueryRowCloseAfterScan(t *testing.T) {
+	setup()
+
+	_, err := queryManager.ExecuteWithStmt(sqlInsertCity, "row_close_city", 42, true, 40.5, time.Now(), nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	city := City{}
+	row := queryManager.QueryRowWithStmt(sqlSelectCityWithName, "row_close_city")
+	if err = row.Scan(&city); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err = row.Close(); err != nil {
+		t.Fatalf("close after scan : %s", err.Error())
+	}
+	if err = row.Close(); err != nil {
+		t.Fatalf("close twice : %s", err.Error())
+	}
+
+	row = queryManager.QueryRowWithStmt(sqlSelectCityWithName, "row_close_city")
+	if err = row.Close(); err != nil {
+		t.Fatalf("close without scan : %s", err.Error())
+	}
+	if err = row.Scan(&city); err != ErrRowResultClosed {
+		t.Fatalf("scan after close should be ErrRowResultClosed : %v", err)
+	}
+}
+
+func TestSessionVariable(t *testing.T) {
+	setup()
+
+	session, err := queryManager.Session(context.Background())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer session.Close()
+
+	_, err = session.ExecuteWithStmt("SET @session_age = {Age}", map[string]interface{}{"Age": 77})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		var age int
+		err = session.QueryRowWithStmt("SELECT @session_age").Scan(&age)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if age != 77 {
+			t.Fatalf("session variable should be kept on the connection : %d", age)
+		}
+	}
+}
+
+func TestExecuteEach(t *testing.T) {
+	setup()
+
+	type cityEvent struct {
+		Kind string
+		Name string
+	}
+	resolver := func(item interface{}) (string, interface{}) {
+		e := item.(cityEvent)
+		if e.Kind == "insert" {
+			return sqlInsertCity, []interface{}{e.Name, 33, true, 40.5, time.Now(), nil}
+		}
+		return "notExistEachStmt", nil
+	}
+
+	results, err := queryManager.ExecuteEach(resolver, []interface{}{cityEvent{"insert", "each_city"}, cityEvent{"insert", "each_city"}})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("fail to execute each : %v", err)
+	}
+
+	_, err = queryManager.ExecuteEach(resolver, []interface{}{cityEvent{"insert", "each_rollback_city"}, cityEvent{"unknown", ""}})
+	if err == nil {
+		t.Fatalf("failing item should be error")
+	}
+
+	cities := make([]City, 0)
+	err = queryManager.QueryWithStmt(sqlSelectCityWithName, "each_rollback_city").ScanAll(&cities)
+	if err != nil || len(cities) != 0 {
+		t.Fatalf("executed items should be rolled back : %v, %v", cities, err)
+	}
+}
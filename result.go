@@ -21,17 +21,49 @@
 package queryman
 
 import (
+	"context"
 	"database/sql"
-	"database/sql/driver"
 	"fmt"
 	"reflect"
 )
 
+// resultRows is the row source of QueryResult. *sql.Rows or cachedRows served from result cache
+type resultRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Columns() ([]string, error)
+	Err() error
+	Close() error
+}
+
+// databaseTypeNames returns database type name of each column of rows
+func databaseTypeNames(rows resultRows) ([]string, error) {
+	if cached, ok := rows.(*cachedRows); ok {
+		return cached.result.typeNames, nil
+	}
+
+	columnTypes, err := rows.(*sql.Rows).ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columnTypes))
+	for i, c := range columnTypes {
+		names[i] = c.DatabaseTypeName()
+	}
+	return names, nil
+}
+
 type QueryResult struct {
 	pstmt              *sql.Stmt
 	err                error
-	rows               *sql.Rows
+	rows               resultRows
 	fieldNameConverter FieldNameConvertStrategy
+	structScanner      *StructureScanner // column to field plan reused across rows
+	columnTypes        []string          // database type names of columns reused across rows by ScanValues
+	strictColumns      bool
+	stripQualifier     bool
+	maxScanRows        int
+	cancel             context.CancelFunc
 }
 
 func newQueryResultError(err error) *QueryResult {
@@ -58,8 +90,10 @@ func (r *QueryResult) Next() bool {
 	return r.rows.Next()
 }
 
+// GetRows returns driver rows. nil when the result is served from result cache
 func (r *QueryResult) GetRows() *sql.Rows {
-	return r.rows
+	rows, _ := r.rows.(*sql.Rows)
+	return rows
 }
 
 func (r *QueryResult) GetError() (err error) {
@@ -100,7 +134,7 @@ func (r *QueryResult) Scan(v ...interface{}) (err error) {
 	case reflect.Ptr:
 		return ErrPtrIsNotSupported
 	case reflect.Struct:
-		if _, is := val.Interface().(driver.Valuer); !is {
+		if !isValueStruct(val.Interface()) {
 			return r.scanToStruct(&val)
 		}
 	}
@@ -113,14 +147,221 @@ func (r *QueryResult) scanToStruct(val *reflect.Value) error {
 		return r.rows.Err()
 	}
 
+	if r.structScanner == nil || r.structScanner.sourceType != val.Type() || r.structScanner.positional {
+		columns, err := r.rows.Columns()
+		if err != nil {
+			return err
+		}
+		if r.stripQualifier {
+			columns = unqualifiedColumns(columns)
+		}
+		r.structScanner, err = newStructureScanner(r.fieldNameConverter, columns, val, r.strictColumns)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.structScanner.reset(val)
+	return r.rows.Scan(r.structScanner.cloneScannerList()...)
+}
+
+// ScanPositional scans current row into struct dest. n-th column goes to n-th exported field in declaration order
+// without column name matching. e.g) SELECT a+b, count(*)
+func (r *QueryResult) ScanPositional(dest interface{}) (err error) {
+	if r.err != nil {
+		return r.err
+	}
+
+	if r.rows.Err() != nil {
+		return r.rows.Err()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fail to scan : %s", r)
+		}
+	}()
+
+	val, err := positionalDest(dest)
+	if err != nil {
+		return err
+	}
+
+	if r.structScanner == nil || r.structScanner.sourceType != val.Type() || !r.structScanner.positional {
+		columns, err := r.rows.Columns()
+		if err != nil {
+			return err
+		}
+		r.structScanner, err = newPositionalScanner(len(columns), &val)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.structScanner.reset(&val)
+	return r.rows.Scan(r.structScanner.cloneScannerList()...)
+}
+
+func positionalDest(dest interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr {
+		return rv, ErrQueryNeedsPtrParameter
+	}
+	if rv.IsNil() {
+		return rv, ErrNilPtr
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return rv, ErrScanPositionalNeedStruct
+	}
+	return rv.Elem(), nil
+}
+
+// Columns returns column names of the result
+func (r *QueryResult) Columns() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.rows.Columns()
+}
+
+// ScanValues scans current row into new slice ordered by Columns() without knowing the schema.
+// text is string, integer is int64 (uint64 over int64), float is float64, binary is []byte
+// and decimal is string to keep precision. NULL is nil
+func (r *QueryResult) ScanValues() ([]interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.columnTypes == nil {
+		columnTypes, err := databaseTypeNames(r.rows)
+		if err != nil {
+			return nil, err
+		}
+		r.columnTypes = columnTypes
+	}
+
+	values, err := scanRowValues(r.rows, len(r.columnTypes))
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		values[i] = columnValue(r.columnTypes[i], v)
+	}
+	return values, nil
+}
+
+// ScanAll appends the remaining rows to slice dest. e.g) *[]City, *[]*City or *[]string of single column.
+// with MaxScanRows, rows more than the limit fail with ErrMaxScanRowsExceeded
+func (r *QueryResult) ScanAll(dest interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return ErrScanAllNeedSlice
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	count := 0
+	for r.rows.Next() {
+		if err := r.checkScanRows(count); err != nil {
+			return err
+		}
+		count++
+
+		var elem reflect.Value
+		if elemType.Kind() == reflect.Ptr {
+			elem = reflect.New(elemType.Elem())
+		} else {
+			elem = reflect.New(elemType)
+		}
+		if err := r.Scan(elem.Interface()); err != nil {
+			return err
+		}
+		if elemType.Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	rv.Elem().Set(slice)
+	return r.rows.Err()
+}
+
+// ScanMapSlice returns the remaining rows as maps of column name to value of ScanValues.
+// with MaxScanRows, rows more than the limit fail with ErrMaxScanRowsExceeded
+func (r *QueryResult) ScanMapSlice() ([]map[string]interface{}, error) {
+	columns, err := r.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]map[string]interface{}, 0)
+	for r.rows.Next() {
+		if err = r.checkScanRows(len(list)); err != nil {
+			return nil, err
+		}
+
+		values, err := r.ScanValues()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			m[c] = values[i]
+		}
+		list = append(list, m)
+	}
+	return list, r.rows.Err()
+}
+
+// Stream calls fn for each remaining row with column names and scan of the row which works like Scan.
+// error of fn stops streaming and is returned. the result is closed when Stream returns
+func (r *QueryResult) Stream(fn func(columns []string, scan func(dest ...interface{}) error) error) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.Close()
+
 	columns, err := r.rows.Columns()
 	if err != nil {
 		return err
 	}
 
-	ss := newStructureScanner(r.fieldNameConverter, columns, val)
+	scan := func(dest ...interface{}) error {
+		return r.Scan(dest...)
+	}
+	for r.rows.Next() {
+		if err = fn(columns, scan); err != nil {
+			return err
+		}
+	}
+	return r.rows.Err()
+}
 
-	return r.rows.Scan(ss.cloneScannerList()...)
+// scanFirst scans the first row into dest and closes the result. remaining rows are ignored
+func (r *QueryResult) scanFirst(dest interface{}) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	defer r.Close()
+
+	if !r.rows.Next() {
+		return false, r.rows.Err()
+	}
+	if err := r.Scan(dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkScanRows fails when one more row exceeds MaxScanRows
+func (r *QueryResult) checkScanRows(scanned int) error {
+	if r.maxScanRows > 0 && scanned >= r.maxScanRows {
+		return fmt.Errorf("%w : %d", ErrMaxScanRowsExceeded, r.maxScanRows)
+	}
+	return nil
 }
 
 func (r *QueryResult) Close() error {
@@ -130,6 +371,10 @@ func (r *QueryResult) Close() error {
 			r.pstmt.Close()
 			r.pstmt = nil
 		}
+		if r.cancel != nil {
+			r.cancel()
+			r.cancel = nil
+		}
 	}()
 
 	if r.rows != nil {
@@ -145,6 +390,9 @@ type QueryRowResult struct {
 	err                error
 	rows               *sql.Rows
 	fieldNameConverter FieldNameConvertStrategy
+	strictColumns      bool
+	stripQualifier     bool
+	cancel             context.CancelFunc
 }
 
 func newQueryRowResultError(err error) *QueryRowResult {
@@ -165,28 +413,39 @@ func (r *QueryRowResult) SetTransaction() {
 	r.transaction = true
 }
 
-func (r *QueryRowResult) Scan(v ...interface{}) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("fail to scan : %s", r)
-		}
-	}()
+// Close releases rows (and statement) of the result. Scan closes the result by itself
+// but Close is safe to call any number of times, before or after Scan
+func (r *QueryRowResult) Close() error {
+	return r.release()
+}
 
-	defer func() {
-		if r.rows != nil {
-			r.rows.Close()
-			r.rows = nil
-		}
-		if !r.transaction && r.pstmt != nil {
-			r.pstmt.Close()
-			r.pstmt = nil
-		}
-	}()
+// release closes rows (and statement) after the single row is scanned
+func (r *QueryRowResult) release() (err error) {
+	if r.rows != nil {
+		err = r.rows.Close()
+		r.rows = nil
+	}
+	if !r.transaction && r.pstmt != nil {
+		r.pstmt.Close()
+		r.pstmt = nil
+	}
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	return err
+}
 
+// next moves to the single row. no row is ErrNoRows
+func (r *QueryRowResult) next() error {
 	if r.err != nil {
 		return r.err
 	}
 
+	if r.rows == nil {
+		return ErrRowResultClosed
+	}
+
 	if r.rows.Err() != nil {
 		return r.rows.Err()
 	}
@@ -197,6 +456,21 @@ func (r *QueryRowResult) Scan(v ...interface{}) (err error) {
 		}
 		return ErrNoRows
 	}
+	return nil
+}
+
+func (r *QueryRowResult) Scan(v ...interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fail to scan : %s", r)
+		}
+	}()
+
+	defer r.release()
+
+	if err = r.next(); err != nil {
+		return err
+	}
 
 	atype := reflect.TypeOf(v[0])
 
@@ -217,7 +491,7 @@ func (r *QueryRowResult) Scan(v ...interface{}) (err error) {
 	case reflect.Ptr:
 		return ErrPtrIsNotSupported
 	case reflect.Struct:
-		if _, is := val.Interface().(driver.Valuer); !is {
+		if !isValueStruct(val.Interface()) {
 			return r.scanToStruct(&val)
 		}
 	}
@@ -225,20 +499,71 @@ func (r *QueryRowResult) Scan(v ...interface{}) (err error) {
 	return r.rows.Scan(v...)
 }
 
+// ScanOrDefault scans the row into dest like Scan. no row is not an error but found=false with dest untouched
+func (r *QueryRowResult) ScanOrDefault(dest interface{}) (found bool, err error) {
+	err = r.Scan(dest)
+	if err == ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ScanPositional scans the row into struct dest by column position. see QueryResult.ScanPositional
+func (r *QueryRowResult) ScanPositional(dest interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fail to scan : %s", r)
+		}
+	}()
+
+	defer r.release()
+
+	if err = r.next(); err != nil {
+		return err
+	}
+
+	val, err := positionalDest(dest)
+	if err != nil {
+		return err
+	}
+
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	ss, err := newPositionalScanner(len(columns), &val)
+	if err != nil {
+		return err
+	}
+	return r.rows.Scan(ss.cloneScannerList()...)
+}
+
 func (r *QueryRowResult) scanToStruct(val *reflect.Value) error {
 	columns, err := r.rows.Columns()
 	if err != nil {
 		return err
 	}
+	if r.stripQualifier {
+		columns = unqualifiedColumns(columns)
+	}
 
-	ss := newStructureScanner(r.fieldNameConverter, columns, val)
+	ss, err := newStructureScanner(r.fieldNameConverter, columns, val, r.strictColumns)
+	if err != nil {
+		return err
+	}
 
 	return r.rows.Scan(ss.cloneScannerList()...)
 }
 
 type ExecMultiResult struct {
-	idList      []int64
-	rowAffected int64
+	idList          []int64
+	rowAffected     int64
+	affectedUnknown bool       // driver did not report affected rows of an execution
+	last            sql.Result // driver result of the last execution
 }
 
 func (p *ExecMultiResult) addInsertId(id int64) {
@@ -249,6 +574,18 @@ func (p *ExecMultiResult) addInsertId(id int64) {
 	p.idList = append(p.idList, id)
 }
 
+// merge adds affected rows and insert ids of res. all ids of ExecMultiResult are added
+func (p *ExecMultiResult) merge(res sql.Result) {
+	p.addRowsAffected(res)
+	if multi, ok := res.(ExecMultiResult); ok {
+		p.idList = append(p.idList, multi.idList...)
+		return
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		p.addInsertId(id)
+	}
+}
+
 func (p ExecMultiResult) GetInsertIdList() []int64 {
 	return p.idList
 }
@@ -261,6 +598,31 @@ func (p ExecMultiResult) LastInsertId() (int64, error) {
 	return p.idList[0], nil
 }
 
+// addRowsAffected adds affected rows of res. count not supported by the driver makes the sum unknown
+func (p *ExecMultiResult) addRowsAffected(res sql.Result) {
+	if multi, ok := res.(ExecMultiResult); ok {
+		p.affectedUnknown = p.affectedUnknown || multi.affectedUnknown
+		p.rowAffected = addAffected(p.rowAffected, multi.rowAffected)
+		return
+	}
+
+	affectedCount, err := res.RowsAffected()
+	if err != nil {
+		p.affectedUnknown = true
+		return
+	}
+	p.rowAffected = addAffected(p.rowAffected, affectedCount)
+}
+
+// RowsAffected returns the sum of affected rows. -1 when the driver did not report it for an execution (AffectedUnknown)
 func (p ExecMultiResult) RowsAffected() (int64, error) {
+	if p.affectedUnknown {
+		return -1, nil
+	}
 	return p.rowAffected, nil
 }
+
+// AffectedUnknown reports the driver did not support affected rows for an execution, distinct from zero affected rows
+func (p ExecMultiResult) AffectedUnknown() bool {
+	return p.affectedUnknown
+}
@@ -0,0 +1,355 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const defaultResultCacheSize = 1000
+
+// cachedResult is materialized rows of select statement declared with cacheTTL
+type cachedResult struct {
+	expire    time.Time
+	columns   []string
+	typeNames []string
+	values    [][]interface{}
+}
+
+type resultCacheEntry struct {
+	key    string
+	result *cachedResult
+}
+
+// resultCache keeps materialized rows keyed by statement id and parameters until TTL expiry
+// in LRU order up to capacity. writes do not invalidate the cache
+type resultCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+func newResultCache(capacity int) *resultCache {
+	c := &resultCache{}
+	c.capacity = capacity
+	c.entries = make(map[string]*list.Element)
+	c.order = list.New()
+	return c
+}
+
+// resultCacheKey hashes statement id, resolved query and bind values formatted with their types.
+// pointer and driver.Valuer are formatted by the value they hold at the time of the call
+func resultCacheKey(stmtId string, query string, params []interface{}) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(stmtId)
+	buffer.WriteString("\x00")
+	buffer.WriteString(query)
+	for _, p := range params {
+		p = cacheKeyValue(p)
+		buffer.WriteString(fmt.Sprintf("\x00%T=%v", p, p))
+	}
+	sum := sha256.Sum256(buffer.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheKeyValue dereferences pointers and driver.Valuer down to the value passed to the driver
+func cacheKeyValue(p interface{}) interface{} {
+	for i := 0; i < 8 && p != nil; i++ {
+		if valuer, ok := p.(driver.Valuer); ok {
+			rv := reflect.ValueOf(p)
+			if rv.Kind() == reflect.Ptr && rv.IsNil() {
+				return nil
+			}
+			value, err := valuer.Value()
+			if err != nil {
+				return p
+			}
+			p = value
+			continue
+		}
+
+		rv := reflect.ValueOf(p)
+		if rv.Kind() != reflect.Ptr {
+			return p
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		p = rv.Elem().Interface()
+	}
+	return p
+}
+
+// resolveBinds resolves query and bind values of v the way queryMultiRow does without executing.
+// single is false when v is not resolved to a single query (e.g. list parameter queried per element)
+func resolveBinds(sqlProxy SqlProxy, stmt QueryStatement, v []interface{}) (query string, params []interface{}, single bool, err error) {
+	v = derefArgList(v)
+	execStmt, err := refineConditional(sqlProxy.getNormalizer(), stmt, v...)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if len(v) == 0 {
+		return execStmt.Query, nil, true, nil
+	}
+
+	var failed *QueryResult
+	if m, named := findNamedParams(v); named {
+		query, params, failed = resolveColumnBindInMap(sqlProxy, execStmt, m)
+	} else {
+		val := reflect.ValueOf(v[0])
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				return "", nil, false, ErrNilPtr
+			}
+			val = val.Elem()
+		}
+
+		switch val.Kind() {
+		case reflect.Interface:
+			return "", nil, false, ErrInterfaceIsNotSupported
+		case reflect.Ptr:
+			return "", nil, false, ErrPtrIsNotSupported
+		case reflect.Slice, reflect.Array:
+			if !stmt.firstArgsIsArray() && !stmt.firstArgsHasModifier() && !isBindConverted(v[0]) {
+				return "", nil, false, nil
+			}
+			query, params, err = resolveColumnBindInList(sqlProxy.getNormalizer(), execStmt, v)
+		case reflect.Struct:
+			if isValueStruct(val.Interface()) {
+				query, params, err = resolveColumnBindInList(sqlProxy.getNormalizer(), execStmt, v)
+			} else {
+				query, params, failed = resolveColumnBindInMap(sqlProxy, execStmt, flattenStructToMap(val.Interface()))
+			}
+		case reflect.Map:
+			query, params, failed = resolveColumnBindInMap(sqlProxy, execStmt, flattenToMap(val.Interface()))
+		default:
+			query, params, err = resolveColumnBindInList(sqlProxy.getNormalizer(), execStmt, v)
+		}
+	}
+	if failed != nil {
+		return "", nil, false, failed.err
+	}
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	params, err = bindValues(sqlProxy, params)
+	if err != nil {
+		return "", nil, false, err
+	}
+	return query, params, true, nil
+}
+
+func (c *resultCache) get(key string, now time.Time) (*cachedResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	result := e.Value.(*resultCacheEntry).result
+	if !now.Before(result.expire) {
+		c.remove(e)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return result, true
+}
+
+// put stores result, drops expired entries and evicts the least recently used entries over capacity
+func (c *resultCache) put(key string, result *cachedResult, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for e := c.order.Back(); e != nil; {
+		prev := e.Prev()
+		if !now.Before(e.Value.(*resultCacheEntry).result.expire) {
+			c.remove(e)
+		}
+		e = prev
+	}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*resultCacheEntry).result = result
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&resultCacheEntry{key: key, result: result})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *resultCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*resultCacheEntry).key)
+}
+
+// materialize reads the remaining rows of result into cachedResult. MaxScanRows limits the rows
+func materialize(result *QueryResult, ttl time.Duration) (*cachedResult, error) {
+	columns, err := result.rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	typeNames, err := databaseTypeNames(result.rows)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedResult{}
+	cached.columns = columns
+	cached.typeNames = typeNames
+	cached.values = make([][]interface{}, 0)
+	for result.rows.Next() {
+		if err = result.checkScanRows(len(cached.values)); err != nil {
+			return nil, err
+		}
+		values, err := scanRowValues(result.rows, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		cached.values = append(cached.values, values)
+	}
+	if err = result.rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cached.expire = time.Now().Add(ttl)
+	return cached, nil
+}
+
+// queryCached serves select statement declared with cacheTTL from result cache keyed by resolved bind values.
+// on miss, rows are materialized and cached. parameters not resolved to a single query bypass the cache
+func (man *QueryMan) queryCached(ctx context.Context, stmt QueryStatement, v ...interface{}) *QueryResult {
+	proxy := man.proxyFor(stmt)
+	query, params, single, err := resolveBinds(proxy, stmt, v)
+	if err != nil {
+		return newQueryResultError(err)
+	}
+	if !single {
+		return queryMultiRow(ctx, proxy, stmt, v...)
+	}
+
+	key := resultCacheKey(stmt.Id, query, params)
+	cached, ok := man.resultCache.get(key, time.Now())
+	if !ok {
+		result := queryMultiRow(ctx, proxy, stmt, v...)
+		if result.err != nil {
+			return result
+		}
+
+		cached, err = materialize(result, stmt.cacheTTL)
+		result.Close()
+		if err != nil {
+			return newQueryResultError(err)
+		}
+		man.resultCache.put(key, cached, time.Now())
+	}
+
+	return man.newCachedQueryResult(cached)
+}
+
+// newCachedQueryResult returns QueryResult iterating materialized rows
+func (man *QueryMan) newCachedQueryResult(cached *cachedResult) *QueryResult {
+	queryedRow := &QueryResult{}
+	queryedRow.rows = &cachedRows{result: cached, index: -1}
+	queryedRow.fieldNameConverter = man.fieldNameConverter
+	queryedRow.strictColumns = man.preference.StrictColumns
+	queryedRow.stripQualifier = man.preference.StripColumnQualifier
+	queryedRow.maxScanRows = man.preference.MaxScanRows
+	return queryedRow
+}
+
+// cachedRows iterates cachedResult like *sql.Rows
+type cachedRows struct {
+	result *cachedResult
+	index  int
+	closed bool
+}
+
+func (c *cachedRows) Next() bool {
+	if c.closed {
+		return false
+	}
+	c.index++
+	return c.index < len(c.result.values)
+}
+
+func (c *cachedRows) Scan(dest ...interface{}) error {
+	if c.closed {
+		return errors.New("sql: Rows are closed")
+	}
+	if c.index < 0 || c.index >= len(c.result.values) {
+		return errors.New("sql: Scan called without calling Next")
+	}
+
+	row := c.result.values[c.index]
+	if len(dest) != len(row) {
+		return fmt.Errorf("sql: expected %d destination arguments in Scan, not %d", len(row), len(dest))
+	}
+	for i, d := range dest {
+		// cached bytes are shared by every hit
+		value := row[i]
+		if b, ok := value.([]byte); ok {
+			value = cloneBytes(b)
+		}
+
+		var err error
+		if scanner, ok := d.(sql.Scanner); ok {
+			err = scanner.Scan(value)
+		} else {
+			err = convertAssign(d, value)
+		}
+		if err != nil {
+			return fmt.Errorf("sql: Scan error on column index %d, name %q: %s", i, c.result.columns[i], err.Error())
+		}
+	}
+	return nil
+}
+
+func (c *cachedRows) Columns() ([]string, error) {
+	columns := make([]string, len(c.result.columns))
+	copy(columns, c.result.columns)
+	return columns, nil
+}
+
+func (c *cachedRows) Err() error {
+	return nil
+}
+
+func (c *cachedRows) Close() error {
+	c.closed = true
+	return nil
+}
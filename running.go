@@ -0,0 +1,124 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RunningQuery is a statement executing now. rows of query are counted until the result is closed
+type RunningQuery struct {
+	Id     uint64
+	StmtId string
+	Start  time.Time
+}
+
+type runningQuery struct {
+	RunningQuery
+	cancel context.CancelFunc
+}
+
+// runningRegistry tracks executing statements with cancel func of their context
+type runningRegistry struct {
+	seq     uint64
+	mutex   sync.Mutex
+	running map[uint64]runningQuery
+}
+
+func newRunningRegistry() *runningRegistry {
+	registry := &runningRegistry{}
+	registry.running = make(map[uint64]runningQuery)
+	return registry
+}
+
+// register returns cancelable context for the execution and func deregistering it on completion
+func (r *runningRegistry) register(ctx context.Context, stmtId string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	q := runningQuery{cancel: cancel}
+	q.Id = atomic.AddUint64(&r.seq, 1)
+	q.StmtId = stmtId
+	q.Start = time.Now()
+
+	r.mutex.Lock()
+	r.running[q.Id] = q
+	r.mutex.Unlock()
+
+	return ctx, func() {
+		r.mutex.Lock()
+		delete(r.running, q.Id)
+		r.mutex.Unlock()
+		cancel()
+	}
+}
+
+func (r *runningRegistry) list() []RunningQuery {
+	r.mutex.Lock()
+	list := make([]RunningQuery, 0, len(r.running))
+	for _, q := range r.running {
+		list = append(list, q.RunningQuery)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+func (r *runningRegistry) cancel(id uint64) bool {
+	r.mutex.Lock()
+	q, ok := r.running[id]
+	r.mutex.Unlock()
+
+	if ok {
+		q.cancel()
+	}
+	return ok
+}
+
+func noopCancel() {}
+
+// RunningQueries returns statements executing now in start order. only available with TrackRunning preference
+func (man *QueryMan) RunningQueries() []RunningQuery {
+	if man.running == nil {
+		return []RunningQuery{}
+	}
+	return man.running.list()
+}
+
+// CancelRunning cancels context of the running statement. returns false when it is not running
+func (man *QueryMan) CancelRunning(id uint64) bool {
+	if man.running == nil {
+		return false
+	}
+	return man.running.cancel(id)
+}
+
+func (man *QueryMan) trackRunning(ctx context.Context, stmtId string) (context.Context, context.CancelFunc) {
+	if man.running == nil {
+		return ctx, noopCancel
+	}
+	return man.running.register(ctx, stmtId)
+}
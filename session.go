@@ -0,0 +1,182 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Session pins one connection of the primary pool so that session scoped state (SET variables,
+// temporary tables) is kept between statements. Close returns the connection to the pool
+type Session struct {
+	conn    *sql.Conn
+	manager *QueryMan
+}
+
+// Session takes a connection from the primary pool. ctx bounds waiting for the connection
+func (man *QueryMan) Session(ctx context.Context) (*Session, error) {
+	conn, err := man.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get connection : %s", err.Error())
+	}
+
+	session := &Session{}
+	session.conn = conn
+	session.manager = man
+	return session, nil
+}
+
+// Close returns the connection to the pool. session state stays on the connection unless reset by the driver
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+func (s *Session) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.conn.ExecContext(ctx, query, args...)
+}
+
+func (s *Session) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn.QueryContext(ctx, query, args...)
+}
+
+func (s *Session) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.conn.QueryRowContext(ctx, query, args...)
+}
+
+func (s *Session) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.conn.PrepareContext(ctx, query)
+}
+
+func (s *Session) isTransaction() bool {
+	return false
+}
+
+func (s *Session) getPreference() *QuerymanPreference {
+	return &s.manager.preference
+}
+
+func (s *Session) getNormalizer() QueryNormalizer {
+	return s.manager.getNormalizer()
+}
+
+func (s *Session) debugEnabled() bool {
+	return s.manager.debugEnabled()
+}
+
+func (s *Session) debugPrint(format string, params ...interface{}) {
+	s.manager.debugPrint(format, params...)
+}
+
+func (s *Session) recordExcution(stmtId string, bind time.Duration, start time.Time) {
+	s.manager.recordExcution(stmtId, bind, start)
+}
+
+func (s *Session) recordAffected(stmtId string, affected int64) {
+	s.manager.recordAffected(stmtId, affected)
+}
+
+func (s *Session) trackRunning(ctx context.Context, stmtId string) (context.Context, context.CancelFunc) {
+	return s.manager.trackRunning(ctx, stmtId)
+}
+
+// Begin starts transaction on the connection of the session
+func (s *Session) Begin(ctx context.Context) (*DBTransaction, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.manager.WrapTx(tx), nil
+}
+
+func (s *Session) ExecuteWithStmt(stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	return s.ExecuteWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *Session) ExecuteWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	if statements, ok := findMultiStatement(s.getPreference(), stmtIdOrUserQuery); ok {
+		if s.getPreference().MultiStatementExec == MultiStatementReject {
+			return nil, ErrMultiStatement
+		}
+
+		tx, err := s.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result, err := tx.executeStatements(ctx, statements, v)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		return result, tx.Commit()
+	}
+
+	stmt, err := s.manager.find(stmtIdOrUserQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.eleType != eleTypeInsert && stmt.eleType != eleTypeUpdate {
+		return nil, ErrExecutionInvalidSqlType
+	}
+
+	return execute(ctx, s, stmt, v...)
+}
+
+func (s *Session) QueryWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	return s.QueryWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *Session) QueryWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	stmt, err := s.manager.find(stmtIdOrUserQuery)
+	if err != nil {
+		return newQueryResultError(err)
+	}
+
+	if stmt.eleType != eleTypeSelect {
+		return newQueryResultError(ErrQueryInvalidSqlType)
+	}
+
+	queryedRow := queryMultiRow(ctx, s, stmt, v...)
+	queryedRow.fieldNameConverter = s.manager.fieldNameConverter
+	return queryedRow
+}
+
+func (s *Session) QueryRowWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
+	return s.QueryRowWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *Session) QueryRowWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
+	stmt, err := s.manager.find(stmtIdOrUserQuery)
+	if err != nil {
+		return newQueryRowResultError(err)
+	}
+
+	if stmt.eleType != eleTypeSelect {
+		return newQueryRowResultError(ErrQueryInvalidSqlType)
+	}
+
+	queryRowResult := querySingleRow(ctx, s, stmt, v...)
+	queryRowResult.fieldNameConverter = s.manager.fieldNameConverter
+	return queryRowResult
+}
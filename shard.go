@@ -0,0 +1,203 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ShardResolver returns shard key for statement and its parameters
+type ShardResolver func(ctx context.Context, stmtId string, params []interface{}) (string, error)
+
+// ShardedQueryMan routes each execution to the db pool of resolved shard.
+// statements are loaded once and shared by all shards
+type ShardedQueryMan struct {
+	shards   map[string]*QueryMan
+	resolver ShardResolver
+}
+
+// NewShardedQueryman opens db pool for each shard key -> data source url.
+// data source url of pref is not used
+func NewShardedQueryman(pref QuerymanPreference, dataSourceUrls map[string]string, resolver ShardResolver) (*ShardedQueryMan, error) {
+	if len(dataSourceUrls) == 0 {
+		return nil, fmt.Errorf("no shard data source")
+	}
+	if resolver == nil {
+		return nil, fmt.Errorf("shard resolver is nil")
+	}
+
+	keys := make([]string, 0, len(dataSourceUrls))
+	for k := range dataSourceUrls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sharded := &ShardedQueryMan{}
+	sharded.shards = make(map[string]*QueryMan)
+	sharded.resolver = resolver
+
+	statementMap := make(map[string]QueryStatement)
+	statementMutex := &sync.RWMutex{}
+	for i, k := range keys {
+		manager, err := openQueryman(pref, dataSourceUrls[k], statementMap, statementMutex)
+		if err != nil {
+			sharded.Close()
+			return nil, fmt.Errorf("fail to open shard %s : %s", k, err.Error())
+		}
+		sharded.shards[k] = manager
+
+		if i > 0 {
+			continue
+		}
+
+		err = loadXmlFile(manager, pref.queryFilePath, pref.Fileset)
+		if err != nil {
+			sharded.Close()
+			return nil, fmt.Errorf("fail to load xml file : %w [path=%s,fileset=%s]", err, pref.queryFilePath, pref.Fileset)
+		}
+	}
+
+	return sharded, nil
+}
+
+// Shard returns QueryMan of shard key
+func (s *ShardedQueryMan) Shard(shardKey string) (*QueryMan, error) {
+	manager, ok := s.shards[shardKey]
+	if !ok {
+		return nil, fmt.Errorf("not found shard : %s", shardKey)
+	}
+	return manager, nil
+}
+
+// anyShard returns a shard to register statements. statements are shared by all shards
+func (s *ShardedQueryMan) anyShard() (*QueryMan, error) {
+	for _, manager := range s.shards {
+		return manager, nil
+	}
+	return nil, fmt.Errorf("no shard")
+}
+
+// Register adds statement to all shards
+func (s *ShardedQueryMan) Register(stmt QueryStatement) error {
+	manager, err := s.anyShard()
+	if err != nil {
+		return err
+	}
+	return manager.Register(stmt)
+}
+
+// RegisterXML loads statements of xml from reader to all shards
+func (s *ShardedQueryMan) RegisterXML(reader io.Reader) error {
+	manager, err := s.anyShard()
+	if err != nil {
+		return err
+	}
+	return manager.RegisterXML(reader)
+}
+
+func (s *ShardedQueryMan) resolve(ctx context.Context, stmtIdOrUserQuery string, v []interface{}) (*QueryMan, error) {
+	shardKey, err := s.resolver(ctx, stmtIdOrUserQuery, v)
+	if err != nil {
+		return nil, fmt.Errorf("fail to resolve shard : %s", err.Error())
+	}
+	return s.Shard(shardKey)
+}
+
+func (s *ShardedQueryMan) ExecuteWithStmt(stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	return s.ExecuteWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) ExecuteWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) (sql.Result, error) {
+	manager, err := s.resolve(ctx, stmtIdOrUserQuery, v)
+	if err != nil {
+		return nil, err
+	}
+	return manager.ExecuteWithStmtContext(ctx, stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) QueryWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	return s.QueryWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) QueryWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryResult {
+	manager, err := s.resolve(ctx, stmtIdOrUserQuery, v)
+	if err != nil {
+		return newQueryResultError(err)
+	}
+	return manager.QueryWithStmtContext(ctx, stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) QueryRowWithStmt(stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
+	return s.QueryRowWithStmtContext(context.Background(), stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) QueryRowWithStmtContext(ctx context.Context, stmtIdOrUserQuery string, v ...interface{}) *QueryRowResult {
+	manager, err := s.resolve(ctx, stmtIdOrUserQuery, v)
+	if err != nil {
+		return newQueryRowResultError(err)
+	}
+	return manager.QueryRowWithStmtContext(ctx, stmtIdOrUserQuery, v...)
+}
+
+func (s *ShardedQueryMan) QueryFirst(stmtIdOrUserQuery string, dest interface{}, v ...interface{}) (bool, error) {
+	return s.QueryFirstContext(context.Background(), stmtIdOrUserQuery, dest, v...)
+}
+
+func (s *ShardedQueryMan) QueryFirstContext(ctx context.Context, stmtIdOrUserQuery string, dest interface{}, v ...interface{}) (bool, error) {
+	manager, err := s.resolve(ctx, stmtIdOrUserQuery, v)
+	if err != nil {
+		return false, err
+	}
+	return manager.QueryFirstContext(ctx, stmtIdOrUserQuery, dest, v...)
+}
+
+// PoolStats returns connection pool statistics by shard key
+func (s *ShardedQueryMan) PoolStats() map[string]sql.DBStats {
+	m := make(map[string]sql.DBStats)
+	for k, manager := range s.shards {
+		m[k] = manager.PoolStats()
+	}
+	return m
+}
+
+// Begin starts transaction bound to the shard of shardKey
+func (s *ShardedQueryMan) Begin(shardKey string) (*DBTransaction, error) {
+	manager, err := s.Shard(shardKey)
+	if err != nil {
+		return nil, err
+	}
+	return manager.Begin()
+}
+
+func (s *ShardedQueryMan) Close() error {
+	var lastErr error
+	for _, manager := range s.shards {
+		if err := manager.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
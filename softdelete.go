@@ -0,0 +1,77 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	softDeleteMarker        = "{softDeleteFilter}"
+	defaultSoftDeleteFilter = "deleted_at IS NULL"
+	alwaysTrueCondition     = "1 = 1"
+)
+
+// applySoftDelete replaces {softDeleteFilter} marker of query and if clauses with filter
+// when the statement is declared softDelete="true". otherwise the marker is always true condition
+func applySoftDelete(stmt *QueryStatement, filter string) error {
+	condition := alwaysTrueCondition
+	if stmt.softDelete {
+		condition = filter
+		if len(strings.TrimSpace(condition)) == 0 {
+			condition = defaultSoftDeleteFilter
+		}
+		if !hasSoftDeleteMarker(stmt.Query, stmt.clause) {
+			return fmt.Errorf("statement %s is softDelete but has no %s marker", stmt.Id, softDeleteMarker)
+		}
+	}
+
+	stmt.Query = strings.Replace(stmt.Query, softDeleteMarker, condition, -1)
+	stmt.clause = replaceSoftDeleteMarker(stmt.clause, condition)
+	return nil
+}
+
+func hasSoftDeleteMarker(query string, clause []IfClause) bool {
+	if strings.Contains(query, softDeleteMarker) {
+		return true
+	}
+	for _, c := range clause {
+		if hasSoftDeleteMarker(c.query, c.clause) {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceSoftDeleteMarker(clause []IfClause, condition string) []IfClause {
+	if len(clause) == 0 {
+		return clause
+	}
+
+	replaced := make([]IfClause, len(clause))
+	for i, c := range clause {
+		c.query = strings.Replace(c.query, softDeleteMarker, condition, -1)
+		c.clause = replaceSoftDeleteMarker(c.clause, condition)
+		replaced[i] = c
+	}
+	return replaced
+}
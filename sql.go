@@ -22,15 +22,33 @@ package queryman
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 )
 
-func execute(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (result sql.Result, err error) {
-	execStmt, err := refineConditional(stmt, v...)
+func execute(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (result sql.Result, err error) {
+	ctx, done := sqlProxy.trackRunning(withBindTimer(ctx), stmt.Id)
+	defer done()
+
+	if stmt.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stmt.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if err == nil && result != nil {
+			recordResultAffected(sqlProxy, stmt.Id, result)
+		}
+	}()
+
+	v = derefArgList(v)
+	execStmt, err := refineConditional(sqlProxy.getNormalizer(), stmt, v...)
 	if err != nil {
 		err = fmt.Errorf("fail to buld conditional query : %s", err.Error())
 		return
@@ -40,7 +58,12 @@ func execute(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (result s
 		if sqlProxy.debugEnabled() {
 			sqlProxy.debugPrint("%s", stmt.Debug())
 		}
-		return sqlProxy.exec(execStmt.Query)
+
+		start := time.Now()
+		defer func() {
+			sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
+		}()
+		return proxyExec(ctx, sqlProxy, stmt.Id, execStmt.Query)
 	}
 
 	defer func() {
@@ -50,6 +73,10 @@ func execute(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (result s
 		}
 	}()
 
+	if m, ok := findNamedParams(v); ok {
+		return execWithMap(ctx, sqlProxy, execStmt, m)
+	}
+
 	atype := reflect.TypeOf(v[0])
 	val := v[0]
 
@@ -70,55 +97,64 @@ func execute(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (result s
 	case reflect.Ptr:
 		return nil, ErrPtrIsNotSupported
 	case reflect.Slice, reflect.Array:
-		if !stmt.hasArrayBind() {
-			return execList(sqlProxy, val, execStmt)
+		// slice is the positional argument list unless it is the IN array of the first bind
+		if !stmt.firstArgsIsArray() && !stmt.firstArgsHasModifier() && !isBindConverted(v[0]) {
+			return execList(ctx, sqlProxy, val, execStmt)
 		}
 	case reflect.Struct:
-		if _, is := val.(driver.Valuer); !is {
-			return execWithObject(sqlProxy, execStmt, val)
+		if !isValueStruct(val) {
+			return execWithObject(ctx, sqlProxy, execStmt, val)
 		}
 	case reflect.Map:
-		return execMap(sqlProxy, val, execStmt)
+		return execMap(ctx, sqlProxy, val, execStmt)
 	}
 
-	return execWithList(sqlProxy, execStmt, v)
+	return execWithList(ctx, sqlProxy, execStmt, v)
 }
 
-func execList(sqlProxy SqlProxy, val interface{}, stmt QueryStatement) (sql.Result, error) {
+func execList(ctx context.Context, sqlProxy SqlProxy, val interface{}, stmt QueryStatement) (sql.Result, error) {
 	if slice, ok := val.([]interface{}); ok {
-		return execWithList(sqlProxy, stmt, slice)
+		return execWithList(ctx, sqlProxy, stmt, slice)
 	}
 	passing := flattenToList(val)
-	return execWithList(sqlProxy, stmt, passing)
+	return execWithList(ctx, sqlProxy, stmt, passing)
 }
 
-func execMap(sqlProxy SqlProxy, val interface{}, stmt QueryStatement) (sql.Result, error) {
+func execMap(ctx context.Context, sqlProxy SqlProxy, val interface{}, stmt QueryStatement) (sql.Result, error) {
 	if m, ok := val.(map[string]interface{}); ok {
-		return execWithMap(sqlProxy, stmt, m)
+		return execWithMap(ctx, sqlProxy, stmt, m)
 	}
 	passing := flattenToMap(val)
-	return execWithMap(sqlProxy, stmt, passing)
+	return execWithMap(ctx, sqlProxy, stmt, passing)
 }
 
-func execWithObject(sqlProxy SqlProxy, stmt QueryStatement, parameter interface{}) (sql.Result, error) {
+func execWithObject(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, parameter interface{}) (sql.Result, error) {
 	m := flattenStructToMap(parameter)
-	return execWithMap(sqlProxy, stmt, m)
+	return execWithMap(ctx, sqlProxy, stmt, m)
 }
 
-func execWithMap(sqlProxy SqlProxy, stmt QueryStatement, m map[string]interface{}) (sql.Result, error) {
-	effectiveQuery, param, bindErr := resolveColumnBindInMap(stmt, m)
+func execWithMap(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, m map[string]interface{}) (sql.Result, error) {
+	effectiveQuery, param, bindErr := resolveColumnBindInMap(sqlProxy, stmt, m)
 	if bindErr != nil {
 		return nil, bindErr.err
 	}
 
+	param, err := bindValues(sqlProxy, param)
+	if err != nil {
+		return nil, err
+	}
 	if sqlProxy.debugEnabled() {
-		sqlProxy.debugPrint("%s", stmt.Debug(param...))
+		sqlProxy.debugPrint("%s", stmt.Debug(maskParams(sqlProxy.getPreference(), stmt.Id, param)...))
 	}
 
-	return sqlProxy.exec(effectiveQuery, param...)
+	start := time.Now()
+	defer func() {
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
+	}()
+	return proxyExec(ctx, sqlProxy, stmt.Id, effectiveQuery, param...)
 }
 
-func execWithList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
+func execWithList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
 	atype := reflect.TypeOf(args[0])
 	val := args[0]
 
@@ -133,71 +169,90 @@ func execWithList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (s
 	}
 
 	if stmt.hasArrayBind() {
-		effectiveQuery, param, bindErr := resolveColumnBindInList(stmt, args)
+		if bindCountMismatch(sqlProxy.getPreference(), stmt, len(args)) {
+			return nil, fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", len(stmt.columnMention), len(args))
+		}
+		args = expandRepeatedBind(stmt, args)
+		effectiveQuery, param, bindErr := resolveColumnBindInList(sqlProxy.getNormalizer(), stmt, args)
 		if bindErr != nil {
 			return nil, bindErr
 		}
+		param, err := bindValues(sqlProxy, param)
+		if err != nil {
+			return nil, err
+		}
 
 		start := time.Now()
 		defer func() {
-			sqlProxy.recordExcution(stmt.Id, start)
+			sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
 		}()
 
-		return sqlProxy.exec(effectiveQuery, param...)
+		return proxyExec(ctx, sqlProxy, stmt.Id, effectiveQuery, param...)
 	}
 
-	// check nested list
+	// check nested list. slice of modified bind(e.g. json) is a single parameter
 	switch atype.Kind() {
 	case reflect.Slice:
-		return execWithNestedList(sqlProxy, stmt, args)
+		if !stmt.firstArgsHasModifier() && !isBindConverted(args[0]) {
+			return execWithNestedList(ctx, sqlProxy, stmt, args)
+		}
 	case reflect.Struct:
-		if _, is := val.(driver.Valuer); !is {
-			return execWithStructList(sqlProxy, stmt, args)
+		if !isValueStruct(val) {
+			return execWithStructList(ctx, sqlProxy, stmt, args)
 		}
 	case reflect.Map:
-		return execWithNestedMap(sqlProxy, stmt, args)
+		return execWithNestedMap(ctx, sqlProxy, stmt, args)
 	}
 
-	if len(stmt.columnMention) > len(args) {
+	if bindCountMismatch(sqlProxy.getPreference(), stmt, len(args)) {
 		return nil, fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", len(stmt.columnMention), len(args))
 	}
 
+	args, err := modifyBindValues(stmt, expandRepeatedBind(stmt, args))
+	if err != nil {
+		return nil, err
+	}
+	args, err = bindValues(sqlProxy, args)
+	if err != nil {
+		return nil, err
+	}
 	if sqlProxy.debugEnabled() {
-		sqlProxy.debugPrint("%s", stmt.Debug(args...))
+		sqlProxy.debugPrint("%s", stmt.Debug(maskParams(sqlProxy.getPreference(), stmt.Id, args)...))
 	}
 
 	start := time.Now()
 	defer func() {
-		sqlProxy.recordExcution(stmt.Id, start)
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
 	}()
-	return sqlProxy.exec(stmt.Query, args...)
+	return proxyExec(ctx, sqlProxy, stmt.Id, stmt.Query, args...)
 }
 
-func execWithNestedList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
-	executed, result, err := doExecWithNestedList(sqlProxy, stmt, args)
+func execWithNestedList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
+	executed, result, err := doExecWithNestedList(ctx, sqlProxy, stmt, args)
 	if err != nil && err == driver.ErrBadConn {
 		var nextResult ExecMultiResult
-		_, nextResult, err = doExecWithNestedList(sqlProxy, stmt, args[executed:])
+		_, nextResult, err = doExecWithNestedList(ctx, sqlProxy, stmt, args[executed:])
 		if err == nil {
 			result.idList = append(result.idList, nextResult.idList...)
-			result.rowAffected += nextResult.rowAffected
+			(&result).addRowsAffected(nextResult)
+			result.last = nextResult.last
 		}
 	}
-	return result, err
+	return unwrapSingleResult(sqlProxy, args, result), err
 }
 
-func doExecWithNestedList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
+func doExecWithNestedList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
 	// all data in the list should be 'slice' or 'array'
 	for i, v := range args {
 		if reflect.TypeOf(v).Kind() != reflect.Slice && reflect.TypeOf(v).Kind() != reflect.Array {
 			return 0, ExecMultiResult{}, fmt.Errorf("nested listing structure should have slice type data only. %d=%s", i, reflect.TypeOf(v).String())
 		}
-		if len(stmt.columnMention) > reflect.ValueOf(v).Len() {
+		if bindCountMismatch(sqlProxy.getPreference(), stmt, reflect.ValueOf(v).Len()) {
 			return 0, ExecMultiResult{}, fmt.Errorf("binding parameter count mismatch. defined=%d, args[%d]=%d", len(stmt.columnMention), i, reflect.ValueOf(v).Len())
 		}
 	}
 
-	pstmt, err := sqlProxy.prepare(stmt.Query)
+	pstmt, err := proxyPrepare(ctx, sqlProxy, stmt.Id, stmt.Query)
 	if err != nil {
 		return 0, ExecMultiResult{}, err
 	}
@@ -206,25 +261,27 @@ func doExecWithNestedList(sqlProxy SqlProxy, stmt QueryStatement, args []interfa
 	sqlProxy.debugPrint("[%s] %s", stmt.Id, stmt.Query)
 	result := ExecMultiResult{}
 	for i, v := range args {
-		passing := flattenToList(v)
-
-		if sqlProxy.debugEnabled() {
-			var buffer bytes.Buffer
-			buffer.WriteString(fmt.Sprintf("[%s] params : ", stmt.Id))
-			for _, v := range passing {
-				buffer.WriteString(fmt.Sprintf("[%v] ", v))
-			}
-			sqlProxy.debugPrint("%s", buffer.String())
+		passing, err := modifyBindValues(stmt, expandRepeatedBind(stmt, flattenToList(v)))
+		if err != nil {
+			return i, result, err
+		}
+		passing, err = bindValues(sqlProxy, passing)
+		if err != nil {
+			return i, result, err
 		}
 
+		debugRowParams(sqlProxy, stmt.Id, i, passing)
+
 		start := time.Now()
-		res, err := pstmt.Exec(passing...)
+		res, err := pstmt.ExecContext(ctx, passing...)
+		emitDebugEvent(sqlProxy, stmt.Id, stmt.Query, passing, start, res, err)
 		if err != nil {
-			return i, result, err
+			return i, result, classifyError(sqlProxy, err)
 		}
-		sqlProxy.recordExcution(stmt.Id, start)
-		affectedCount, _ := res.RowsAffected()
-		result.rowAffected += affectedCount
+		result.last = res
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
+		captureRowParams(ctx, passing)
+		(&result).addRowsAffected(res)
 
 		if stmt.eleType == eleTypeInsert {
 			id, err := res.LastInsertId()
@@ -238,20 +295,39 @@ func doExecWithNestedList(sqlProxy SqlProxy, stmt QueryStatement, args []interfa
 	return len(args), result, nil
 }
 
-func execWithNestedMap(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
-	executed, result, err := doExecWithNestedMap(sqlProxy, stmt, args)
+// debugRowParams prints parameters of i-th row of list execution. with BulkDebugSampleRate n,
+// only the first row of every n rows is printed
+func debugRowParams(sqlProxy SqlProxy, stmtId string, i int, params []interface{}) {
+	if !sqlProxy.debugEnabled() {
+		return
+	}
+	if rate := sqlProxy.getPreference().BulkDebugSampleRate; rate > 1 && i%rate != 0 {
+		return
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("[%s] params : ", stmtId))
+	for _, v := range maskParams(sqlProxy.getPreference(), stmtId, params) {
+		buffer.WriteString(fmt.Sprintf("[%v] ", v))
+	}
+	sqlProxy.debugPrint("%s", buffer.String())
+}
+
+func execWithNestedMap(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
+	executed, result, err := doExecWithNestedMap(ctx, sqlProxy, stmt, args)
 	if err != nil && err == driver.ErrBadConn {
 		var nextResult ExecMultiResult
-		_, nextResult, err = doExecWithNestedMap(sqlProxy, stmt, args[executed:])
+		_, nextResult, err = doExecWithNestedMap(ctx, sqlProxy, stmt, args[executed:])
 		if err == nil {
 			result.idList = append(result.idList, nextResult.idList...)
-			result.rowAffected += nextResult.rowAffected
+			(&result).addRowsAffected(nextResult)
+			result.last = nextResult.last
 		}
 	}
-	return result, err
+	return unwrapSingleResult(sqlProxy, args, result), err
 }
 
-func doExecWithNestedMap(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
+func doExecWithNestedMap(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
 	// all data in the list should be 'map'
 	for i, v := range args {
 		if reflect.TypeOf(v).Kind() != reflect.Map {
@@ -262,7 +338,7 @@ func doExecWithNestedMap(sqlProxy SqlProxy, stmt QueryStatement, args []interfac
 		}
 	}
 
-	pstmt, err := sqlProxy.prepare(stmt.Query)
+	pstmt, err := proxyPrepare(ctx, sqlProxy, stmt.Id, stmt.Query)
 	if err != nil {
 		return 0, ExecMultiResult{}, err
 	}
@@ -279,30 +355,36 @@ func doExecWithNestedMap(sqlProxy SqlProxy, stmt QueryStatement, args []interfac
 
 		param := make([]interface{}, 0)
 		for _, v2 := range stmt.columnMention {
-			found, ok := m[v2.Name()]
+			found, ok, err := findBindValue(sqlProxy.getPreference(), m, v2.Name())
+			if err != nil {
+				return i, result, err
+			}
 			if !ok {
 				return i, result, fmt.Errorf("not found \"%s\" from map", v)
 			}
+			found, err = modifyBindValue(v2, found)
+			if err != nil {
+				return i, result, err
+			}
 			param = append(param, found)
 		}
-
-		if sqlProxy.debugEnabled() {
-			var buffer bytes.Buffer
-			buffer.WriteString(fmt.Sprintf("[%s] params : ", stmt.Id))
-			for _, v := range param {
-				buffer.WriteString(fmt.Sprintf("[%v] ", v))
-			}
-			sqlProxy.debugPrint("%s", buffer.String())
+		param, err = bindValues(sqlProxy, param)
+		if err != nil {
+			return i, result, err
 		}
 
+		debugRowParams(sqlProxy, stmt.Id, i, param)
+
 		start := time.Now()
-		res, err := pstmt.Exec(param...)
+		res, err := pstmt.ExecContext(ctx, param...)
+		emitDebugEvent(sqlProxy, stmt.Id, stmt.Query, param, start, res, err)
 		if err != nil {
-			return i, result, err
+			return i, result, classifyError(sqlProxy, err)
 		}
-		sqlProxy.recordExcution(stmt.Id, start)
-		affectedCount, _ := res.RowsAffected()
-		result.rowAffected += affectedCount
+		result.last = res
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
+		captureRowParams(ctx, param)
+		(&result).addRowsAffected(res)
 
 		if stmt.eleType == eleTypeInsert {
 			id, err := res.LastInsertId()
@@ -316,21 +398,22 @@ func doExecWithNestedMap(sqlProxy SqlProxy, stmt QueryStatement, args []interfac
 	return len(args), result, nil
 }
 
-func execWithStructList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
-	executed, result, err := doExecWithStructList(sqlProxy, stmt, args)
+func execWithStructList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (sql.Result, error) {
+	executed, result, err := doExecWithStructList(ctx, sqlProxy, stmt, args)
 	if err != nil && err == driver.ErrBadConn {
 		var nextResult ExecMultiResult
-		_, nextResult, err = doExecWithStructList(sqlProxy, stmt, args[executed:])
+		_, nextResult, err = doExecWithStructList(ctx, sqlProxy, stmt, args[executed:])
 		if err == nil {
 			result.idList = append(result.idList, nextResult.idList...)
-			result.rowAffected += nextResult.rowAffected
+			(&result).addRowsAffected(nextResult)
+			result.last = nextResult.last
 		}
 	}
-	return result, err
+	return unwrapSingleResult(sqlProxy, args, result), err
 }
 
-func doExecWithStructList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
-	pstmt, err := sqlProxy.prepare(stmt.Query)
+func doExecWithStructList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) (int, ExecMultiResult, error) {
+	pstmt, err := proxyPrepare(ctx, sqlProxy, stmt.Id, stmt.Query)
 	if err != nil {
 		return 0, ExecMultiResult{}, err
 	}
@@ -355,30 +438,36 @@ func doExecWithStructList(sqlProxy SqlProxy, stmt QueryStatement, args []interfa
 		param := make([]interface{}, 0)
 
 		for _, v := range stmt.columnMention {
-			found, ok := m[v.Name()]
+			found, ok, err := findBindValue(sqlProxy.getPreference(), m, v.Name())
+			if err != nil {
+				return i, result, err
+			}
 			if !ok {
 				return i, result, fmt.Errorf("doExecWithStructList : not found \"%s\" from parameter values", v)
 			}
+			found, err = modifyBindValue(v, found)
+			if err != nil {
+				return i, result, err
+			}
 			param = append(param, found)
 		}
-
-		if sqlProxy.debugEnabled() {
-			var buffer bytes.Buffer
-			buffer.WriteString(fmt.Sprintf("[%s] params : ", stmt.Id))
-			for _, v := range param {
-				buffer.WriteString(fmt.Sprintf("[%v] ", v))
-			}
-			sqlProxy.debugPrint("%s", buffer.String())
+		param, err = bindValues(sqlProxy, param)
+		if err != nil {
+			return i, result, err
 		}
 
+		debugRowParams(sqlProxy, stmt.Id, i, param)
+
 		start := time.Now()
-		res, err := pstmt.Exec(param...)
+		res, err := pstmt.ExecContext(ctx, param...)
+		emitDebugEvent(sqlProxy, stmt.Id, stmt.Query, param, start, res, err)
 		if err != nil {
-			return i, result, err
+			return i, result, classifyError(sqlProxy, err)
 		}
-		sqlProxy.recordExcution(stmt.Id, start)
-		affectedCount, _ := res.RowsAffected()
-		result.rowAffected += affectedCount
+		result.last = res
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
+		captureRowParams(ctx, param)
+		(&result).addRowsAffected(res)
 
 		if stmt.eleType == eleTypeInsert {
 			id, err := res.LastInsertId()
@@ -392,6 +481,291 @@ func doExecWithStructList(sqlProxy SqlProxy, stmt QueryStatement, args []interfa
 	return len(args), result, nil
 }
 
+// unwrapSingleResult returns driver result of single element batch with UnwrapSingleBatch
+func unwrapSingleResult(sqlProxy SqlProxy, args []interface{}, result ExecMultiResult) sql.Result {
+	pref := sqlProxy.getPreference()
+	if pref != nil && pref.UnwrapSingleBatch && len(args) == 1 && result.last != nil {
+		return result.last
+	}
+	return result
+}
+
+// proxyExec executes resolved query after applying QueryRewriter. retried on deadlock with RetryOnDeadlock
+func proxyExec(ctx context.Context, sqlProxy SqlProxy, stmtId string, query string, args ...interface{}) (sql.Result, error) {
+	query, err := rewriteQuery(ctx, sqlProxy, stmtId, query)
+	if err != nil {
+		return nil, err
+	}
+	if capture := findExecCapture(ctx); capture != nil {
+		capture.query = query
+		capture.params = args
+	}
+
+	var result sql.Result
+	start := time.Now()
+	err = retryOnDeadlock(ctx, sqlProxy, stmtId, func() (execErr error) {
+		result, execErr = proxyExecOnce(ctx, sqlProxy, query, args...)
+		return execErr
+	})
+	emitDebugEvent(sqlProxy, stmtId, query, args, start, result, err)
+	return result, classifyError(sqlProxy, err)
+}
+
+func proxyExecOnce(ctx context.Context, sqlProxy SqlProxy, query string, args ...interface{}) (sql.Result, error) {
+	if pref := sqlProxy.getPreference(); pref == nil || !pref.AlwaysPrepare || len(args) == 0 {
+		return sqlProxy.exec(ctx, query, args...)
+	}
+
+	pstmt, err := sqlProxy.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer pstmt.Close()
+	return pstmt.ExecContext(ctx, args...)
+}
+
+// proxyQuery queries resolved query after applying QueryRewriter.
+// with AlwaysPrepare, returns prepared statement which should be closed after rows
+func proxyQuery(ctx context.Context, sqlProxy SqlProxy, stmtId string, query string, args ...interface{}) (*sql.Rows, *sql.Stmt, error) {
+	query, err := rewriteQuery(ctx, sqlProxy, stmtId, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	if pref := sqlProxy.getPreference(); pref == nil || !pref.AlwaysPrepare || len(args) == 0 {
+		rows, err := sqlProxy.query(ctx, query, args...)
+		emitDebugEvent(sqlProxy, stmtId, query, args, start, nil, err)
+		return rows, nil, classifyError(sqlProxy, err)
+	}
+
+	pstmt, err := sqlProxy.prepare(ctx, query)
+	if err != nil {
+		emitDebugEvent(sqlProxy, stmtId, query, args, start, nil, err)
+		return nil, nil, classifyError(sqlProxy, err)
+	}
+	rows, err := pstmt.QueryContext(ctx, args...)
+	emitDebugEvent(sqlProxy, stmtId, query, args, start, nil, err)
+	if err != nil {
+		pstmt.Close()
+		return nil, nil, classifyError(sqlProxy, err)
+	}
+	return rows, pstmt, nil
+}
+
+// proxyPrepare prepares resolved query after applying QueryRewriter
+func proxyPrepare(ctx context.Context, sqlProxy SqlProxy, stmtId string, query string) (*sql.Stmt, error) {
+	query, err := rewriteQuery(ctx, sqlProxy, stmtId, query)
+	if err != nil {
+		return nil, err
+	}
+	if capture := findExecCapture(ctx); capture != nil {
+		capture.query = query
+		capture.params = make([]interface{}, 0)
+	}
+	return sqlProxy.prepare(ctx, query)
+}
+
+type execCaptureKey struct{}
+
+// execCapture records the query and parameters actually passed to the driver.
+// for the prepared multi row execution, params has []interface{} of each executed row
+type execCapture struct {
+	query  string
+	params []interface{}
+}
+
+func withExecCapture(ctx context.Context) (context.Context, *execCapture) {
+	capture := &execCapture{}
+	return context.WithValue(ctx, execCaptureKey{}, capture), capture
+}
+
+func findExecCapture(ctx context.Context) *execCapture {
+	capture, _ := ctx.Value(execCaptureKey{}).(*execCapture)
+	return capture
+}
+
+func captureRowParams(ctx context.Context, params []interface{}) {
+	if capture := findExecCapture(ctx); capture != nil {
+		capture.params = append(capture.params, params)
+	}
+}
+
+func rewriteQuery(ctx context.Context, sqlProxy SqlProxy, stmtId string, query string) (string, error) {
+	query = explainQuery(ctx, query)
+	pref := sqlProxy.getPreference()
+	if pref == nil || pref.QueryRewriter == nil {
+		return query, nil
+	}
+
+	return pref.QueryRewriter(ctx, stmtId, query)
+}
+
+// recordResultAffected records affected rows of result for statistics
+func recordResultAffected(sqlProxy SqlProxy, stmtId string, result sql.Result) {
+	if pref := sqlProxy.getPreference(); pref == nil || !pref.CollectStats {
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected >= 0 {
+		sqlProxy.recordAffected(stmtId, affected)
+	}
+}
+
+// maxPlaceholders returns placeholder count of a statement accepted by the database.
+// MaxPlaceholders preference overrides the default of the driver
+func maxPlaceholders(sqlProxy SqlProxy) int {
+	pref := sqlProxy.getPreference()
+	if pref != nil && pref.MaxPlaceholders > 0 {
+		return pref.MaxPlaceholders
+	}
+	if normalizer := sqlProxy.getNormalizer(); normalizer != nil {
+		return normalizer.maxPlaceholders()
+	}
+	if pref != nil {
+		return driverMaxPlaceholders(pref.DriverName)
+	}
+	return mysqlMaxPlaceholders
+}
+
+// bindValues converts parameter values to the form passed to the driver.
+// parameters more than placeholder limit (e.g. large IN array) are ErrTooManyPlaceholders
+func bindValues(sqlProxy SqlProxy, params []interface{}) ([]interface{}, error) {
+	if limit := maxPlaceholders(sqlProxy); len(params) > limit {
+		return nil, fmt.Errorf("%w : %d > %d", ErrTooManyPlaceholders, len(params), limit)
+	}
+
+	pref := sqlProxy.getPreference()
+	stringerAsValue := pref != nil && pref.StringerAsValue
+	if !stringerAsValue && !hasBindConverter() {
+		return params, nil
+	}
+
+	converted := make([]interface{}, len(params))
+	for i, v := range params {
+		c, err := bindValue(stringerAsValue, v)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+func bindValue(stringerAsValue bool, v interface{}) (interface{}, error) {
+	if v == nil {
+		return v, nil
+	}
+
+	if fn, ok := findBindConverter(reflect.TypeOf(v)); ok {
+		converted, err := fn(v)
+		if err != nil {
+			return nil, fmt.Errorf("fail to convert %T parameter : %s", v, err.Error())
+		}
+		return converted, nil
+	}
+
+	switch v.(type) {
+	case driver.Valuer, time.Time:
+		return v, nil
+	}
+
+	if stringerAsValue {
+		if stringer, ok := v.(fmt.Stringer); ok {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Ptr && rv.IsNil() {
+				return nil, nil
+			}
+			return stringer.String(), nil
+		}
+	}
+
+	return v, nil
+}
+
+// bindCountMismatch reports whether positional parameter count does not fit column binds.
+// fewer parameters are always mismatch and extra parameters too with StrictArity
+func bindCountMismatch(pref *QuerymanPreference, stmt QueryStatement, count int) bool {
+	defined := len(stmt.columnMention)
+	if count != defined && count == len(distinctBindIndex(stmt)) {
+		// repeated bind names are given once
+		return false
+	}
+	if defined > count {
+		return true
+	}
+	return pref != nil && pref.StrictArity && defined != count
+}
+
+// distinctBindIndex returns bind name -> order of its first appearance
+func distinctBindIndex(stmt QueryStatement) map[string]int {
+	index := make(map[string]int)
+	for _, v := range stmt.columnMention {
+		if _, ok := index[v.name]; !ok {
+			index[v.name] = len(index)
+		}
+	}
+	return index
+}
+
+// expandRepeatedBind expands positional args given once per distinct bind name into args per bind.
+// e.g) WHERE name={Name} <if key="Alias">OR alias={Name}</if> accepts single Name
+func expandRepeatedBind(stmt QueryStatement, args []interface{}) []interface{} {
+	if len(args) >= len(stmt.columnMention) {
+		return args
+	}
+
+	index := distinctBindIndex(stmt)
+	if len(args) != len(index) {
+		return args
+	}
+
+	expanded := make([]interface{}, len(stmt.columnMention))
+	for i, v := range stmt.columnMention {
+		expanded[i] = args[index[v.name]]
+	}
+	return expanded
+}
+
+// isValueStruct reports whether struct v is a single bind value which should not be flattened
+func isValueStruct(v interface{}) bool {
+	switch v.(type) {
+	case driver.Valuer, time.Time:
+		return true
+	}
+	return isBindConverted(v)
+}
+
+// isBindConverted reports whether v is a single bind value converted by registered bind converter
+func isBindConverted(v interface{}) bool {
+	if !hasBindConverter() {
+		return false
+	}
+	_, ok := findBindConverter(reflect.TypeOf(v))
+	return ok
+}
+
+// derefArgList replaces *[]interface{} argument with the list it points
+// so that the argument works exactly same as []interface{}. v is not modified
+func derefArgList(v []interface{}) []interface{} {
+	var derefed []interface{}
+	for i, arg := range v {
+		list, ok := arg.(*[]interface{})
+		if !ok || list == nil {
+			continue
+		}
+		if derefed == nil {
+			derefed = make([]interface{}, len(v))
+			copy(derefed, v)
+		}
+		derefed[i] = *list
+	}
+	if derefed == nil {
+		return v
+	}
+	return derefed
+}
+
 func flattenToList(v interface{}) []interface{} {
 	s := reflect.ValueOf(v)
 	passing := make([]interface{}, s.Len())
@@ -423,28 +797,92 @@ func flattenStructToMap(s interface{}) map[string]interface{} {
 		f := t.Field(i)
 		fv := v.FieldByName(f.Name)
 		if fv.CanInterface() {
-			m[f.Name] = fv.Interface()
+			m[f.Name] = fieldBindValue(fv)
 		}
 	}
 
 	return m
 }
 
-func queryMultiRow(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (queryedRow *QueryResult) {
-	execStmt, err := refineConditional(stmt, v...)
+// fieldBindValue returns nil for nil pointer field so that driver sees NULL consistently,
+// and dereferenced value for non nil pointer field of basic kind or time.Time. pointer of other types
+// (driver.Valuer or custom type of driver NamedValueChecker. e.g. geometry) reaches the driver as it is
+func fieldBindValue(fv reflect.Value) interface{} {
+	if fv.Kind() != reflect.Ptr {
+		return fv.Interface()
+	}
+
+	if fv.IsNil() {
+		return nil
+	}
+
+	if _, ok := fv.Interface().(driver.Valuer); ok {
+		return fv.Interface()
+	}
+	if !isDerefBindType(fv.Type().Elem()) {
+		return fv.Interface()
+	}
+	return fv.Elem().Interface()
+}
+
+// isDerefBindType reports whether pointer of t is bound as its value
+func isDerefBindType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Slice, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return true
+		}
+	}
+
+	_, ok := findBindConverter(t)
+	return ok
+}
+
+func queryMultiRow(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (queryedRow *QueryResult) {
+	ctx, cancel := sqlProxy.trackRunning(withBindTimer(ctx), stmt.Id)
+	if stmt.timeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, stmt.timeout)
+		done := cancel
+		cancel = func() {
+			timeoutCancel()
+			done()
+		}
+	}
+	// rows are read after return. cancel when result is closed
+	defer func() {
+		if queryedRow.err != nil {
+			cancel()
+			return
+		}
+		queryedRow.cancel = cancel
+		if pref := sqlProxy.getPreference(); pref != nil {
+			queryedRow.strictColumns = pref.StrictColumns
+			queryedRow.stripQualifier = pref.StripColumnQualifier
+			queryedRow.maxScanRows = pref.MaxScanRows
+		}
+	}()
+
+	v = derefArgList(v)
+	execStmt, err := refineConditional(sqlProxy.getNormalizer(), stmt, v...)
 	if err != nil {
 		return newQueryResultError(fmt.Errorf("fail to buld conditional query : %s", err.Error()))
 	}
 
 	if len(v) == 0 {
-		rows, err := sqlProxy.query(execStmt.Query)
+		rows, pstmt, err := proxyQuery(ctx, sqlProxy, stmt.Id, execStmt.Query)
 		if sqlProxy.debugEnabled() {
 			sqlProxy.debugPrint("%s", stmt.Debug())
 		}
 		if err != nil {
 			return newQueryResultError(err)
 		}
-		return newQueryResult(nil, rows)
+		return newQueryResult(pstmt, rows)
 	}
 
 	defer func() {
@@ -453,6 +891,10 @@ func queryMultiRow(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (qu
 		}
 	}()
 
+	if m, ok := findNamedParams(v); ok {
+		return queryWithMap(ctx, sqlProxy, execStmt, m)
+	}
+
 	atype := reflect.TypeOf(v[0])
 	val := v[0]
 
@@ -471,27 +913,54 @@ func queryMultiRow(sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) (qu
 	case reflect.Ptr:
 		return newQueryResultError(ErrPtrIsNotSupported)
 	case reflect.Slice, reflect.Array:
-		if !stmt.firstArgsIsArray() {
-			return queryList(sqlProxy, val, execStmt)
+		if !stmt.firstArgsIsArray() && !stmt.firstArgsHasModifier() && !isBindConverted(v[0]) {
+			return queryList(ctx, sqlProxy, val, execStmt)
 		}
 	case reflect.Struct:
-		if _, is := val.(driver.Valuer); !is {
-			return queryWithObject(sqlProxy, stmt, val)
+		if !isValueStruct(val) {
+			return queryWithObject(ctx, sqlProxy, execStmt, val)
 		}
 	case reflect.Map:
-		return queryMap(sqlProxy, val, execStmt)
+		return queryMap(ctx, sqlProxy, val, execStmt)
+	}
+
+	return queryWithList(ctx, sqlProxy, execStmt, v)
+}
+
+// querySingleRow queries stmt and hands over rows to QueryRowResult
+func querySingleRow(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, v ...interface{}) *QueryRowResult {
+	var queryRowResult *QueryRowResult
+	queryResult := queryMultiRow(ctx, sqlProxy, stmt, v...)
+	if queryResult.err != nil {
+		queryResult.Close()
+		queryRowResult = newQueryRowResultError(queryResult.err)
+	} else {
+		queryRowResult = newQueryRowResult(queryResult.pstmt, queryResult.GetRows())
+		queryRowResult.cancel = queryResult.cancel
+		queryRowResult.strictColumns = queryResult.strictColumns
+		queryRowResult.stripQualifier = queryResult.stripQualifier
 	}
 
-	return queryWithList(sqlProxy, execStmt, v)
+	queryResult.pstmt = nil
+	queryResult.rows = nil
+	queryResult.cancel = nil
+	return queryRowResult
 }
 
-func refineConditional(stmt QueryStatement, v ...interface{}) (QueryStatement, error) {
+func refineConditional(normalizer QueryNormalizer, stmt QueryStatement, v ...interface{}) (QueryStatement, error) {
 	if !stmt.HasCondition() {
+		if normalizer != queryNormalizer {
+			stmt.Query = normalizer.resolveHolding(stmt.HoldedQuery)
+		}
 		return stmt, nil
 	}
 
 	if len(v) == 0 {
-		return stmt.RefineStatement(nil)
+		return stmt.refine(normalizer, nil)
+	}
+
+	if m, ok := findNamedParams(v); ok {
+		return stmt.refine(normalizer, m)
 	}
 
 	atype := reflect.TypeOf(v[0])
@@ -509,76 +978,96 @@ func refineConditional(stmt QueryStatement, v ...interface{}) (QueryStatement, e
 	switch atype.Kind() {
 	case reflect.Map:
 		if m, ok := val.(map[string]interface{}); ok {
-			return stmt.RefineStatement(m)
+			return stmt.refine(normalizer, m)
 		}
 		passing := flattenToMap(val)
-		return stmt.RefineStatement(passing)
+		return stmt.refine(normalizer, passing)
 	default:
-		return stmt.RefineStatement(nil)
+		return stmt.refine(normalizer, nil)
 	}
 }
 
-func queryList(sqlProxy SqlProxy, val interface{}, stmt QueryStatement) *QueryResult {
+func queryList(ctx context.Context, sqlProxy SqlProxy, val interface{}, stmt QueryStatement) *QueryResult {
 	if slice, ok := val.([]interface{}); ok {
-		return queryWithList(sqlProxy, stmt, slice)
+		return queryWithList(ctx, sqlProxy, stmt, slice)
 	}
 	passing := flattenToList(val)
-	return queryWithList(sqlProxy, stmt, passing)
+	return queryWithList(ctx, sqlProxy, stmt, passing)
 }
 
-func queryWithList(sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) *QueryResult {
+func queryWithList(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, args []interface{}) *QueryResult {
 	atype := reflect.TypeOf(args[0])
+	val := args[0]
 
 	// reform ptr
 	if atype.Kind() == reflect.Ptr {
 		atype = atype.Elem()
+		if !reflect.ValueOf(val).IsNil() {
+			val = reflect.ValueOf(val).Elem().Interface()
+		}
 	}
 
 	// check nested list
 	switch atype.Kind() {
-	case reflect.Slice, reflect.Struct, reflect.Map:
-		if !stmt.firstArgsIsArray() {
+	case reflect.Struct:
+		if !stmt.firstArgsIsArray() && !isValueStruct(val) {
+			return newQueryResultError(fmt.Errorf("unacceptable parameter type in list. kind=%s", atype.Kind().String()))
+		}
+	case reflect.Slice, reflect.Map:
+		if !stmt.firstArgsIsArray() && !stmt.firstArgsHasModifier() && !isBindConverted(args[0]) {
 			return newQueryResultError(fmt.Errorf("unacceptable parameter type in list. kind=%s", atype.Kind().String()))
 		}
 	}
 
-	if len(stmt.columnMention) > len(args) {
+	if bindCountMismatch(sqlProxy.getPreference(), stmt, len(args)) {
 		return newQueryResultError(fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", len(stmt.columnMention), len(args)))
 	}
 
-	effectiveQuery, param, bindErr := resolveColumnBindInList(stmt, args)
+	effectiveQuery, param, bindErr := resolveColumnBindInList(sqlProxy.getNormalizer(), stmt, args)
 	if bindErr != nil {
 		return newQueryResultError(bindErr)
 	}
+	param, err := bindValues(sqlProxy, param)
+	if err != nil {
+		return newQueryResultError(err)
+	}
 
 	start := time.Now()
 	defer func() {
-		sqlProxy.recordExcution(stmt.Id, start)
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
 	}()
 
-	rows, err := sqlProxy.query(effectiveQuery, param...)
+	rows, pstmt, err := proxyQuery(ctx, sqlProxy, stmt.Id, effectiveQuery, param...)
 	if sqlProxy.debugEnabled() {
-		sqlProxy.debugPrint("%s", stmt.Debug(param...))
+		sqlProxy.debugPrint("%s", stmt.Debug(maskParams(sqlProxy.getPreference(), stmt.Id, param)...))
 	}
 	if err != nil {
 		return newQueryResultError(err)
 	}
-	return newQueryResult(nil, rows)
+	return newQueryResult(pstmt, rows)
 }
 
-func queryWithObject(sqlProxy SqlProxy, stmt QueryStatement, parameter interface{}) *QueryResult {
+func queryWithObject(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, parameter interface{}) *QueryResult {
 	m := flattenStructToMap(parameter)
-	return queryWithMap(sqlProxy, stmt, m)
+	return queryWithMap(ctx, sqlProxy, stmt, m)
 }
 
-func resolveColumnBindInMap(stmt QueryStatement, m map[string]interface{}) (string, []interface{}, *QueryResult) {
+func resolveColumnBindInMap(sqlProxy SqlProxy, stmt QueryStatement, m map[string]interface{}) (string, []interface{}, *QueryResult) {
+	pref := sqlProxy.getPreference()
 	param := make([]interface{}, 0)
 	if !stmt.hasArrayBind() {
 		for _, v := range stmt.columnMention {
-			found, ok := m[v.Name()]
+			found, ok, err := findBindValue(pref, m, v.Name())
+			if err != nil {
+				return stmt.Query, param, newQueryResultError(err)
+			}
 			if !ok {
 				return stmt.Query, param, newQueryResultError(fmt.Errorf("queryWithMap : not found \"%s\" from parameter values", v))
 			}
+			found, err = modifyBindValue(v, found)
+			if err != nil {
+				return stmt.Query, param, newQueryResultError(err)
+			}
 			param = append(param, found)
 		}
 		return stmt.Query, param, nil
@@ -588,33 +1077,38 @@ func resolveColumnBindInMap(stmt QueryStatement, m map[string]interface{}) (stri
 	effectiveQuery := clone.Query
 	holdedQuery := clone.HoldedQuery
 
-	touch := false
+	expansions := make([]arrayExpansion, 0)
 	for _, v := range clone.columnMention {
-		found := m[v.Name()]
+		found, _, err := findBindValue(pref, m, v.Name())
+		if err != nil {
+			return effectiveQuery, param, newQueryResultError(err)
+		}
 		if v.bindType == columnBindTypeNormal {
+			found, err = modifyBindValue(v, found)
+			if err != nil {
+				return effectiveQuery, param, newQueryResultError(err)
+			}
 			param = append(param, found)
 			continue
 		}
 
 		if v.bindType == columnBindTypeArray {
 			arr, cnt := flattenArray(found)
-			param = append(param, arr...)
-			if cnt > 1 {
-				if touch {
-					return effectiveQuery,
-						param,
-						newQueryResultError(fmt.Errorf("this version only support 1 IN array binding"))
-				}
-				holdedQuery = reformHoldQuery(holdedQuery, v, cnt)
-				touch = true
+			if len(arr) == 0 {
+				return effectiveQuery, param, newQueryResultError(fmt.Errorf("IN array bind %s has no element", v.Name()))
 			}
+			if err = checkBindElements(v, arr); err != nil {
+				return effectiveQuery, param, newQueryResultError(err)
+			}
+			param = append(param, arr...)
+			expansions = append(expansions, arrayExpansion{bind: v, count: cnt})
 			continue
 		}
 		param = append(param, found)
 	}
 
-	if touch {
-		effectiveQuery = queryNormalizer.resolveHolding(holdedQuery)
+	if len(expansions) > 0 {
+		effectiveQuery = sqlProxy.getNormalizer().resolveHolding(expandArrayHolds(holdedQuery, expansions))
 	}
 	return effectiveQuery, param, nil
 
@@ -630,9 +1124,96 @@ func resolveColumnBindInMap(stmt QueryStatement, m map[string]interface{}) (stri
 	//return effectiveQuery, param, nil
 }
 
-func resolveColumnBindInList(stmt QueryStatement, args []interface{}) (string, []interface{}, error) {
+// findBindValue looks up bind name in m. when exact key is not found and MapKeyCaseInsensitive is set,
+// looks up key case insensitively. two or more keys differing only by case is ambiguous
+func findBindValue(pref *QuerymanPreference, m map[string]interface{}, name string) (interface{}, bool, error) {
+	if found, ok := m[name]; ok {
+		return found, true, nil
+	}
+
+	if index := strings.Index(name, "."); index > 0 {
+		return findNestedBindValue(pref, m, name[:index], name[index+1:])
+	}
+
+	if pref == nil || !pref.MapKeyCaseInsensitive {
+		return nil, false, nil
+	}
+
+	return findBindValueFold(m, name)
+}
+
+// findNestedBindValue resolves dotted bind name. e.g) {address.city} -> Address.City of struct parameter.
+// each name is matched exactly first and then case insensitively
+func findNestedBindValue(pref *QuerymanPreference, m map[string]interface{}, head string, path string) (interface{}, bool, error) {
+	found, ok, err := findBindValue(pref, m, head)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		found, ok, err = findBindValueFold(m, head)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	if found == nil {
+		return nil, true, nil
+	}
+
+	val := reflect.ValueOf(found)
+	for _, name := range strings.Split(path, ".") {
+		for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+			if val.IsNil() {
+				// nil parent binds NULL
+				return nil, true, nil
+			}
+			val = val.Elem()
+		}
+
+		switch val.Kind() {
+		case reflect.Struct:
+			field, ok := findStructField(val.Type(), name)
+			if !ok || len(field.PkgPath) > 0 {
+				return nil, false, nil
+			}
+			val = val.FieldByIndex(field.Index)
+		case reflect.Map:
+			if val.Type().Key().Kind() != reflect.String {
+				return nil, false, nil
+			}
+			val = val.MapIndex(reflect.ValueOf(name).Convert(val.Type().Key()))
+			if !val.IsValid() {
+				return nil, false, nil
+			}
+		default:
+			return nil, false, nil
+		}
+	}
+
+	return fieldBindValue(val), true, nil
+}
+
+func findBindValueFold(m map[string]interface{}, name string) (interface{}, bool, error) {
+	var found interface{}
+	matched := ""
+	for k, v := range m {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if len(matched) > 0 {
+			return nil, false, fmt.Errorf("ambiguous map key \"%s\" and \"%s\" for \"%s\"", matched, k, name)
+		}
+		matched = k
+		found = v
+	}
+
+	return found, len(matched) > 0, nil
+}
+
+func resolveColumnBindInList(normalizer QueryNormalizer, stmt QueryStatement, args []interface{}) (string, []interface{}, error) {
+	args = expandRepeatedBind(stmt, args)
 	if !stmt.hasArrayBind() {
-		return stmt.Query, args, nil
+		modified, err := modifyBindValues(stmt, args)
+		return stmt.Query, modified, err
 	}
 
 	clone := stmt.clone()
@@ -644,37 +1225,58 @@ func resolveColumnBindInList(stmt QueryStatement, args []interface{}) (string, [
 		return effectiveQuery, param, fmt.Errorf("binding parameter count mismatch. defined=%d, args=%d", len(stmt.columnMention), len(args))
 	}
 
-	touch := false
+	// each column bind consumes exactly one positional parameter in order.
+	// the parameter of IN array bind is a slice (or array) expanded to its elements and a scalar is one element
+	expansions := make([]arrayExpansion, 0)
 	for i, v := range clone.columnMention {
 		found := args[i]
 		if v.bindType == columnBindTypeNormal {
+			found, err := modifyBindValue(v, found)
+			if err != nil {
+				return effectiveQuery, param, err
+			}
 			param = append(param, found)
 			continue
 		}
 
 		if v.bindType == columnBindTypeArray {
 			arr, cnt := flattenArray(found)
-			param = append(param, arr...)
-			if cnt > 1 {
-				if touch {
-					return effectiveQuery,
-						param,
-						fmt.Errorf("this version only support 1 IN array binding")
-				}
-				holdedQuery = reformHoldQuery(holdedQuery, v, cnt)
-				touch = true
+			if len(arr) == 0 {
+				return effectiveQuery, param, fmt.Errorf("IN array bind %s (parameter %d) has no element", v.Name(), i+1)
 			}
+			if err := checkBindElements(v, arr); err != nil {
+				return effectiveQuery, param, err
+			}
+			param = append(param, arr...)
+			expansions = append(expansions, arrayExpansion{bind: v, count: cnt})
 			continue
 		}
 		param = append(param, found)
 	}
 
-	if touch {
-		effectiveQuery = queryNormalizer.resolveHolding(holdedQuery)
+	if len(expansions) > 0 {
+		effectiveQuery = normalizer.resolveHolding(expandArrayHolds(holdedQuery, expansions))
 	}
 	return effectiveQuery, param, nil
 }
 
+// arrayExpansion is the element count of IN array bind
+type arrayExpansion struct {
+	bind  ColumnBind
+	count int
+}
+
+// expandArrayHolds repeats hold of each IN array bind by its element count.
+// expanded from the last bind so that hold positions of earlier binds are kept
+func expandArrayHolds(holdedQuery string, expansions []arrayExpansion) string {
+	for i := len(expansions) - 1; i >= 0; i-- {
+		if expansions[i].count > 1 {
+			holdedQuery = reformHoldQuery(holdedQuery, expansions[i].bind, expansions[i].count)
+		}
+	}
+	return holdedQuery
+}
+
 func reformHoldQuery(holdQuery string, columnBind ColumnBind, cnt int) string {
 	prefix := holdQuery[:columnBind.holdPos-1]
 	suffix := holdQuery[columnBind.holdPos:]
@@ -722,48 +1324,48 @@ func flattenArray(v interface{}) ([]interface{}, int) {
 	}
 
 	if slice, ok := val.([]interface{}); ok {
-		varCnt = 0
-		for i, item := range slice {
-			param = append(param, item)
-			varCnt = i
-		}
-		return param, varCnt
+		param = append(param, slice...)
+		return param, len(slice)
 	}
 
-	s := reflect.ValueOf(v)
+	// each element (e.g. driver.Valuer of []MyEnum) is one placeholder and never flattened further
+	s := reflect.ValueOf(val)
 	for i := 0; i < s.Len(); i++ {
 		param = append(param, s.Index(i).Interface())
-		varCnt++
 	}
 
 	return param, s.Len()
 }
 
-func queryWithMap(sqlProxy SqlProxy, stmt QueryStatement, m map[string]interface{}) *QueryResult {
-	effectiveQuery, param, bindErr := resolveColumnBindInMap(stmt, m)
+func queryWithMap(ctx context.Context, sqlProxy SqlProxy, stmt QueryStatement, m map[string]interface{}) *QueryResult {
+	effectiveQuery, param, bindErr := resolveColumnBindInMap(sqlProxy, stmt, m)
 	if bindErr != nil {
 		return bindErr
 	}
+	param, err := bindValues(sqlProxy, param)
+	if err != nil {
+		return newQueryResultError(err)
+	}
 
 	start := time.Now()
 	defer func() {
-		sqlProxy.recordExcution(stmt.Id, start)
+		sqlProxy.recordExcution(stmt.Id, bindElapsed(ctx, start), start)
 	}()
 
-	rows, err := sqlProxy.query(effectiveQuery, param...)
+	rows, pstmt, err := proxyQuery(ctx, sqlProxy, stmt.Id, effectiveQuery, param...)
 	if sqlProxy.debugEnabled() {
-		sqlProxy.debugPrint("%s", stmt.Debug(param...))
+		sqlProxy.debugPrint("%s", stmt.Debug(maskParams(sqlProxy.getPreference(), stmt.Id, param)...))
 	}
 	if err != nil {
 		return newQueryResultError(err)
 	}
-	return newQueryResult(nil, rows)
+	return newQueryResult(pstmt, rows)
 }
 
-func queryMap(sqlProxy SqlProxy, val interface{}, stmt QueryStatement) *QueryResult {
+func queryMap(ctx context.Context, sqlProxy SqlProxy, val interface{}, stmt QueryStatement) *QueryResult {
 	if m, ok := val.(map[string]interface{}); ok {
-		return queryWithMap(sqlProxy, stmt, m)
+		return queryWithMap(ctx, sqlProxy, stmt, m)
 	}
 	passing := flattenToMap(val)
-	return queryWithMap(sqlProxy, stmt, passing)
+	return queryWithMap(ctx, sqlProxy, stmt, passing)
 }
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 github.com/fatima-go
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @project fatima-core
+ * @author jin
+ * @date 23. 4. 14. 오후 6:09
+ */
+
+package queryman
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// StatementStats is cumulative execution statistics of a statement
+type StatementStats struct {
+	Executions       int64
+	TotalElapsed     time.Duration // database time of driver calls
+	MaxElapsed       time.Duration
+	TotalBindElapsed time.Duration // queryman time resolving query and parameters before driver calls
+	RowsAffected     int64
+}
+
+type statsCollector struct {
+	mutex sync.Mutex
+	stats map[string]*StatementStats
+}
+
+func newStatsCollector() *statsCollector {
+	c := &statsCollector{}
+	c.stats = make(map[string]*StatementStats)
+	return c
+}
+
+func (c *statsCollector) get(stmtId string) *StatementStats {
+	s, ok := c.stats[stmtId]
+	if !ok {
+		s = &StatementStats{}
+		c.stats[stmtId] = s
+	}
+	return s
+}
+
+func (c *statsCollector) recordElapsed(stmtId string, elapsed time.Duration, bind time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s := c.get(stmtId)
+	s.Executions++
+	s.TotalElapsed += elapsed
+	s.TotalBindElapsed += bind
+	if elapsed > s.MaxElapsed {
+		s.MaxElapsed = elapsed
+	}
+}
+
+func (c *statsCollector) recordAffected(stmtId string, affected int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s := c.get(stmtId)
+	s.RowsAffected = addAffected(s.RowsAffected, affected)
+}
+
+func (c *statsCollector) snapshot() map[string]StatementStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	m := make(map[string]StatementStats)
+	for k, v := range c.stats {
+		m[k] = *v
+	}
+	return m
+}
+
+func (c *statsCollector) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats = make(map[string]*StatementStats)
+}
+
+// addAffected adds affected row counts. the sum saturates at math.MaxInt64 instead of overflow
+func addAffected(total int64, affected int64) int64 {
+	if affected > 0 && total > math.MaxInt64-affected {
+		return math.MaxInt64
+	}
+	return total + affected
+}
+
+// bindTimer marks where resolution of the next driver call of an execution began
+type bindTimer struct {
+	mark time.Time
+}
+
+type bindTimerKey struct{}
+
+// withBindTimer starts measuring bind resolution (if clauses, parameter binding and reflection) of an execution
+func withBindTimer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bindTimerKey{}, &bindTimer{mark: time.Now()})
+}
+
+// bindElapsed returns time from the mark to start of the driver call and marks now for the next call
+// of the same execution (e.g. next row of list execution)
+func bindElapsed(ctx context.Context, start time.Time) time.Duration {
+	timer, ok := ctx.Value(bindTimerKey{}).(*bindTimer)
+	if !ok {
+		return 0
+	}
+	elapsed := start.Sub(timer.mark)
+	timer.mark = time.Now()
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
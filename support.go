@@ -24,8 +24,11 @@ import (
 	"bytes"
 	"database/sql"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
@@ -43,8 +46,17 @@ const (
 
 type fieldNameConvertMethod uint8
 
+// FieldNameConverter maps result column name to struct field name and struct field name to column name.
+// set QuerymanPreference.FieldNameConverter to override default snake_case <-> CamelCase mapping.
+// ColumnToField receives the column name as the driver returns it (not case folded)
+type FieldNameConverter interface {
+	ColumnToField(column string) string
+	FieldToColumn(field string) string
+}
+
 type FieldNameConvertStrategy interface {
 	convertFieldName(name string) string
+	convertColumnName(name string) string
 }
 
 type UnderstoreConvertStrategy struct {
@@ -52,6 +64,10 @@ type UnderstoreConvertStrategy struct {
 
 func (u UnderstoreConvertStrategy) convertFieldName(name string) string {
 	// TODO
+	return strings.ToLower(name)
+}
+
+func (u UnderstoreConvertStrategy) convertColumnName(name string) string {
 	return name
 }
 
@@ -61,7 +77,7 @@ type CamelConvertStrategy struct {
 func (u CamelConvertStrategy) convertFieldName(name string) string {
 	var buffer bytes.Buffer
 	needUpper := true
-	for _, c := range name {
+	for _, c := range strings.ToLower(name) {
 		if needUpper {
 			buffer.WriteRune(unicode.ToUpper(c))
 			needUpper = false
@@ -79,6 +95,51 @@ func (u CamelConvertStrategy) convertFieldName(name string) string {
 	return buffer.String()
 }
 
+func (u CamelConvertStrategy) convertColumnName(name string) string {
+	return toSnakeCase(name)
+}
+
+// toSnakeCase converts field name to column name. e.g) CreateTime -> create_time
+func toSnakeCase(name string) string {
+	var buffer bytes.Buffer
+	runes := []rune(name)
+	for i, c := range runes {
+		if unicode.IsUpper(c) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				buffer.WriteByte('_')
+			}
+			buffer.WriteRune(unicode.ToLower(c))
+			continue
+		}
+		buffer.WriteRune(c)
+	}
+	return buffer.String()
+}
+
+// SnakeCaseConverter is the default FieldNameConverter. e.g) user_name <-> UserName, user_id <-> UserID
+type SnakeCaseConverter struct {
+}
+
+func (c SnakeCaseConverter) ColumnToField(column string) string {
+	return CamelConvertStrategy{}.convertFieldName(column)
+}
+
+func (c SnakeCaseConverter) FieldToColumn(field string) string {
+	return toSnakeCase(field)
+}
+
+type userConvertStrategy struct {
+	converter FieldNameConverter
+}
+
+func (u userConvertStrategy) convertFieldName(name string) string {
+	return u.converter.ColumnToField(name)
+}
+
+func (u userConvertStrategy) convertColumnName(name string) string {
+	return u.converter.FieldToColumn(name)
+}
+
 /*
 MySQL               PostgreSQL            Oracle
 =====               ==========            ======
@@ -97,10 +158,39 @@ func newNormalizer(driverName string) QueryNormalizer {
 	default:
 		normalizer.strategy = &MysqlPlaceholderStrategy{}
 	}
+	normalizer.dialect = dialectOf(driverName)
+	normalizer.placeholderLimit = driverMaxPlaceholders(driverName)
 
 	return normalizer
 }
 
+const (
+	dialectMysql      = "mysql"
+	dialectPostgresql = "postgresql"
+	dialectOracle     = "oracle"
+)
+
+// dialectDriverName returns driver name of the dialect for normalizer
+func dialectDriverName(dialect string) string {
+	if dialect == dialectOracle {
+		return "oci8"
+	}
+	return dialect
+}
+
+// dialectOf returns dialect of driver name or dialect attribute. empty for unknown name
+func dialectOf(name string) string {
+	switch strings.ToLower(name) {
+	case "mysql":
+		return dialectMysql
+	case "postgres", "postgresql", "pgx":
+		return dialectPostgresql
+	case "oci8", "oracle":
+		return dialectOracle
+	}
+	return ""
+}
+
 type SqlVariablePlaceholderStrategy interface {
 	getNextMark() string
 	clone() SqlVariablePlaceholderStrategy
@@ -129,7 +219,7 @@ func (p *PostgreSQLPlaceholderStrategy) getNextMark() string {
 
 func (p *PostgreSQLPlaceholderStrategy) clone() SqlVariablePlaceholderStrategy {
 	n := &PostgreSQLPlaceholderStrategy{}
-	n.paramIndex = 0
+	n.paramIndex = 1 // postgresql placeholder starts from $1
 	return n
 }
 
@@ -150,7 +240,188 @@ func (p *OraclePlaceholderStrategy) clone() SqlVariablePlaceholderStrategy {
 }
 
 type UserQueryNormalizer struct {
-	strategy SqlVariablePlaceholderStrategy
+	strategy         SqlVariablePlaceholderStrategy
+	dialect          string // dialect of driver name. empty for the driver of unknown dialect (e.g. sqlite3)
+	placeholderLimit int    // placeholders of a statement accepted by the driver. 0 is the default of the strategy
+}
+
+func (n *UserQueryNormalizer) procedureOut() procedureOutMode {
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		return procedureOutSessionVar
+	case *PostgreSQLPlaceholderStrategy:
+		return procedureOutResultRow
+	}
+	return procedureOutUnsupported
+}
+
+// explainPrefix returns EXPLAIN keyword of the dialect prepended to query
+func (n *UserQueryNormalizer) explainPrefix(analyze bool) (string, error) {
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy, *PostgreSQLPlaceholderStrategy:
+		if analyze {
+			return "EXPLAIN ANALYZE ", nil
+		}
+		return "EXPLAIN ", nil
+	}
+	// oracle writes the plan into PLAN_TABLE instead of returning it
+	return "", fmt.Errorf("explain is not supported by the driver")
+}
+
+func (n *UserQueryNormalizer) estimatePlan() estimatePlanMode {
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		return estimatePlanRows
+	case *PostgreSQLPlaceholderStrategy:
+		return estimatePlanJson
+	}
+	return estimatePlanUnsupported
+}
+
+// upsertClause returns conflict clause of the dialect appended to insert
+func (n *UserQueryNormalizer) upsertClause(conflictColumns []string, updateColumns []string) (string, error) {
+	var buffer bytes.Buffer
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		if len(updateColumns) == 0 {
+			return "", fmt.Errorf("upsert needs update columns for ON DUPLICATE KEY UPDATE")
+		}
+		buffer.WriteString("ON DUPLICATE KEY UPDATE ")
+		for i, c := range updateColumns {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteString(fmt.Sprintf("%s = VALUES(%s)", c, c))
+		}
+		return buffer.String(), nil
+	case *PostgreSQLPlaceholderStrategy:
+		if len(conflictColumns) == 0 {
+			return "", fmt.Errorf("upsert needs conflict columns for ON CONFLICT")
+		}
+		buffer.WriteString(fmt.Sprintf("ON CONFLICT (%s) ", strings.Join(conflictColumns, ", ")))
+		if len(updateColumns) == 0 {
+			buffer.WriteString("DO NOTHING")
+			return buffer.String(), nil
+		}
+		buffer.WriteString("DO UPDATE SET ")
+		for i, c := range updateColumns {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			buffer.WriteString(fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		}
+		return buffer.String(), nil
+	}
+	return "", fmt.Errorf("upsert is not supported by the driver")
+}
+
+// dialectName returns dialect of the driver. empty for unknown dialect
+func (n *UserQueryNormalizer) dialectName() string {
+	return n.dialect
+}
+
+// likeEscapeClause returns ESCAPE clause following the placeholder of like bind.
+// mysql and postgresql escape LIKE with backslash by default and the others (e.g. oracle, sqlite3, sqlserver) need ESCAPE
+func (n *UserQueryNormalizer) likeEscapeClause() string {
+	switch n.dialect {
+	case dialectMysql, dialectPostgresql:
+		return ""
+	}
+	return " ESCAPE '" + likeEscapeChar + "'"
+}
+
+const (
+	mysqlMaxPlaceholders    = 65535 // prepared statement parameter count is 16 bit
+	postgresMaxPlaceholders = 65535 // bind message parameter count is 16 bit
+	oracleMaxPlaceholders   = 65535
+	sqliteMaxPlaceholders   = 32766 // SQLITE_MAX_VARIABLE_NUMBER since 3.32.0 (999 before, set MaxPlaceholders)
+	mssqlMaxPlaceholders    = 2098  // 2100 parameters of sp_executesql less the statement and its declaration
+)
+
+// driverMaxPlaceholders returns placeholder count of a statement accepted by the database of driver name.
+// unknown driver is regarded as mysql
+func driverMaxPlaceholders(driverName string) int {
+	switch strings.ToLower(driverName) {
+	case "sqlite3", "sqlite":
+		return sqliteMaxPlaceholders
+	case "sqlserver", "mssql":
+		return mssqlMaxPlaceholders
+	}
+
+	switch dialectOf(driverName) {
+	case dialectPostgresql:
+		return postgresMaxPlaceholders
+	case dialectOracle:
+		return oracleMaxPlaceholders
+	}
+	return mysqlMaxPlaceholders
+}
+
+// maxPlaceholders returns placeholder count of a statement accepted by the database
+func (n *UserQueryNormalizer) maxPlaceholders() int {
+	if n.placeholderLimit > 0 {
+		return n.placeholderLimit
+	}
+
+	switch n.strategy.(type) {
+	case *PostgreSQLPlaceholderStrategy:
+		return postgresMaxPlaceholders
+	case *OraclePlaceholderStrategy:
+		return oracleMaxPlaceholders
+	}
+	return mysqlMaxPlaceholders
+}
+
+// quoteIdentifier quotes each part of (schema qualified) identifier. mysql uses backtick and the others double quote
+func (n *UserQueryNormalizer) quoteIdentifier(name string) string {
+	quote := "\""
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.Replace(part, quote, quote+quote, -1) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+const (
+	nullsOrderFirst = "first"
+	nullsOrderLast  = "last"
+)
+
+// orderByNulls returns ORDER BY term of expr with nulls ordering (first, last or empty for the database default).
+// postgresql and oracle use NULLS FIRST/LAST and mysql sorts by expr IS NULL ahead of expr
+func (n *UserQueryNormalizer) orderByNulls(expr string, desc bool, nulls string) (string, error) {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	switch strings.ToLower(nulls) {
+	case "":
+		return fmt.Sprintf("%s %s", expr, direction), nil
+	case nullsOrderFirst, nullsOrderLast:
+	default:
+		return "", fmt.Errorf("invalid nulls ordering [%s]", nulls)
+	}
+
+	first := strings.ToLower(nulls) == nullsOrderFirst
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		if first {
+			return fmt.Sprintf("%s IS NULL DESC, %s %s", expr, expr, direction), nil
+		}
+		return fmt.Sprintf("%s IS NULL ASC, %s %s", expr, expr, direction), nil
+	case *PostgreSQLPlaceholderStrategy, *OraclePlaceholderStrategy:
+		if first {
+			return fmt.Sprintf("%s %s NULLS FIRST", expr, direction), nil
+		}
+		return fmt.Sprintf("%s %s NULLS LAST", expr, direction), nil
+	}
+	return "", fmt.Errorf("nulls ordering is not supported by the driver")
 }
 
 // var holdByte byte = '`'
@@ -165,9 +436,30 @@ func (n *UserQueryNormalizer) normalize(stmt *QueryStatement) error {
 
 	var hold bytes.Buffer
 
+	rewriteOrdinal := stmt.userQuery && n.rewritesOrdinal()
+	backslashEscape := backslashEscapes(n.dialect)
+	ordinalCount := 0
 	queryLen := len(stmt.Query)
 	for i := 0; i < queryLen; i++ {
 		ch := stmt.Query[i]
+		if skip := skipLiteralOrComment(stmt.Query, i, backslashEscape); skip > i {
+			hold.WriteString(stmt.Query[i:skip])
+			i = skip - 1
+			continue
+		}
+
+		if ch == ordinalPlaceholder && rewriteOrdinal {
+			if i+1 < queryLen && stmt.Query[i+1] == ordinalPlaceholder {
+				// ?? is literal ? e.g) jsonb operator of postgresql
+				hold.WriteByte(ordinalPlaceholder)
+				i++
+				continue
+			}
+			hold.WriteByte(holdByte)
+			ordinalCount++
+			continue
+		}
+
 		if ch != delimStartCharacter {
 			hold.WriteByte(ch)
 			continue
@@ -186,13 +478,32 @@ func (n *UserQueryNormalizer) normalize(stmt *QueryStatement) error {
 			return fmt.Errorf("invalid variable declare format : %s", stmt.Query)
 		}
 
-		if isInClause(stmt.Query[:i]) {
-			stmt.columnMention = append(stmt.columnMention, NewColumnBindArray(v, hold.Len()+1))
+		name, modifier, err := parseColumnBind(v)
+		if err != nil {
+			return err
+		}
+
+		var bind ColumnBind
+		if _, isModifier := bindModifiers[modifier]; !isModifier && isInClause(stmt.Query[:i]) {
+			bind = NewColumnBindArray(name, hold.Len()+1)
+		} else {
+			bind = NewColumnBind(name, hold.Len()+1)
+		}
+		if _, isType := bindTypes[modifier]; isType {
+			bind.expect = modifier
 		} else {
-			stmt.columnMention = append(stmt.columnMention, NewColumnBind(v, hold.Len()+1))
+			bind.modifier = modifier
 		}
+		stmt.columnMention = append(stmt.columnMention, bind)
 		i = i + stopIndex + 1
 		hold.WriteByte(holdByte)
+		if modifier == bindModifierLike {
+			hold.WriteString(n.likeEscapeClause())
+		}
+	}
+
+	if ordinalCount > 0 && len(stmt.columnMention) > 0 {
+		return fmt.Errorf("? placeholder can not be used with {} bind : %s", stmt.Query)
 	}
 
 	stmt.HoldedQuery = hold.String()
@@ -200,6 +511,71 @@ func (n *UserQueryNormalizer) normalize(stmt *QueryStatement) error {
 	return nil
 }
 
+const ordinalPlaceholder byte = '?'
+
+// rewritesOrdinal reports whether ? placeholder of user query is rewritten to the placeholder of the dialect.
+// ? of xml statement is kept as it is. e.g) jsonb operator of postgresql
+func (n *UserQueryNormalizer) rewritesOrdinal() bool {
+	switch n.strategy.(type) {
+	case *MysqlPlaceholderStrategy:
+		return false
+	}
+	return true
+}
+
+// backslashEscapes reports whether backslash escapes the quote of string literal in dialect.
+// standard sql (e.g. postgresql with standard_conforming_strings) escapes quote by doubling only
+func backslashEscapes(dialect string) bool {
+	return dialect == dialectMysql
+}
+
+// skipLiteralOrComment returns the end index of quoted string literal or comment starting at i.
+// returns i when query[i] does not start them. doubled quote is an escaped quote and backslash
+// escapes the next character only with backslashEscape or in E'...' string of postgresql
+func skipLiteralOrComment(query string, i int, backslashEscape bool) int {
+	queryLen := len(query)
+	switch ch := query[i]; {
+	case ch == '\'' || ch == '"':
+		escape := backslashEscape || (ch == '\'' && isEscapeStringPrefix(query, i))
+		for j := i + 1; j < queryLen; j++ {
+			if query[j] == '\\' && escape {
+				j++
+				continue
+			}
+			if query[j] != ch {
+				continue
+			}
+			if j+1 < queryLen && query[j+1] == ch {
+				j++
+				continue
+			}
+			return j + 1
+		}
+		return queryLen
+	case ch == '-' && i+1 < queryLen && query[i+1] == '-':
+		end := strings.IndexByte(query[i:], '\n')
+		if end < 0 {
+			return queryLen
+		}
+		return i + end
+	case ch == '/' && i+1 < queryLen && query[i+1] == '*':
+		end := strings.Index(query[i+2:], "*/")
+		if end < 0 {
+			return queryLen
+		}
+		return i + 2 + end + 2
+	}
+	return i
+}
+
+// isEscapeStringPrefix reports whether quote at i starts E'...' string. e.g) E'C:\\'
+func isEscapeStringPrefix(query string, i int) bool {
+	if i < 1 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	return i < 2 || !isKeywordChar(query[i-2])
+}
+
 func (n *UserQueryNormalizer) resolveHolding(query string) string {
 	var buffer bytes.Buffer
 
@@ -231,54 +607,489 @@ func isInClause(sqlPrefix string) bool {
 	return false
 }
 
+var scanConverterMap = make(map[reflect.Type]func(src interface{}) (interface{}, error))
+var scanConverterMutex sync.RWMutex
+
+// RegisterScanConverter registers converter for struct field of goType.
+// scanning column into the field, fn converts driver value(src) first. e.g) 'ACTIVE' -> StatusActive
+func RegisterScanConverter(goType reflect.Type, fn func(src interface{}) (interface{}, error)) {
+	scanConverterMutex.Lock()
+	defer scanConverterMutex.Unlock()
+
+	if fn == nil {
+		delete(scanConverterMap, goType)
+		return
+	}
+	scanConverterMap[goType] = fn
+}
+
+func findScanConverter(goType reflect.Type) (func(src interface{}) (interface{}, error), bool) {
+	scanConverterMutex.RLock()
+	defer scanConverterMutex.RUnlock()
+
+	fn, ok := scanConverterMap[goType]
+	return fn, ok
+}
+
+var bindConverterMap = make(map[reflect.Type]func(v interface{}) (interface{}, error))
+var bindConverterMutex sync.RWMutex
+
+// RegisterBindConverter registers converter for parameter of goType.
+// binding the parameter, fn converts it to driver acceptable value first. e.g) time.Duration -> int64 milliseconds
+func RegisterBindConverter(goType reflect.Type, fn func(v interface{}) (interface{}, error)) {
+	bindConverterMutex.Lock()
+	defer bindConverterMutex.Unlock()
+
+	if fn == nil {
+		delete(bindConverterMap, goType)
+		return
+	}
+	bindConverterMap[goType] = fn
+}
+
+func findBindConverter(goType reflect.Type) (func(v interface{}) (interface{}, error), bool) {
+	bindConverterMutex.RLock()
+	defer bindConverterMutex.RUnlock()
+
+	fn, ok := bindConverterMap[goType]
+	return fn, ok
+}
+
+func hasBindConverter() bool {
+	bindConverterMutex.RLock()
+	defer bindConverterMutex.RUnlock()
+
+	return len(bindConverterMap) > 0
+}
+
+var columnCombinerMap = make(map[string]func(values []interface{}) (interface{}, error))
+var columnCombinerMutex sync.RWMutex
+
+// RegisterColumnCombiner registers combiner of name for struct field tagged with several columns.
+// e.g) FullName string `db:"first_name,last_name" combine:"fullName"`. scanning the row, fn receives
+// driver values of the columns in tag order and returns the field value. nil result leaves the field untouched
+func RegisterColumnCombiner(name string, fn func(values []interface{}) (interface{}, error)) {
+	columnCombinerMutex.Lock()
+	defer columnCombinerMutex.Unlock()
+
+	if fn == nil {
+		delete(columnCombinerMap, name)
+		return
+	}
+	columnCombinerMap[name] = fn
+}
+
+func findColumnCombiner(name string) (func(values []interface{}) (interface{}, error), bool) {
+	columnCombinerMutex.RLock()
+	defer columnCombinerMutex.RUnlock()
+
+	fn, ok := columnCombinerMap[name]
+	return fn, ok
+}
+
+// combinedField collects column values of a field combined from several columns
+type combinedField struct {
+	name       string
+	fieldIndex []int
+	fieldType  reflect.Type
+	combiner   func(values []interface{}) (interface{}, error)
+	values     []interface{}
+	last       int // column index completing the values
+}
+
 type StructureScanner struct {
 	scanIndex     int
+	columns       []string
+	strictColumns bool // column without field is error instead of ignored
 	fieldNameList []string
+	fieldIndex    [][]int // nil when field is not exist or settable
+	converters    []func(src interface{}) (interface{}, error)
+	sourceType    reflect.Type
+	positional    bool // column is mapped to field by position
+	combined      []*combinedField
+	combinedPos   []int // position of column in values of combined field
 	source        *reflect.Value
+	scanners      []interface{}
 }
 
-func newStructureScanner(converter FieldNameConvertStrategy, columns []string, val *reflect.Value) *StructureScanner {
+// newStructureScanner resolves column to field plan once. the scanner can be reused for rows of the same struct type.
+// n-th scanner is for n-th column regardless of field order. two columns of the same field is an error
+func newStructureScanner(converter FieldNameConvertStrategy, columns []string, val *reflect.Value, strictColumns bool) (*StructureScanner, error) {
 	ss := &StructureScanner{}
 	ss.scanIndex = 0
+	ss.columns = columns
+	ss.strictColumns = strictColumns
 	ss.fieldNameList = make([]string, len(columns))
+	ss.fieldIndex = make([][]int, len(columns))
+	ss.converters = make([]func(src interface{}) (interface{}, error), len(columns))
+	ss.sourceType = val.Type()
+	planned := make(map[string]int)
 	for i := 0; i < len(columns); i++ {
-		ss.fieldNameList[i] = converter.convertFieldName(strings.ToLower(columns[i]))
+		// column is passed as it is. case folding is up to the converter
+		ss.fieldNameList[i] = converter.convertFieldName(columns[i])
+		field, ok := findColumnField(ss.sourceType, columns[i], ss.fieldNameList[i])
+		if !ok || len(field.PkgPath) > 0 {
+			continue
+		}
+		key := fmt.Sprint(field.Index)
+		if prev, exists := planned[key]; exists {
+			return nil, fmt.Errorf("columns %s and %s map to the same field %s", columns[prev], columns[i], field.Name)
+		}
+		planned[key] = i
+		ss.plan(i, field)
+	}
+	if err := ss.planCombined(); err != nil {
+		return nil, err
+	}
+	ss.source = val
+	return ss, nil
+}
+
+// planCombined binds columns of the fields tagged with combine to their combiner.
+// the field is skipped when none of its columns is selected
+func (ss *StructureScanner) planCombined() error {
+	for i := 0; i < ss.sourceType.NumField(); i++ {
+		field := ss.sourceType.Field(i)
+		name := strings.TrimSpace(field.Tag.Get(structCombineTag))
+		if len(name) == 0 || len(field.PkgPath) > 0 {
+			continue
+		}
+
+		columns := strings.Split(field.Tag.Get(structColumnTag), ",")
+		positions := make([]int, len(columns))
+		found := 0
+		for k, column := range columns {
+			positions[k] = -1
+			for j, c := range ss.columns {
+				if strings.EqualFold(c, strings.TrimSpace(column)) {
+					positions[k] = j
+					found++
+					break
+				}
+			}
+		}
+		if found == 0 {
+			continue
+		}
+		if found < len(columns) {
+			return fmt.Errorf("combined field %s needs columns %s", field.Name, field.Tag.Get(structColumnTag))
+		}
+
+		combiner, ok := findColumnCombiner(name)
+		if !ok {
+			return fmt.Errorf("column combiner %s of field %s is not registered", name, field.Name)
+		}
+
+		if ss.combined == nil {
+			ss.combined = make([]*combinedField, len(ss.columns))
+			ss.combinedPos = make([]int, len(ss.columns))
+		}
+		c := &combinedField{name: name, fieldIndex: field.Index, fieldType: field.Type, combiner: combiner}
+		c.values = make([]interface{}, len(columns))
+		for k, j := range positions {
+			if ss.combined[j] != nil {
+				return fmt.Errorf("column %s is combined into fields %s and %s", ss.columns[j], ss.sourceType.FieldByIndex(ss.combined[j].fieldIndex).Name, field.Name)
+			}
+			ss.combined[j] = c
+			ss.combinedPos[j] = k
+			if j > c.last {
+				c.last = j
+			}
+		}
+	}
+	return nil
+}
+
+// combine keeps value of column index and sets the field when all its columns are scanned
+func (ss *StructureScanner) combine(index int, value interface{}) error {
+	c := ss.combined[index]
+	if b, ok := value.([]byte); ok {
+		value = cloneBytes(b)
+	}
+	c.values[ss.combinedPos[index]] = value
+	if index != c.last {
+		return nil
+	}
+
+	combined, err := c.combiner(c.values)
+	if err != nil {
+		return fmt.Errorf("fail to combine field %s : %s", ss.sourceType.FieldByIndex(c.fieldIndex).Name, err.Error())
+	}
+	if combined == nil {
+		return nil
+	}
+
+	targetField := ss.source.FieldByIndex(c.fieldIndex)
+	cv := reflect.ValueOf(combined)
+	if cv.Type().AssignableTo(c.fieldType) {
+		targetField.Set(cv)
+		return nil
+	}
+	return convertAssign(targetField.Addr().Interface(), combined)
+}
+
+// newPositionalScanner maps n-th column to n-th exported field in declaration order regardless of column name
+func newPositionalScanner(columnCount int, val *reflect.Value) (*StructureScanner, error) {
+	ss := &StructureScanner{}
+	ss.fieldNameList = make([]string, columnCount)
+	ss.fieldIndex = make([][]int, columnCount)
+	ss.converters = make([]func(src interface{}) (interface{}, error), columnCount)
+	ss.sourceType = val.Type()
+	ss.positional = true
+
+	column := 0
+	for i := 0; i < ss.sourceType.NumField() && column < columnCount; i++ {
+		field := ss.sourceType.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		ss.fieldNameList[column] = field.Name
+		ss.plan(column, field)
+		column++
+	}
+	if column < columnCount {
+		return nil, fmt.Errorf("%d columns but %s has %d exported fields", columnCount, ss.sourceType.String(), column)
+	}
+
+	ss.source = val
+	return ss, nil
+}
+
+// plan binds column i to field with its converter
+func (ss *StructureScanner) plan(i int, field reflect.StructField) {
+	ss.fieldIndex[i] = field.Index
+	if fn, ok := findScanConverter(field.Type); ok {
+		ss.converters[i] = fn
+	} else if isArrayField(field.Type) {
+		ss.converters[i] = newArrayConverter(field.Type)
+	}
+}
+
+// unqualifiedColumn strips table qualifier of column. e.g) users.id -> id
+func unqualifiedColumn(column string) string {
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		return column[i+1:]
+	}
+	return column
+}
+
+// unqualifiedColumns strips table qualifier of the columns. a column whose stripped name is already taken
+// by former column (e.g. users.id, orders.id of a join) keeps its qualified name so that it is not mapped
+func unqualifiedColumns(columns []string) []string {
+	list := make([]string, len(columns))
+	seen := make(map[string]bool)
+	for i, c := range columns {
+		name := strings.ToLower(unqualifiedColumn(c))
+		if seen[name] {
+			list[i] = c
+			continue
+		}
+		seen[name] = true
+		list[i] = unqualifiedColumn(c)
+	}
+	return list
+}
+
+// findColumnField finds field of column. db tag (e.g. db:"user_id") is matched first and then converted field name.
+// field tagged db:"-" or tagged with another column is not mapped
+func findColumnField(t reflect.Type, column string, fieldName string) (reflect.StructField, bool) {
+	noTag := func(string) string { return "" }
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagged, _, skip := structColumn(field, noTag)
+		if !skip && len(tagged) > 0 && strings.EqualFold(tagged, column) {
+			return field, true
+		}
+	}
+
+	field, ok := findStructField(t, fieldName)
+	if !ok {
+		return field, false
+	}
+	if tagged, _, skip := structColumn(field, noTag); skip || len(tagged) > 0 {
+		return reflect.StructField{}, false
+	}
+	return field, true
+}
+
+// findStructField finds field by name. falls back to case insensitive match for acronym. e.g) UserId -> UserID
+func findStructField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if field, ok := t.FieldByName(name); ok {
+		return field, true
 	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) == 0 && strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// reset prepares scanner for next row
+func (ss *StructureScanner) reset(val *reflect.Value) {
+	ss.scanIndex = 0
 	ss.source = val
-	return ss
 }
 
 func (ss *StructureScanner) cloneScannerList() []interface{} {
-	scanners := make([]interface{}, len(ss.fieldNameList))
+	if ss.scanners != nil {
+		return ss.scanners
+	}
+
+	ss.scanners = make([]interface{}, len(ss.fieldNameList))
 	for i := 0; i < len(ss.fieldNameList); i++ {
-		scanners[i] = ss
+		ss.scanners[i] = ss
 	}
-	return scanners
+	return ss.scanners
 }
 
 // Scan implements the Scanner interface.
 func (ss *StructureScanner) Scan(value interface{}) error {
-	fieldName := ss.fieldNameList[ss.scanIndex]
+	index := ss.scanIndex
 	ss.scanIndex++
 
-	targetField := ss.source.FieldByName(fieldName)
-	if !targetField.IsValid() || !targetField.CanInterface() {
-		return fmt.Errorf("field %s is not exist or settable", fieldName)
+	if index >= len(ss.fieldIndex) {
+		return fmt.Errorf("scan of column %d but %d columns are planned", index, len(ss.fieldIndex))
 	}
+	combined := ss.combined != nil && ss.combined[index] != nil
+	if combined {
+		if err := ss.combine(index, value); err != nil {
+			return err
+		}
+	}
+	if ss.fieldIndex[index] == nil {
+		if ss.strictColumns && !combined {
+			return fmt.Errorf("column %s has no field %s (not exist or settable)", ss.columns[index], ss.fieldNameList[index])
+		}
+		return nil
+	}
+	targetField := ss.source.FieldByIndex(ss.fieldIndex[index])
 
 	dest := targetField.Addr().Interface()
+	if converter := ss.converters[index]; converter != nil {
+		converted, err := converter(value)
+		if err != nil {
+			return fmt.Errorf("fail to convert field %s : %s", ss.fieldNameList[index], err.Error())
+		}
+		if converted == nil {
+			return nil
+		}
+		return convertAssign(dest, converted)
+	}
+
 	if scanner, ok := dest.(sql.Scanner); ok {
 		return scanner.Scan(value)
 	}
 
-	switch value.(type) {
+	switch t := value.(type) {
 	case nil:
 		return nil // do nothing...
+	case []byte:
+		if isNumericKind(targetField.Type()) {
+			return ss.scanNumeric(index, targetField, string(t))
+		}
+	case string:
+		if isNumericKind(targetField.Type()) {
+			return ss.scanNumeric(index, targetField, t)
+		}
 	}
 
 	return convertAssign(dest, value)
 }
 
+// isNumericKind reports t (or element of pointer t) is integer or float
+func isNumericKind(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// scanNumeric parses numeric column delivered as text (e.g. text protocol, DECIMAL, padded CHAR) into numeric field.
+// integer field accepts decimal text without fraction. e.g) "42.00"
+func (ss *StructureScanner) scanNumeric(index int, field reflect.Value, text string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := ss.scanNumeric(index, elem.Elem(), text); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	s := strings.TrimSpace(text)
+	var err error
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, field.Type().Bits()); err != nil {
+			var f float64
+			if f, err = parseIntegral(s, field.Type().Bits(), true); err == nil {
+				n = int64(f)
+			}
+		}
+		if err == nil {
+			field.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, field.Type().Bits()); err != nil {
+			var f float64
+			if f, err = parseIntegral(s, field.Type().Bits(), false); err == nil {
+				n = uint64(f)
+			}
+		}
+		if err == nil {
+			field.SetUint(n)
+		}
+	default:
+		var f float64
+		if f, err = strconv.ParseFloat(s, field.Type().Bits()); err == nil {
+			field.SetFloat(f)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("fail to scan column %s into %s field %s : %q is not a %s", ss.columnName(index), field.Kind(), ss.fieldNameList[index], text, field.Kind())
+	}
+	return nil
+}
+
+// parseIntegral parses decimal text having zero fraction within integer of bits. e.g) "42.00"
+func parseIntegral(s string, bits int, signed bool) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || (!signed && f < 0) {
+		return 0, strconv.ErrSyntax
+	}
+	limit := math.Ldexp(1, bits-1)
+	if !signed {
+		limit = math.Ldexp(1, bits)
+	}
+	if f >= limit || f < -limit {
+		return 0, strconv.ErrRange
+	}
+	return f, nil
+}
+
+// columnName returns column of index. positional scanner has no column name
+func (ss *StructureScanner) columnName(index int) string {
+	if index < len(ss.columns) {
+		return ss.columns[index]
+	}
+	return strconv.Itoa(index + 1)
+}
+
 func currentTimeMillis() int {
 	return int(time.Now().UnixNano() / 1000000)
 }
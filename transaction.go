@@ -21,6 +21,7 @@
 package queryman
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"runtime"
@@ -32,6 +33,8 @@ type DBTransaction struct {
 	queryFinder        QueryStatementFinder
 	fieldNameConverter FieldNameConvertStrategy
 	debugger           SqlDebugger
+	preference         *QuerymanPreference
+	normalizer         QueryNormalizer
 }
 
 func (t *DBTransaction) Rollback() error {
@@ -48,35 +51,47 @@ func (t *DBTransaction) Commit() error {
 	return t.tx.Commit()
 }
 
-func newTransaction(debugger SqlDebugger, tx *sql.Tx, queryFinder QueryStatementFinder, fieldNameConverter FieldNameConvertStrategy) *DBTransaction {
+func newTransaction(debugger SqlDebugger, tx *sql.Tx, queryFinder QueryStatementFinder, fieldNameConverter FieldNameConvertStrategy, preference *QuerymanPreference) *DBTransaction {
 	dbTransaction := DBTransaction{}
 	dbTransaction.debugger = debugger
 	dbTransaction.tx = tx
 	dbTransaction.queryFinder = queryFinder
 	dbTransaction.fieldNameConverter = fieldNameConverter
+	dbTransaction.preference = preference
 	return &dbTransaction
 }
 
-func (t *DBTransaction) exec(query string, args ...interface{}) (sql.Result, error) {
-	return t.tx.Exec(query, args...)
+func (t *DBTransaction) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
 }
 
-func (t *DBTransaction) query(query string, args ...interface{}) (*sql.Rows, error) {
-	return t.tx.Query(query, args...)
+func (t *DBTransaction) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
 }
 
-func (t *DBTransaction) queryRow(query string, args ...interface{}) *sql.Row {
-	return t.tx.QueryRow(query, args...)
+func (t *DBTransaction) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
 }
 
-func (t *DBTransaction) prepare(query string) (*sql.Stmt, error) {
-	return t.tx.Prepare(query)
+func (t *DBTransaction) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
 }
 
 func (t *DBTransaction) isTransaction() bool {
 	return true
 }
 
+func (t *DBTransaction) getPreference() *QuerymanPreference {
+	return t.preference
+}
+
+func (t *DBTransaction) getNormalizer() QueryNormalizer {
+	if t.normalizer != nil {
+		return t.normalizer
+	}
+	return queryNormalizer
+}
+
 func (t *DBTransaction) debugEnabled() bool {
 	return t.debugger.debugEnabled()
 }
@@ -85,8 +100,16 @@ func (t *DBTransaction) debugPrint(format string, params ...interface{}) {
 	t.debugger.debugPrint(format, params...)
 }
 
-func (t *DBTransaction) recordExcution(stmtId string, start time.Time) {
-	t.debugger.recordExcution(stmtId, start)
+func (t *DBTransaction) recordExcution(stmtId string, bind time.Duration, start time.Time) {
+	t.debugger.recordExcution(stmtId, bind, start)
+}
+
+func (t *DBTransaction) recordAffected(stmtId string, affected int64) {
+	t.debugger.recordAffected(stmtId, affected)
+}
+
+func (t *DBTransaction) trackRunning(ctx context.Context, stmtId string) (context.Context, context.CancelFunc) {
+	return t.debugger.trackRunning(ctx, stmtId)
 }
 
 func (t *DBTransaction) CreateBulk() (Bulk, error) {
@@ -116,6 +139,17 @@ func (t *DBTransaction) Execute(v ...interface{}) (sql.Result, error) {
 }
 
 func (t *DBTransaction) ExecuteWithStmt(id string, v ...interface{}) (sql.Result, error) {
+	return t.ExecuteWithStmtContext(context.Background(), id, v...)
+}
+
+func (t *DBTransaction) ExecuteWithStmtContext(ctx context.Context, id string, v ...interface{}) (sql.Result, error) {
+	if statements, ok := findMultiStatement(t.preference, id); ok {
+		if t.preference.MultiStatementExec == MultiStatementReject {
+			return nil, ErrMultiStatement
+		}
+		return t.executeStatements(ctx, statements, v)
+	}
+
 	stmt, err := t.queryFinder.find(id)
 	if err != nil {
 		return nil, err
@@ -125,7 +159,52 @@ func (t *DBTransaction) ExecuteWithStmt(id string, v ...interface{}) (sql.Result
 		return nil, ErrExecutionInvalidSqlType
 	}
 
-	return execute(t, stmt, v...)
+	return execute(ctx, t, stmt, v...)
+}
+
+// ExecuteAffected executes statement and returns affected row count
+func (t *DBTransaction) ExecuteAffected(id string, v ...interface{}) (int64, error) {
+	result, err := t.ExecuteWithStmt(id, v...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ExecuteVerbose executes statement and returns the query and parameters actually passed to the driver with result
+func (t *DBTransaction) ExecuteVerbose(id string, v ...interface{}) (sql.Result, string, []interface{}, error) {
+	ctx, capture := withExecCapture(context.Background())
+	result, err := t.ExecuteWithStmtContext(ctx, id, v...)
+	return result, capture.query, capture.params, err
+}
+
+// UpdatePartial updates table with only the present (non nil pointer) fields of v
+func (t *DBTransaction) UpdatePartial(table string, v interface{}, keys ...string) (sql.Result, error) {
+	omitZero := t.preference != nil && t.preference.PartialOmitZero
+	query, params, err := buildPartialUpdate(table, v, omitZero, t.fieldNameConverter.convertColumnName, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return t.ExecuteWithStmt(query, params)
+}
+
+// InsertStruct inserts the exported fields of struct v into table. nil pointer fields are skipped
+func (t *DBTransaction) InsertStruct(table string, v interface{}) (sql.Result, error) {
+	query, params, err := buildStructInsert(table, v, t.fieldNameConverter.convertColumnName)
+	if err != nil {
+		return nil, err
+	}
+	return t.ExecuteWithStmt(query, params)
+}
+
+// UpdateStruct updates table with struct v keyed by the fields tagged with pk option and returns affected row count
+func (t *DBTransaction) UpdateStruct(table string, v interface{}) (int64, error) {
+	updateAll := t.preference != nil && t.preference.UpdateStructAll
+	query, params, err := buildStructUpdate(table, v, updateAll, t.fieldNameConverter.convertColumnName)
+	if err != nil {
+		return 0, err
+	}
+	return t.ExecuteAffected(query, params)
 }
 
 func (t *DBTransaction) Query(v ...interface{}) *QueryResult {
@@ -135,6 +214,10 @@ func (t *DBTransaction) Query(v ...interface{}) *QueryResult {
 }
 
 func (t *DBTransaction) QueryWithStmt(id string, v ...interface{}) *QueryResult {
+	return t.QueryWithStmtContext(context.Background(), id, v...)
+}
+
+func (t *DBTransaction) QueryWithStmtContext(ctx context.Context, id string, v ...interface{}) *QueryResult {
 	stmt, err := t.queryFinder.find(id)
 	if err != nil {
 		return newQueryResultError(err)
@@ -144,7 +227,7 @@ func (t *DBTransaction) QueryWithStmt(id string, v ...interface{}) *QueryResult
 		return newQueryResultError(ErrQueryInvalidSqlType)
 	}
 
-	queryedRow := queryMultiRow(t, stmt, v...)
+	queryedRow := queryMultiRow(ctx, t, stmt, v...)
 	queryedRow.fieldNameConverter = t.fieldNameConverter
 	return queryedRow
 }
@@ -156,6 +239,10 @@ func (t *DBTransaction) QueryRow(v ...interface{}) *QueryRowResult {
 }
 
 func (t *DBTransaction) QueryRowWithStmt(id string, v ...interface{}) *QueryRowResult {
+	return t.QueryRowWithStmtContext(context.Background(), id, v...)
+}
+
+func (t *DBTransaction) QueryRowWithStmtContext(ctx context.Context, id string, v ...interface{}) *QueryRowResult {
 	stmt, err := t.queryFinder.find(id)
 	if err != nil {
 		return newQueryRowResultError(err)
@@ -165,17 +252,60 @@ func (t *DBTransaction) QueryRowWithStmt(id string, v ...interface{}) *QueryRowR
 		return newQueryRowResultError(ErrQueryInvalidSqlType)
 	}
 
-	var queryRowResult *QueryRowResult
-	queryResult := queryMultiRow(t, stmt, v...)
-	if queryResult.err != nil {
-		queryRowResult = newQueryRowResultError(queryResult.err)
-	} else {
-		queryRowResult = newQueryRowResult(queryResult.pstmt, queryResult.rows)
+	queryRowResult := querySingleRow(ctx, t, stmt, v...)
+	queryRowResult.fieldNameConverter = t.fieldNameConverter
+	queryRowResult.SetTransaction()
+	return queryRowResult
+}
+
+// QueryFirst scans the first row of select statement into dest and ignores the rest.
+// no row is not an error but found=false with dest untouched
+func (t *DBTransaction) QueryFirst(id string, dest interface{}, v ...interface{}) (bool, error) {
+	return t.QueryFirstContext(context.Background(), id, dest, v...)
+}
+
+func (t *DBTransaction) QueryFirstContext(ctx context.Context, id string, dest interface{}, v ...interface{}) (bool, error) {
+	return t.QueryWithStmtContext(ctx, id, v...).scanFirst(dest)
+}
+
+// InsertReturning executes insert statement having RETURNING clause and scans the returned row into dest.
+// no returned row is ErrNoRows
+func (t *DBTransaction) InsertReturning(id string, dest interface{}, v ...interface{}) error {
+	return t.InsertReturningContext(context.Background(), id, dest, v...)
+}
+
+func (t *DBTransaction) InsertReturningContext(ctx context.Context, id string, dest interface{}, v ...interface{}) error {
+	stmt, err := t.queryFinder.find(id)
+	if err != nil {
+		return err
+	}
+
+	if stmt.eleType != eleTypeInsert {
+		return ErrInsertReturningInvalidSqlType
 	}
 
-	queryResult.pstmt = nil
-	queryResult.rows = nil
+	queryRowResult := querySingleRow(ctx, t, stmt, v...)
 	queryRowResult.fieldNameConverter = t.fieldNameConverter
 	queryRowResult.SetTransaction()
-	return queryRowResult
+	return queryRowResult.Scan(dest)
+}
+
+// ExecuteQuery executes insert, update or delete statement having RETURNING clause and returns the returned rows
+func (t *DBTransaction) ExecuteQuery(id string, v ...interface{}) *QueryResult {
+	return t.ExecuteQueryContext(context.Background(), id, v...)
+}
+
+func (t *DBTransaction) ExecuteQueryContext(ctx context.Context, id string, v ...interface{}) *QueryResult {
+	stmt, err := t.queryFinder.find(id)
+	if err != nil {
+		return newQueryResultError(err)
+	}
+
+	if stmt.eleType != eleTypeInsert && stmt.eleType != eleTypeUpdate {
+		return newQueryResultError(ErrExecutionInvalidSqlType)
+	}
+
+	queryedRow := queryMultiRow(ctx, t, stmt, v...)
+	queryedRow.fieldNameConverter = t.fieldNameConverter
+	return queryedRow
 }